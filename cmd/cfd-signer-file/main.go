@@ -0,0 +1,106 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// cfd-signer-file is a reference signer plugin that holds the keys on files (PKCS#8 PEM, as
+// written by cfd). It is useful to test the signer protocol, real plugins would use a HSM or a KMS.
+//
+//	cfd-signer-file -key /path/to/ca.key                 # subprocess: exec:cfd-signer-file -key /path/to/ca.key
+//	cfd-signer-file -dir /path/to/keys -socket /tmp/s.sock # unix socket: unix:/tmp/s.sock
+//
+// With -dir, the key ID requested is the file name of the key in the directory.
+package main
+
+import (
+	"crypto"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/signer"
+)
+
+var errKeyNotFound = errors.New("key not found")
+
+func main() {
+
+	var (
+		keyFile string
+		keysDir string
+		socket  string
+		err     error
+	)
+
+	flag.StringVar(&keyFile, "key", "", "Key file used when no key ID is requested.")
+	flag.StringVar(&keysDir, "dir", "", "Directory with the keys, the key ID is its file name.")
+	flag.StringVar(&socket, "socket", "", "Unix socket to listen on, standard input/output is used if empty.")
+	flag.Parse()
+
+	keys := func(keyID string) (crypto.Signer, error) {
+
+		var path string = keyFile
+
+		if keyID != "" {
+			if keysDir == "" {
+				return nil, errKeyNotFound
+			}
+			path = filepath.Join(keysDir, filepath.Base(keyID))
+		}
+
+		if path == "" {
+			return nil, errKeyNotFound
+		}
+
+		keyPEM, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errKeyNotFound
+		}
+
+		key, err := manager.PrivateKeyFromPEM(keyPEM)
+		if err != nil {
+			return nil, err
+		}
+
+		return key.(crypto.Signer), nil
+
+	}
+
+	if socket == "" {
+		err = signer.Serve(os.Stdin, os.Stdout, keys)
+	} else {
+		var listener net.Listener
+		listener, err = net.Listen("unix", socket)
+		if err == nil {
+			err = signer.ServeListener(listener, keys)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+}
@@ -58,6 +58,7 @@ func apiFunc(cmd *cobra.Command, args []string) {
 	sto, err = store.Open(context.Background(), viper.GetString(configDBType), viper.GetString(configDBConnectionString))
 	er(err)
 	srv = service.NewAsServer(sto, Version)
+	configureService(srv)
 
 	a = api.New(srv, Version)
 	go a.Start(viper.GetString(configAPIAddr),
@@ -0,0 +1,121 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// caPolicyCmd shows, sets or removes the issuance policy of a CA
+var caPolicyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Shows, sets or removes the issuance policy of the CA.",
+	Long: `Shows, sets or removes the issuance policy of the CA.
+
+New certificates, signed CSRs and renewals that do not meet the policy are rejected, the error tells
+the rule that failed. Without flags the current policy is shown as YAML.
+
+SAN patterns are globs (* matches any characters) or regular expressions if prefixed by 'regex:'.
+The ssh_* rules apply to the SSH certificates, principals use the same patterns.
+
+Example:
+
+  allowed_sans:
+  - '*.example.com'
+  - 'regex:^10\.0\.[0-9]+\.[0-9]+$'
+  denied_sans:
+  - '*.prod.example.com'
+  max_exp: 90
+  allowed_keys:
+  - ecdsa:256
+  - rsa:2048
+  deny_client: true
+  required_dn:
+  - o
+  ssh_max_validity: 8h
+  ssh_allowed_principals:
+  - 'deploy-*'
+  ssh_allowed_extensions:
+  - permit-pty`,
+	Run: caPolicyFunc,
+}
+
+func init() {
+	caCmd.AddCommand(caPolicyCmd)
+	caPolicyCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required). [$CFD_CA_ID]")
+	caPolicyCmd.Flags().StringVarP(&global.filename, "file", "f", "", "File with the policy to set in YAML format.")
+	caPolicyCmd.Flags().BoolVar(&global.bool1, "delete", false, "Remove the policy, the CA will sign any request.")
+}
+
+func caPolicyFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		policy     client.APIPolicy
+		bytesFile  []byte
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	switch {
+	case global.bool1:
+		_, err = srv.PolicyDelete(ctx, collection)
+		er(err)
+
+		echo("\n\nPolicy Removed.")
+
+	case global.filename != "":
+		bytesFile, err = ioutil.ReadFile(global.filename)
+		er(err)
+
+		err = yaml.Unmarshal(bytesFile, &policy)
+		er(err)
+
+		_, err = srv.PolicySet(ctx, collection, policy)
+		er(err)
+
+		echo("\n\nPolicy Saved.")
+
+	default:
+		policy, err = srv.PolicyGet(ctx, collection)
+		er(err)
+
+		bytesFile, err = yaml.Marshal(policy)
+		er(err)
+
+		saveOrShowFile("stdout", bytesFile, 0640)
+	}
+
+}
@@ -0,0 +1,96 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// caRolloverCmd replaces a root CA by a new one
+var caRolloverCmd = &cobra.Command{
+	Use:   "rollover",
+	Short: "Replaces a root CA by a new one under the same CA ID.",
+	Long: `Replaces a root CA by a new one under the same CA ID.
+
+A new CA key and certificate are created with the same subject, constraints and usages. Both CAs 
+are cross signed and, until the rollover finishes (--finish), the CA certificate returned with 
+every certificate is a trust bundle with both CAs and the cross signed certificates, so clients
+trusting any of them keep working.
+
+Certificates must be re-issued with the new CA key (--reissue), or they will be re-issued on its 
+next renewal.
+
+To write to the standard output (console) the file contents (cert, key, ca-cert) use 'out' or 'stdout'.`,
+	Run: caRolloverFunc,
+}
+
+func init() {
+	caCmd.AddCommand(caRolloverCmd)
+	caRolloverCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required). [$CFD_CA_ID]")
+	caRolloverCmd.Flags().StringVarP(&global.certFile, "cert", "c", "", "New CA certificate file location.")
+	caRolloverCmd.Flags().StringVarP(&global.keyFile, "key", "k", "", "New CA key file location. NOTE: Do not share this file.")
+	caRolloverCmd.Flags().StringVar(&global.caCertFile, "ca-cert", "", "Trust bundle file location.")
+	caRolloverCmd.Flags().StringVar(&global.keyType, "key-type", "", "Key algorithm of the new CA (defaults to the current one).")
+	caRolloverCmd.Flags().Int64Var(&global.days, "exp", 0, "Days the new CA will be valid (defaults to the current CA lifetime).")
+	caRolloverCmd.Flags().BoolVar(&global.bool1, "reissue", false, "Re-issue the certificates with the new CA key.")
+	caRolloverCmd.Flags().BoolVar(&global.bool2, "finish", false, "Finish the rollover, the previous CA will not be included on the trust bundle.")
+}
+
+func caRolloverFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		ca         client.Certificate
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	if global.bool2 {
+		er(srv.CARolloverFinish(ctx, collection))
+		echo("\n\nCA Rollover Finished.")
+		return
+	}
+
+	ca, err = srv.CARollover(ctx, collection, client.APICARolloverRequest{
+		Key:            global.keyType,
+		ExpirationDays: global.days,
+		Reissue:        global.bool1,
+	})
+	er(err)
+
+	saveFiles(append(append([]byte{}, ca.Certificate...), ca.CACertificate...), ca.Certificate, ca.Key)
+
+	echo(fmt.Sprintf("\n\nCA Rolled Over. ID: '%s'\n", collection))
+
+}
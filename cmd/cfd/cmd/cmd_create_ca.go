@@ -69,7 +69,6 @@ func createCaFunc(cmd *cobra.Command, args []string) {
 	defer srv.Close()
 
 	// fill from YAML
-	// TODO: fill from CSR
 	if global.filename != "" {
 		bytesInput, err = ioutil.ReadFile(global.filename)
 		er(err)
@@ -24,7 +24,9 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
+	"strconv"
 
 	"github.com/fernandezvara/certsfor/internal/service"
 	"github.com/fernandezvara/certsfor/pkg/client"
@@ -41,6 +43,12 @@ var createCertificateCmd = &cobra.Command{
 	
 Every CA is identified by a UUID. To operate with the CA, you must be pass this ID on each request.
 
+Using --csr the certificate is signed from a PKCS#10 certificate signing request. Subject and SANs are
+taken from it and no private key is generated, stored or returned.
+
+Using --profile the profile of the CA is applied to the request: its defaults fill the values left empty
+and its fixed values override the requested ones.
+
 To write to the standard output (console) the file contents (cert, key, bundle, ca-cert) use 'out' or 'stdout'.`,
 	Run: createCertificateFunc,
 }
@@ -55,6 +63,9 @@ func init() {
 	createCertificateCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required). [$CFD_CA_ID]")
 	createCertificateCmd.Flags().StringVar(&global.pfxFile, "pfx", "", "pfx file location")
 	createCertificateCmd.Flags().StringVar(&global.pfxPassword, "pfx-password", "changeit", "pfx password")
+	createCertificateCmd.Flags().StringVar(&global.csrFile, "csr", "", "Certificate signing request (PEM) to sign.")
+	createCertificateCmd.Flags().StringVar(&global.profile, "profile", "", "Profile of the CA to apply to the request.")
+	createCertificateCmd.Flags().StringVar(&global.validity, "validity", "", "Time the certificate will be valid as a duration (ex: 6h, 90m), overrides the expiration days.")
 }
 
 func createCertificateFunc(cmd *cobra.Command, args []string) {
@@ -76,7 +87,10 @@ func createCertificateFunc(cmd *cobra.Command, args []string) {
 
 	collection = collectionOrExit()
 
-	// TODO: fill from CSR
+	if global.csrFile != "" {
+		createCertificateFromCSR(ctx, srv, collection)
+		return
+	}
 
 	// fill from YAML
 	if global.filename != "" {
@@ -92,6 +106,14 @@ func createCertificateFunc(cmd *cobra.Command, args []string) {
 		interactiveCertificate(&request, true)
 	}
 
+	if global.profile != "" {
+		request.Profile = global.profile
+	}
+
+	if global.validity != "" {
+		request.Validity = global.validity
+	}
+
 	bytesCA, bytesCert, bytesKey, err = srv.CertificateSet(ctx, collection, request)
 	er(err)
 
@@ -100,3 +122,55 @@ func createCertificateFunc(cmd *cobra.Command, args []string) {
 	echo("\n\nCertificate Created.")
 
 }
+
+// createCertificateFromCSR signs the CSR file, expiration and client usage are read from
+// the YAML file if any or asked interactively
+func createCertificateFromCSR(ctx context.Context, srv *service.Service, collection string) {
+
+	var (
+		request    client.APICSRRequest
+		template   client.APICertificateRequest
+		response   client.Certificate
+		bytesInput []byte
+		expires    string
+		err        error
+	)
+
+	request.CSR, err = ioutil.ReadFile(global.csrFile)
+	er(err)
+
+	if global.filename != "" {
+		bytesInput, err = ioutil.ReadFile(global.filename)
+		er(err)
+
+		err = yaml.Unmarshal(bytesInput, &template)
+		er(err)
+
+		request.ExpirationDays = template.ExpirationDays
+		request.Validity = template.Validity
+		request.Client = template.Client
+
+	} else {
+		template = certTemplate()
+
+		expires, err = promptText("Expires in (days)", strconv.Itoa(int(template.ExpirationDays)), validationInteger)
+		er(err)
+		request.ExpirationDays, err = strconv.ParseInt(expires, 10, 64)
+		er(err)
+
+		request.Client, err = promptTrueFalseBool("Client Certificate?", "Yes", "No", false)
+		er(err)
+	}
+
+	if global.validity != "" {
+		request.Validity = global.validity
+	}
+
+	response, err = srv.CertificateSignCSR(ctx, collection, request)
+	er(err)
+
+	saveFiles(response.CACertificate, response.Certificate, response.Key)
+
+	echo(fmt.Sprintf("\n\nCertificate Created. ID: '%s'\n", response.Request.DN.CN))
+
+}
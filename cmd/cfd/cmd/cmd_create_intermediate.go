@@ -0,0 +1,99 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// createIntermediateCmd represents the bootstrap command
+var createIntermediateCmd = &cobra.Command{
+	Use:   "intermediate",
+	Short: "Creates a new intermediate CA certificate/key pair signed by a CA.",
+	Long: `Creates a new intermediate CA certificate/key pair signed by a CA.
+
+The intermediate CA gets its own UUID, certificates can be created from it as from any other CA.
+The parent CA is identified by --ca-id and its path length must allow the new CA.
+
+To write to the standard output (console) the file contents (cert, key, bundle, ca-cert) use 'out' or 'stdout'.`,
+	Run: createIntermediateFunc,
+}
+
+func init() {
+	createCmd.AddCommand(createIntermediateCmd)
+	createIntermediateCmd.Flags().StringVarP(&global.certFile, "cert", "c", "", "Certificate file location.")
+	createIntermediateCmd.Flags().StringVar(&global.caCertFile, "ca-cert", "", "CA Certificate chain file location.")
+	createIntermediateCmd.Flags().StringVarP(&global.bundleFile, "bundle", "b", "", "Bundle file location.")
+	createIntermediateCmd.Flags().StringVarP(&global.keyFile, "key", "k", "", "Key file location. NOTE: Do not share this file.")
+	createIntermediateCmd.Flags().StringVarP(&global.filename, "file", "f", "", "File with the answers in YAML format.")
+	createIntermediateCmd.Flags().StringVar(&global.collection, "ca-id", "", "Parent CA Identifier. (required). [$CFD_CA_ID]")
+}
+
+func createIntermediateFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		request    client.APICertificateRequest
+		response   client.Certificate
+		collection string
+		bytesInput []byte
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	// fill from YAML
+	if global.filename != "" {
+		bytesInput, err = ioutil.ReadFile(global.filename)
+		er(err)
+
+		err = yaml.Unmarshal(bytesInput, &request)
+		er(err)
+
+	} else {
+		// interactive: fill Certificate data
+		request = caTemplate()
+		interactiveCertificate(&request, false)
+	}
+
+	response, err = srv.IntermediateCreate(ctx, collection, request)
+	er(err)
+
+	saveFiles(response.CACertificate, response.Certificate, response.Key)
+
+	echo(fmt.Sprintf("\n\nIntermediate CA Created. ID: '%s'\n", response.CAID))
+	if global.quiet {
+		fmt.Print(response.CAID)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/pem"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// crlCmd represents the bootstrap command
+var crlCmd = &cobra.Command{
+	Use:   "crl",
+	Short: "Gets the certificate revocation list (CRL) of a CA.",
+	Long: `Gets the certificate revocation list (CRL) of a CA.
+
+The CRL is signed when requested, it is DER encoded unless --pem is used.
+
+To write to the standard output (console) use 'out' or 'stdout'.`,
+	Run: crlFunc,
+}
+
+func init() {
+	rootCmd.AddCommand(crlCmd)
+	crlCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required). [$CFD_CA_ID]")
+	crlCmd.Flags().StringVarP(&global.filename, "file", "f", "out", "CRL file location.")
+	crlCmd.Flags().BoolVar(&global.bool1, "pem", false, "PEM encoded CRL.")
+}
+
+func crlFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		crl        []byte
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	crl, err = srv.CRL(ctx, collection)
+	er(err)
+
+	if global.bool1 {
+		crl = pem.EncodeToMemory(&pem.Block{Type: manager.FileCRL, Bytes: crl})
+	}
+
+	saveOrShowFile(global.filename, crl, 0644)
+
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// deleteCaCmd represents the bootstrap command
+var deleteCaCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Deletes a CA with all its certificates from the store",
+	Long: `Deletes a CA with all its certificates from the store
+
+Every certificate issued by the CA, its revocations, profiles, policy, SSH CA and issuance log 
+are removed too. This cannot be undone, export the CA first if it could be needed again.
+
+CAs with intermediate CAs cannot be deleted, delete the intermediates first.`,
+	Example: `  cfd export ca --ca-id <uuid> --out ca-backup.cfd
+  cfd delete ca --ca-id <uuid>`,
+	Run: deleteCaFunc,
+}
+
+func init() {
+	deleteCmd.AddCommand(deleteCaCmd)
+	deleteCaCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required) [$CFD_CA_ID]")
+	deleteCaCmd.Flags().BoolVarP(&global.bool1, "yes", "y", false, "Asumme yes to the prompts (is assumed if --quiet)")
+}
+
+func deleteCaFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		ca         client.APICA
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	ca, err = srv.CAInfo(ctx, collection)
+	er(err)
+
+	// if quiet assume yes
+	if global.quiet {
+		global.bool1 = global.quiet
+	}
+
+	// run interactively?
+	if !global.bool1 {
+		echo(fmt.Sprintf("CA '%s' (%s) and its %d certificates will be deleted.\n", ca.CN, ca.CAID, ca.Certificates))
+		global.bool1, err = promptTrueFalseBool("Are you sure?", "Yes", "No", false)
+		er(err)
+	}
+
+	if global.bool1 {
+		_, err = srv.CADelete(ctx, collection)
+		er(err)
+
+		echo("\n\nCA Deleted.")
+	} else {
+		echo("\n\nOperation Cancelled.")
+	}
+
+}
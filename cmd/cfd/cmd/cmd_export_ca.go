@@ -0,0 +1,104 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// exportCaCmd writes an encrypted backup of a CA
+var exportCaCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Exports a CA with all its data as an encrypted backup.",
+	Long: `Exports a CA with all its data as an encrypted backup.
+
+The backup holds the CA certificate and key, every certificate issued with its key and request, 
+revocations, issuance log, profiles, policy and the rest of the CA data. It is encrypted with a 
+passphrase (AES-256-GCM, key derived with scrypt), keys encrypted at rest with a key encryption 
+key are decrypted so the backup can be restored knowing only its passphrase.
+
+Restore it with 'cfd import backup' on any data store. Intermediate CAs have their own ID, export 
+them too.
+
+Only works with direct access to the store (api.enabled: false). If no passphrase is set it will 
+be requested.
+
+Example:
+
+  cfd export ca --ca-id <uuid> --out ca-backup.cfd`,
+	Run: exportCaFunc,
+}
+
+func init() {
+	exportCmd.AddCommand(exportCaCmd)
+	exportCaCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required) [$CFD_CA_ID]")
+	exportCaCmd.Flags().StringVarP(&global.filename, "out", "o", "", "Backup file location. (required)")
+	exportCaCmd.Flags().StringVar(&global.passphrase, "passphrase", "", "Passphrase to encrypt the backup.")
+	exportCaCmd.MarkFlagRequired("out")
+}
+
+func exportCaFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv          *service.Service
+		collection   string
+		confirmation string
+		sealed       []byte
+		err          error
+		ctx          context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	if global.passphrase == "" {
+		global.passphrase, err = promptPassword("Backup passphrase", validationRequired)
+		er(err)
+
+		confirmation, err = promptPassword("Repeat the passphrase", validationRequired)
+		er(err)
+
+		if confirmation != global.passphrase {
+			er(errors.New("passphrases do not match"))
+		}
+	}
+
+	sealed, err = srv.BackupExport(ctx, collection, global.passphrase)
+	if errors.Is(err, service.ErrServerOnly) {
+		er(fmt.Errorf("%w, set api.enabled to false", err))
+	}
+	er(err)
+
+	er(ioutil.WriteFile(global.filename, sealed, 0600))
+
+	echo(fmt.Sprintf("\n\nCA exported. Backup: '%s'\n", global.filename))
+
+}
@@ -24,6 +24,7 @@ package cmd
 
 import (
 	"context"
+	"os"
 
 	"github.com/fernandezvara/certsfor/internal/service"
 	"github.com/fernandezvara/certsfor/pkg/client"
@@ -36,6 +37,8 @@ var getCertificateCmd = &cobra.Command{
 	Short: "Get a certificate/key from the store.",
 	Long: `Get a certificate/key from the store.
 	
+The certificate can be found by its Common Name (--cn) or by its serial number (--serial).
+
 To write to the standard output (console) the file contents (cert, key, bundle, ca-cert) use 'out' or 'stdout'.`,
 	Run: getCertificateFunc,
 }
@@ -47,11 +50,11 @@ func init() {
 	getCertificateCmd.Flags().StringVarP(&global.bundleFile, "bundle", "b", "", "Bundle file location.")
 	getCertificateCmd.Flags().StringVarP(&global.keyFile, "key", "k", "", "Key file location.")
 	getCertificateCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required). [$CFD_CA_ID]")
-	getCertificateCmd.Flags().StringVar(&global.cn, "cn", "", "Common Name. (required).")
-	getCertificateCmd.Flags().IntVar(&global.remaining, "renew", 20, "Time (expresed as percent) to be used to determine if the certificate must be renewed (defaults to 20 %). Key remains the same.")
+	getCertificateCmd.Flags().StringVar(&global.cn, "cn", "", "Common Name. (required if no --serial).")
+	getCertificateCmd.Flags().StringVar(&global.serial, "serial", "", "Serial number as hexadecimal. (required if no --cn).")
+	getCertificateCmd.Flags().IntVar(&global.remaining, "renew", 20, "Time (expresed as percent) to be used to determine if the certificate must be renewed (defaults to 20 %). Key remains the same unless the certificate renewal policy is rekey.")
 	getCertificateCmd.Flags().StringVar(&global.pfxFile, "pfx", "", "pfx file location")
 	getCertificateCmd.Flags().StringVar(&global.pfxPassword, "pfx-password", "changeit", "pfx password")
-	getCertificateCmd.MarkFlagRequired("cn")
 }
 
 func getCertificateFunc(cmd *cobra.Command, args []string) {
@@ -69,7 +72,15 @@ func getCertificateFunc(cmd *cobra.Command, args []string) {
 
 	collection = collectionOrExit()
 
-	cert, err = srv.CertificateGet(ctx, collection, global.cn, global.remaining)
+	switch {
+	case global.cn != "":
+		cert, err = srv.CertificateGet(ctx, collection, global.cn, global.remaining)
+	case global.serial != "":
+		cert, err = srv.CertificateGetBySerial(ctx, collection, global.serial)
+	default:
+		echo("\n\nCommon Name or serial number missing. Cannot continue.")
+		os.Exit(1)
+	}
 	er(err)
 
 	saveFiles(cert.CACertificate, cert.Certificate, cert.Key)
@@ -0,0 +1,96 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/rest"
+	"github.com/spf13/cobra"
+)
+
+// importBackupCmd restores a CA from an encrypted backup
+var importBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Restores a CA from an encrypted backup.",
+	Long: `Restores a CA from an encrypted backup made with 'cfd export ca'.
+
+The CA keeps its ID, so it must not exist on the data store. Keys are encrypted with the key 
+encryption key configured (db.kek.*), if any.
+
+Only works with direct access to the store (api.enabled: false). If no passphrase is set it will 
+be requested.
+
+Example:
+
+  cfd import backup --file ca-backup.cfd`,
+	Run: importBackupFunc,
+}
+
+func init() {
+	importCmd.AddCommand(importBackupCmd)
+	importBackupCmd.Flags().StringVarP(&global.filename, "file", "f", "", "Backup file location. (required)")
+	importBackupCmd.Flags().StringVar(&global.passphrase, "passphrase", "", "Passphrase of the backup.")
+	importBackupCmd.MarkFlagRequired("file")
+}
+
+func importBackupFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv    *service.Service
+		sealed []byte
+		caID   string
+		err    error
+		ctx    context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	sealed, err = ioutil.ReadFile(global.filename)
+	er(err)
+
+	if global.passphrase == "" {
+		global.passphrase, err = promptPassword("Backup passphrase", validationRequired)
+		er(err)
+	}
+
+	caID, err = srv.BackupImport(ctx, sealed, global.passphrase)
+	switch {
+	case errors.Is(err, service.ErrServerOnly):
+		er(fmt.Errorf("%w, set api.enabled to false", err))
+	case err == rest.ErrConflict:
+		er(errors.New("the CA of the backup already exists on the data store"))
+	}
+	er(err)
+
+	echo(fmt.Sprintf("\n\nCA restored. ID: '%s'\n", caID))
+	if global.quiet {
+		fmt.Print(caID)
+	}
+
+}
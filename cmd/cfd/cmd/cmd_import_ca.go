@@ -0,0 +1,97 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// importCaCmd imports an existing CA
+var importCaCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Imports an existing CA certificate/key pair.",
+	Long: `Imports an existing CA certificate/key pair (openssl, mkcert CAROOT, ...).
+
+Certificate can be PEM (followed by its chain, if any) or DER. Key can be PKCS#1, SEC1 or PKCS#8 
+as PEM or DER. PKCS#12 bundles hold both, so no key file is required.
+
+If the key is held by a signer plugin (--signer), only the reference to the key is stored. Plugins
+are configured by name on ca.signers, where is set how they are reached: running a subprocess
+(exec:/path/to/plugin --flags) or by an unix socket (unix:/path). Only with direct access to the store.
+
+The CA will be identified by a new UUID, to operate with the imported CA you must be pass this ID on each request.`,
+	Run: importCaFunc,
+}
+
+func init() {
+	importCmd.AddCommand(importCaCmd)
+	importCaCmd.Flags().StringVarP(&global.certFile, "cert", "c", "", "CA certificate file location (or PKCS#12 bundle). (required)")
+	importCaCmd.Flags().StringVarP(&global.keyFile, "key", "k", "", "CA key file location. (required if not PKCS#12)")
+	importCaCmd.Flags().StringVar(&global.pfxPassword, "pfx-password", "", "PKCS#12 bundle password")
+	importCaCmd.Flags().StringVar(&global.signer, "signer", "", "Name of the signer plugin holding the CA key (as configured on ca.signers).")
+	importCaCmd.Flags().StringVar(&global.signerKeyID, "signer-key-id", "", "Key ID on the signer plugin.")
+	importCaCmd.MarkFlagRequired("cert")
+}
+
+func importCaFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv         *service.Service
+		request     client.APICAImportRequest
+		certificate client.Certificate
+		err         error
+		ctx         context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	request.Certificate, err = ioutil.ReadFile(global.certFile)
+	er(err)
+
+	switch {
+	case global.signer != "":
+		request.Key = manager.KeyReference(global.signer, global.signerKeyID)
+	case global.keyFile != "":
+		request.Key, err = ioutil.ReadFile(global.keyFile)
+		er(err)
+	}
+
+	request.Password = global.pfxPassword
+
+	certificate, err = srv.CAImport(ctx, request)
+	er(err)
+
+	echo(fmt.Sprintf("\n\nCA Imported. ID: '%s'\n", certificate.CAID))
+	if global.quiet {
+		fmt.Print(certificate.CAID)
+	}
+
+}
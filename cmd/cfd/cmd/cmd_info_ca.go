@@ -0,0 +1,95 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/fernandezvara/certsfor/internal/certinfo"
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+)
+
+// infoCaCmd represents the bootstrap command
+var infoCaCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Show (friendly) information about a CA.",
+	Long: `Show information about a CA of the store: its parent, the certificates issued and revoked, 
+if a rollover is in progress and the details of its certificate.`,
+	Run: infoCaFunc,
+}
+
+func init() {
+	infoCmd.AddCommand(infoCaCmd)
+	infoCaCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required) [$CFD_CA_ID]")
+	infoCaCmd.Flags().BoolVar(&global.bool1, "markdown", false, "Return the data formatted as markdown.")
+	infoCaCmd.Flags().BoolVar(&global.bool2, "csv", false, "Show as CSV.")
+}
+
+func infoCaFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		ca         client.APICA
+		certInfo   certinfo.CertInfo
+		t          table.Writer
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	ca, err = srv.CAInfo(ctx, collection)
+	er(err)
+
+	certInfo, err = certinfo.NewFromBytes(ca.Certificate)
+	er(err)
+
+	t = table.NewWriter()
+
+	t.SetOutputMirror(os.Stdout)
+	t.SetStyle(table.StyleLight)
+	t.Style().Format.Header = text.FormatTitle
+	t.AppendHeader(table.Row{"CA ID", "Parent CA ID", "Certificates", "Revoked", "Rollover"})
+	t.AppendRow(table.Row{ca.CAID, ca.ParentCAID, ca.Certificates, ca.Revoked, ca.Rollover})
+
+	switch {
+	case global.bool1:
+		t.RenderMarkdown()
+	case global.bool2:
+		t.RenderCSV()
+	default:
+		t.Render()
+	}
+
+	certInfo.Show(global.bool1, global.bool2)
+
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fernandezvara/certsfor/internal/certinfo"
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+)
+
+// listCaCmd represents the bootstrap command
+var listCaCmd = &cobra.Command{
+	Use:     "ca",
+	Aliases: []string{"cas"},
+	Short:   "List the CAs on the store",
+	Long: `List the CAs on the store with its CA ID, expiration and the number of certificates issued.
+
+Use the CA ID on the commands that require it (--ca-id or $CFD_CA_ID).`,
+	Run: listCaFunc,
+}
+
+func init() {
+	listCmd.AddCommand(listCaCmd)
+	listCaCmd.Flags().BoolVar(&global.bool1, "md", false, "Return as markdown formatted text")
+	listCaCmd.Flags().BoolVar(&global.bool2, "csv", false, "Return as CSV")
+}
+
+func listCaFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv *service.Service
+		cas []client.APICA
+		t   table.Writer
+		now time.Time = time.Now()
+		err error
+		ctx context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	cas, err = srv.CAList(ctx)
+	er(err)
+
+	t = table.NewWriter()
+
+	t.SetOutputMirror(os.Stdout)
+	t.SetStyle(table.StyleLight)
+	t.Style().Format.Header = text.FormatTitle
+	t.AppendHeader(table.Row{"CA ID", "Distinguished Name", "Expires in", "Certificates", "Parent CA ID"})
+
+	for _, ca := range cas {
+
+		var expires string
+
+		if now.Before(ca.NotAfter) {
+			expires = fmt.Sprintf("%s (%s)", certinfo.Remaining(ca.NotAfter.Sub(now)), ca.NotAfter.Format(timeFormat))
+		} else {
+			expires = fmt.Sprintf("Expired (%s)", ca.NotAfter.Format(timeFormat))
+		}
+
+		t.AppendRow(table.Row{
+			ca.CAID,
+			ca.Subject,
+			expires,
+			ca.Certificates,
+			ca.ParentCAID,
+		})
+	}
+
+	if global.bool1 {
+		t.RenderMarkdown()
+		return
+	}
+
+	if global.bool2 {
+		t.RenderCSV()
+		return
+	}
+
+	t.Render()
+
+}
@@ -28,6 +28,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/fernandezvara/certsfor/internal/certinfo"
 	"github.com/fernandezvara/certsfor/internal/manager"
 	"github.com/fernandezvara/certsfor/internal/service"
 	"github.com/fernandezvara/certsfor/pkg/client"
@@ -102,7 +103,7 @@ func listCertificateFunc(cmd *cobra.Command, args []string) {
 		er(err)
 
 		if now.Before(certificate.X509Certificate.NotAfter) {
-			expires = fmt.Sprintf("%d days (%s)", int64(certificate.X509Certificate.NotAfter.Sub(now).Hours())/int64(24), certificate.X509Certificate.NotAfter.Format(timeFormat))
+			expires = fmt.Sprintf("%s (%s)", certinfo.Remaining(certificate.X509Certificate.NotAfter.Sub(now)), certificate.X509Certificate.NotAfter.Format(timeFormat))
 		} else {
 			expires = fmt.Sprintf("Expired (%s)", certificate.X509Certificate.NotAfter.Format(timeFormat))
 		}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// logVerifyCmd checks the issuance log against the store
+var logVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Checks the CA issuance log against the store.",
+	Long: `Checks the CA issuance log against the store.
+
+The log entries must match the signed tree head, the tree head must be signed by the CA and every 
+certificate stored must be on the log. Certificates issued before the log existed are reported 
+as not logged.
+
+With --checkpoint the log must also contain the tree head saved on the file, so entries removed or 
+rewritten after the last verification are detected. The file is updated with the current tree 
+head if the log is verified (created if it does not exist). Keep it out of the store.
+
+Only works with direct access to the store (api.enabled: false).
+
+Example:
+
+  cfd log verify --ca-id <uuid> --checkpoint /secure/place/ca-log.json`,
+	Run: logVerifyFunc,
+}
+
+func init() {
+	logCmd.AddCommand(logVerifyCmd)
+	logVerifyCmd.Flags().StringVar(&global.filename, "checkpoint", "", "File with the tree head of the last verification.")
+}
+
+func logVerifyFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		checkpoint *client.APISignedTreeHead
+		head       client.APISignedTreeHead
+		problems   []string
+		bytesFile  []byte
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	if global.filename != "" {
+		bytesFile, err = ioutil.ReadFile(global.filename)
+		switch {
+		case err == nil:
+			checkpoint = &client.APISignedTreeHead{}
+			er(json.Unmarshal(bytesFile, checkpoint))
+		case os.IsNotExist(err):
+		default:
+			er(err)
+		}
+	}
+
+	head, problems, err = srv.LogVerify(ctx, collection, checkpoint)
+	if errors.Is(err, service.ErrServerOnly) {
+		er(fmt.Errorf("%w, set api.enabled to false", err))
+	}
+	er(err)
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			echo(fmt.Sprintf("  - %s\n", problem))
+		}
+		er(fmt.Errorf("issuance log verification failed, %d problems found", len(problems)))
+	}
+
+	if global.filename != "" {
+		bytesFile, err = json.MarshalIndent(head, "", "  ")
+		er(err)
+
+		er(ioutil.WriteFile(global.filename, bytesFile, 0600))
+	}
+
+	echo(fmt.Sprintf("\n\nIssuance log verified. Tree size: %d, root hash: %s\n", head.TreeSize, hex.EncodeToString(head.RootHash)))
+
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// profileGetCmd returns a profile as YAML
+var profileGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Returns a certificate profile of the CA as YAML.",
+	Long: `Returns a certificate profile of the CA as YAML.
+
+The profile is written to the standard output (console) if no file is passed.`,
+	Run: profileGetFunc,
+}
+
+func init() {
+	profileCmd.AddCommand(profileGetCmd)
+	profileGetCmd.Flags().StringVar(&global.profile, "name", "", "Profile name. (required)")
+	profileGetCmd.Flags().StringVarP(&global.filename, "file", "f", "", "Filename where the profile will be stored in YAML format.")
+	profileGetCmd.MarkFlagRequired("name")
+}
+
+func profileGetFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		profile    client.APIProfile
+		bytesFile  []byte
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	profile, err = srv.ProfileGet(ctx, collection, global.profile)
+	er(err)
+
+	bytesFile, err = yaml.Marshal(profile)
+	er(err)
+
+	if global.filename == "" {
+		global.filename = "stdout"
+	}
+
+	saveOrShowFile(global.filename, bytesFile, 0640)
+
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+)
+
+// profileListCmd lists the profiles of a CA
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the certificate profiles of the CA.",
+	Long:  `List the certificate profiles of the CA.`,
+	Run:   profileListFunc,
+}
+
+func init() {
+	profileCmd.AddCommand(profileListCmd)
+	profileListCmd.Flags().BoolVar(&global.bool1, "md", false, "Return as markdown formatted text")
+	profileListCmd.Flags().BoolVar(&global.bool2, "csv", false, "Return as CSV")
+}
+
+func profileListFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		profiles   []client.APIProfile
+		t          table.Writer
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	profiles, err = srv.ProfileList(ctx, collection)
+	er(err)
+
+	t = table.NewWriter()
+
+	t.SetOutputMirror(os.Stdout)
+	t.SetStyle(table.StyleLight)
+	t.Style().Format.Header = text.FormatTitle
+	t.AppendHeader(table.Row{"Name", "Key", "Expiration Days", "Extended Key Usages"})
+
+	for _, profile := range profiles {
+
+		var (
+			key  string = profile.Fixed.Key
+			exp  int64  = profile.Fixed.ExpirationDays
+			ekus        = profile.Fixed.ExtKeyUsage
+		)
+
+		if key == "" {
+			key = profile.Defaults.Key
+		}
+		if exp == 0 {
+			exp = profile.Defaults.ExpirationDays
+		}
+		if len(ekus) == 0 {
+			ekus = profile.Defaults.ExtKeyUsage
+		}
+
+		t.AppendRow(table.Row{
+			profile.Name,
+			key,
+			exp,
+			strings.Join(ekus, ", "),
+		})
+	}
+
+	if global.bool1 {
+		t.RenderMarkdown()
+		return
+	}
+
+	if global.bool2 {
+		t.RenderCSV()
+		return
+	}
+
+	t.Render()
+
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// profileSetCmd creates or replaces a profile
+var profileSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Creates or replaces a certificate profile of the CA.",
+	Long: `Creates or replaces a certificate profile of the CA from a YAML file.
+
+Example:
+
+  name: server
+  defaults:
+    key: ecdsa:256
+    exp: 90
+  fixed:
+    dn:
+      o: Example
+    client: false
+    ext_key_usage:
+    - serverAuth`,
+	Run: profileSetFunc,
+}
+
+func init() {
+	profileCmd.AddCommand(profileSetCmd)
+	profileSetCmd.Flags().StringVarP(&global.filename, "file", "f", "", "File with the profile in YAML format. (required)")
+	profileSetCmd.Flags().StringVar(&global.profile, "name", "", "Profile name (defaults to the one on the file).")
+	profileSetCmd.MarkFlagRequired("file")
+}
+
+func profileSetFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		profile    client.APIProfile
+		bytesInput []byte
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	bytesInput, err = ioutil.ReadFile(global.filename)
+	er(err)
+
+	err = yaml.Unmarshal(bytesInput, &profile)
+	er(err)
+
+	if global.profile != "" {
+		profile.Name = global.profile
+	}
+
+	profile, err = srv.ProfileSet(ctx, collection, profile)
+	er(err)
+
+	echo(fmt.Sprintf("\n\nProfile Saved. '%s'\n", profile.Name))
+
+}
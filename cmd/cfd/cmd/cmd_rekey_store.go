@@ -0,0 +1,96 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// rekeyStoreCmd encrypts the CA keys with a new key encryption key
+var rekeyStoreCmd = &cobra.Command{
+	Use:   "rekey-store",
+	Short: "Encrypts again the private keys stored with a new key encryption key.",
+	Long: `Encrypts again the private keys stored with a new key encryption key (KEK).
+
+Keys are decrypted with the KEK configured (db.kek.*) and encrypted with the new one, keys stored 
+without encryption are encrypted too. Once finished, update the configuration to use the new KEK.
+If it fails while writing the keys, run it again with the same new KEK: keys already encrypted 
+with it are kept as are.
+
+Only works with direct access to the store (api.enabled: false). Use --ca-id as many times as 
+CAs to process. If no new KEK is set a passphrase will be requested.`,
+	Run: rekeyStoreFunc,
+}
+
+func init() {
+	rootCmd.AddCommand(rekeyStoreCmd)
+	rekeyStoreCmd.Flags().StringArrayVar(&global.collections, "ca-id", []string{}, "CA Identifier, can be repeated. (defaults to the one configured) [$CFD_CA_ID]")
+	rekeyStoreCmd.Flags().StringVar(&global.passphrase, "new-passphrase", "", "Passphrase of the new key encryption key.")
+	rekeyStoreCmd.Flags().StringVar(&global.kekFile, "new-key-file", "", "File with the new key encryption key (32 bytes, base64 or hexadecimal).")
+	rekeyStoreCmd.Flags().BoolVar(&global.bool1, "decrypt", false, "Store the keys without encryption.")
+}
+
+func rekeyStoreFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv         *service.Service
+		collections []string = global.collections
+		kek         *manager.KEK
+		count       int
+		err         error
+		ctx         context.Context = context.Background()
+	)
+
+	if len(collections) == 0 {
+		collections = []string{collectionOrExit()}
+	}
+
+	if !global.bool1 && global.passphrase == "" && global.kekFile == "" {
+		global.passphrase, err = promptPassword("New passphrase", validationRequired)
+		er(err)
+	}
+
+	srv = buildService()
+	defer srv.Close()
+
+	if !global.bool1 {
+		kek, err = buildKEK(srv, global.passphrase, global.kekFile, "")
+	}
+
+	if err == nil {
+		count, err = srv.RekeyStore(ctx, collections, kek)
+	}
+	if errors.Is(err, service.ErrServerOnly) {
+		er(fmt.Errorf("%w, set api.enabled to false", err))
+	}
+	er(err)
+
+	echo(fmt.Sprintf("\n\n%d keys rewritten.\n", count))
+
+}
@@ -0,0 +1,83 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// renewCmd renews a certificate now
+var renewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renews a certificate now.",
+	Long: `Renews a certificate now and writes its files.
+
+The key remains the same unless --rekey is used or the certificate renewal policy is rekey, then a 
+new key pair is created. Certificates signed from a CSR cannot be rekeyed.
+
+To write to the standard output (console) the file contents (cert, key, bundle, ca-cert) use 'out' or 'stdout'.`,
+	Run: renewFunc,
+}
+
+func init() {
+	rootCmd.AddCommand(renewCmd)
+	renewCmd.Flags().StringVarP(&global.certFile, "cert", "c", "", "Certificate file location.")
+	renewCmd.Flags().StringVar(&global.caCertFile, "ca-cert", "", "CA Certificate file location.")
+	renewCmd.Flags().StringVarP(&global.bundleFile, "bundle", "b", "", "Bundle file location.")
+	renewCmd.Flags().StringVarP(&global.keyFile, "key", "k", "", "Key file location.")
+	renewCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required). [$CFD_CA_ID]")
+	renewCmd.Flags().StringVar(&global.cn, "cn", "", "Common Name. (required)")
+	renewCmd.Flags().BoolVar(&global.bool1, "rekey", false, "Create a new key pair.")
+	renewCmd.Flags().StringVar(&global.pfxFile, "pfx", "", "pfx file location")
+	renewCmd.Flags().StringVar(&global.pfxPassword, "pfx-password", "changeit", "pfx password")
+	renewCmd.MarkFlagRequired("cn")
+}
+
+func renewFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		cert       client.Certificate
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	// a 100% remaining lifetime renews the certificate always
+	cert, err = srv.CertificateRenew(ctx, collection, global.cn, 100, global.bool1)
+	er(err)
+
+	saveFiles(cert.CACertificate, cert.Certificate, cert.Key)
+
+	echo("\n\nCertificate Renewed.")
+
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// revokeCmd represents the bootstrap command
+var revokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revokes a certificate by its Common Name.",
+	Long: `Revokes a certificate by its Common Name.
+
+The certificate is kept on the store, but it will be published on the CA certificate revocation list (CRL)
+and it will not be renewed anymore.
+
+Reasons allowed: unspecified, keyCompromise, cACompromise, affiliationChanged, superseded,
+cessationOfOperation, certificateHold, privilegeWithdrawn, aACompromise.`,
+	Run: revokeFunc,
+}
+
+func init() {
+	rootCmd.AddCommand(revokeCmd)
+	revokeCmd.Flags().BoolVarP(&global.bool1, "yes", "y", false, "Asumme yes to the prompts (is assumed if --quiet)")
+	revokeCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required). [$CFD_CA_ID]")
+	revokeCmd.Flags().StringVar(&global.cn, "cn", "", "Common Name. (required)")
+	revokeCmd.Flags().StringVar(&global.reason, "reason", client.ReasonUnspecified, "Revocation reason.")
+	revokeCmd.MarkFlagRequired("cn")
+}
+
+func revokeFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		revocation client.Revocation
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	// if quiet assume yes
+	if global.quiet {
+		global.bool1 = global.quiet
+	}
+
+	// run interactively?
+	if !global.bool1 {
+		global.bool1, err = promptTrueFalseBool("Revocation cannot be undone. Are you sure?", "Yes", "No", false)
+		er(err)
+	}
+
+	if global.bool1 {
+		revocation, err = srv.CertificateRevoke(ctx, collection, global.cn, client.APIRevocationRequest{Reason: global.reason})
+		er(err)
+
+		echo(fmt.Sprintf("\n\nCertificate Revoked. Serial: '%s'\n", revocation.Serial))
+	}
+
+}
@@ -0,0 +1,132 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// sshCmd holds all `ssh` commands
+var sshCmd = &cobra.Command{
+	Use:   "ssh",
+	Short: "SSH certificate authority commands.",
+	Long: `SSH certificate authority commands.
+
+Every CA has its own SSH CA that signs OpenSSH user and host certificates. Its key is an Ed25519 key,
+different from the CA one, created when the first certificate is signed. The ssh_* rules of the CA
+policy limit the principals, extensions and validity of the certificates.`,
+}
+
+func init() {
+	rootCmd.AddCommand(sshCmd)
+	sshCmd.PersistentFlags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required). [$CFD_CA_ID]")
+}
+
+// sshSignFlags adds the flags shared by the ssh sign commands
+func sshSignFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&global.keyFile, "key", "k", "", "OpenSSH public key file to sign. (required)")
+	cmd.Flags().StringVarP(&global.certFile, "cert", "c", "", "Where to store the certificate. (Default: the key file with -cert.pub suffix)")
+	cmd.Flags().StringSliceVar(&global.principals, "principals", []string{}, "Principals the certificate is valid for, comma separated. (required)")
+	cmd.Flags().StringVar(&global.keyID, "key-id", "", "Key identifier logged by sshd. (Default: the first principal)")
+	cmd.Flags().StringVar(&global.validity, "validity", "", "Time the certificate will be valid as a duration (ex: 8h). (Default: 24h)")
+	cmd.MarkFlagRequired("key")
+	cmd.MarkFlagRequired("principals")
+}
+
+// sshSign signs the public key file as a certificate of the type passed
+func sshSign(certType string) {
+
+	var (
+		srv         *service.Service
+		collection  string
+		request     client.APISSHRequest
+		certificate client.APISSHCertificate
+		err         error
+		ctx         context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	request.PublicKey, err = ioutil.ReadFile(global.keyFile)
+	er(err)
+
+	request.Principals = global.principals
+	request.KeyID = global.keyID
+	request.Validity = global.validity
+
+	if certType == client.SSHUser {
+		request.CriticalOptions, err = sshValues(global.options)
+		er(err)
+
+		request.Extensions, err = sshValues(global.extensions)
+		er(err)
+	}
+
+	certificate, err = srv.SSHSign(ctx, collection, certType, request)
+	er(err)
+
+	if global.certFile == "" {
+		global.certFile = fmt.Sprintf("%s-cert.pub", strings.TrimSuffix(global.keyFile, ".pub"))
+	}
+
+	saveOrShowFile(global.certFile, certificate.Certificate, 0644)
+
+	echo(fmt.Sprintf("\n\nCertificate Created. Serial: %d, valid until %s\n", certificate.Serial, certificate.ValidBefore.Format(timeFormat)))
+
+}
+
+// sshValues returns the name=value pairs as a map, names without value get an empty one. nil if
+// there are no values
+func sshValues(pairs []string) (map[string]string, error) {
+
+	var values map[string]string
+
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if parts[0] == "" {
+			return nil, fmt.Errorf("invalid value: '%s'", pair)
+		}
+
+		if values == nil {
+			values = make(map[string]string)
+		}
+
+		values[parts[0]] = ""
+		if len(parts) == 2 {
+			values[parts[0]] = parts[1]
+		}
+	}
+
+	return values, nil
+
+}
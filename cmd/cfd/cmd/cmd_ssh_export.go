@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// sshExportCmd returns the SSH CA public key
+var sshExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Returns the SSH CA public key to trust its certificates.",
+	Long: `Returns the SSH CA public key to trust its certificates.
+
+By default the public key is returned as a TrustedUserCAKeys line, for the servers to trust the user 
+certificates. With --known-hosts the @cert-authority line is returned instead, for the clients to 
+trust the host certificates of the hosts matching --hosts.
+
+The SSH CA is created when the first certificate is signed, until then there is no key to export.
+
+The result is written to the standard output (console) if no file is passed.
+
+Example:
+
+  cfd ssh export --ca-id <uuid> -f /etc/ssh/trusted_user_ca_keys
+  cfd ssh export --ca-id <uuid> --known-hosts --hosts '*.example.com' >> ~/.ssh/known_hosts`,
+	Run: sshExportFunc,
+}
+
+func init() {
+	sshCmd.AddCommand(sshExportCmd)
+	sshExportCmd.Flags().BoolVar(&global.bool1, "known-hosts", false, "Return the @cert-authority known_hosts line.")
+	sshExportCmd.Flags().StringVar(&global.hosts, "hosts", "*", "Hosts pattern of the known_hosts line.")
+	sshExportCmd.Flags().StringVarP(&global.filename, "file", "f", "", "Filename where the line will be stored.")
+}
+
+func sshExportFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv        *service.Service
+		collection string
+		ca         client.APISSHCA
+		err        error
+		ctx        context.Context = context.Background()
+	)
+
+	srv = buildService()
+	defer srv.Close()
+
+	collection = collectionOrExit()
+
+	ca, err = srv.SSHCA(ctx, collection, global.hosts)
+	er(err)
+
+	if global.filename == "" {
+		global.filename = "stdout"
+	}
+
+	if global.bool1 {
+		saveOrShowFile(global.filename, ca.KnownHosts, 0644)
+		return
+	}
+
+	saveOrShowFile(global.filename, ca.PublicKey, 0644)
+
+}
@@ -0,0 +1,55 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// sshSignHostCmd signs an OpenSSH host certificate
+var sshSignHostCmd = &cobra.Command{
+	Use:   "sign-host",
+	Short: "Signs an OpenSSH host public key as a host certificate.",
+	Long: `Signs an OpenSSH host public key as a host certificate.
+
+Principals are the names clients use to connect to the host. The certificate is set on sshd with 
+HostCertificate, clients trust it adding the @cert-authority line to its known_hosts file 
+(see 'cfd ssh export --known-hosts').
+
+Example:
+
+  cfd ssh sign-host --ca-id <uuid> -k /etc/ssh/ssh_host_ed25519_key.pub --principals web1,web1.example.com --validity 720h`,
+	Run: sshSignHostFunc,
+}
+
+func init() {
+	sshCmd.AddCommand(sshSignHostCmd)
+	sshSignFlags(sshSignHostCmd)
+}
+
+func sshSignHostFunc(cmd *cobra.Command, args []string) {
+
+	sshSign(client.SSHHost)
+
+}
@@ -0,0 +1,57 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// sshSignUserCmd signs an OpenSSH user certificate
+var sshSignUserCmd = &cobra.Command{
+	Use:   "sign-user",
+	Short: "Signs an OpenSSH public key as a user certificate.",
+	Long: `Signs an OpenSSH public key as a user certificate.
+
+Principals are the user names the certificate allows to log in as. If no extension is passed, the 
+certificate gets the ones set by ssh-keygen (permit-pty, permit-agent-forwarding...). Servers trust 
+the certificates adding the SSH CA public key to its TrustedUserCAKeys file (see 'cfd ssh export').
+
+Example:
+
+  cfd ssh sign-user --ca-id <uuid> -k ~/.ssh/id_ed25519.pub --principals alice,deploy --validity 8h`,
+	Run: sshSignUserFunc,
+}
+
+func init() {
+	sshCmd.AddCommand(sshSignUserCmd)
+	sshSignFlags(sshSignUserCmd)
+	sshSignUserCmd.Flags().StringSliceVar(&global.options, "option", []string{}, "Critical option as name=value (force-command, source-address).")
+	sshSignUserCmd.Flags().StringSliceVar(&global.extensions, "extension", []string{}, "Extension as name or name=value (ex: permit-pty). Replaces the default ones.")
+}
+
+func sshSignUserFunc(cmd *cobra.Command, args []string) {
+
+	sshSign(client.SSHUser)
+
+}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fernandezvara/certsfor/db/store"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// storeMigrateCmd copies all the data from one store to other
+var storeMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copies all the CAs and certificates from one data store to other.",
+	Long: `Copies all the CAs and certificates from one data store to other.
+
+Every collection (CAs, revocations, issuance logs, ...) and item is copied as is, private keys 
+encrypted with a key encryption key remain encrypted with the same key. After copying, the items 
+count and checksum of every collection are verified on the destination.
+
+The source defaults to the store configured (db.type, db.connection). The destination must not 
+have any of the collections to copy. Stop the API while migrating so no changes are lost.
+
+With --dry-run the source is read and the checksums are shown, but nothing is written.
+
+Example:
+
+  cfd store migrate --from-type badger --from ~/.cfd/db --to-type sql --to postgres://cfd@db/cfd`,
+	Run: storeMigrateFunc,
+}
+
+func init() {
+	storeCmd.AddCommand(storeMigrateCmd)
+	storeMigrateCmd.Flags().StringVar(&global.fromType, "from-type", "", "Source store driver. (defaults to db.type)")
+	storeMigrateCmd.Flags().StringVar(&global.from, "from", "", "Source store connection. (defaults to db.connection)")
+	storeMigrateCmd.Flags().StringVar(&global.toType, "to-type", "", "Destination store driver. (required)")
+	storeMigrateCmd.Flags().StringVar(&global.to, "to", "", "Destination store connection. (required)")
+	storeMigrateCmd.Flags().BoolVar(&global.bool1, "dry-run", false, "Read the source and show what would be copied, without writing.")
+}
+
+func storeMigrateFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		from, to store.Store
+		migrated []store.MigratedCollection
+		items    int
+		err      error
+		ctx      context.Context = context.Background()
+	)
+
+	if global.fromType == "" {
+		global.fromType = viper.GetString(configDBType)
+	}
+
+	if global.from == "" {
+		global.from = viper.GetString(configDBConnectionString)
+	}
+
+	if global.toType == "" || global.to == "" {
+		fmt.Println("Select the destination store (--to-type, --to)")
+		fmt.Println()
+		cmd.Help()
+		er(fmt.Errorf("destination store not set"))
+	}
+
+	if global.fromType == global.toType && global.from == global.to {
+		er(fmt.Errorf("source and destination are the same store"))
+	}
+
+	from, err = store.Open(ctx, global.fromType, global.from)
+	er(err)
+	defer from.Close()
+
+	to, err = store.Open(ctx, global.toType, global.to)
+	er(err)
+	defer to.Close()
+
+	migrated, err = store.Migrate(ctx, from, to, global.bool1)
+
+	for _, collection := range migrated {
+		echo(fmt.Sprintf("  %-48s %6d items  sha256:%s", collection.Collection, collection.Items, collection.Checksum))
+		items += collection.Items
+	}
+
+	er(err)
+
+	if global.bool1 {
+		echo(fmt.Sprintf("\n\nDry run, %d collections (%d items) would be copied from %s to %s.\n", len(migrated), items, global.fromType, global.toType))
+		return
+	}
+
+	echo(fmt.Sprintf("\n\nStore migrated, %d collections (%d items) copied and verified from %s to %s.\n", len(migrated), items, global.fromType, global.toType))
+
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2020 @fernandezvara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fernandezvara/certsfor/internal/certinfo"
+	"github.com/fernandezvara/certsfor/internal/service"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd checks a certificate against a CA
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verifies a certificate chain, its validity, key usages and hostname.",
+	Long: `Verifies a certificate. The certificate can be read from:
+
+  - File (it can include the intermediate certificates)
+  - Common Name (--ca-id required)
+  - Remote URL (the chain sent by the server is used)
+
+The chain is built up to the CA of the store (--ca-id) or to the CA certificates on a bundle 
+file (--bundle). The validity periods, extended key usages and hostname or IP address are 
+checked. If the verification fails the reason is shown and the exit code is 1.
+
+When --url is used the hostname is taken from it unless --hostname is set. If a hostname is 
+verified the certificate must be valid for server authentication unless --usage is set.
+
+Examples:
+
+  cfd verify --ca-id <uuid> --file web.pem --hostname www.example.com
+  cfd verify --bundle ca.pem --url https://www.example.com
+  cfd verify --ca-id <uuid> --cn client1 --usage client`,
+	Run: verifyFunc,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required if --cn or no --bundle) [$CFD_CA_ID]")
+	verifyCmd.Flags().StringVar(&global.cn, "cn", "", "Common name of the certificate to verify.")
+	verifyCmd.Flags().StringVarP(&global.filename, "file", "f", "", "Source file with the certificate.")
+	verifyCmd.Flags().StringVarP(&global.url, "url", "u", "", "URL to get the certificate from.")
+	verifyCmd.Flags().Int64Var(&timeout, "timeout", 5, "Timeout for network calls (only used if --url is especified).")
+	verifyCmd.Flags().StringVarP(&global.bundleFile, "bundle", "b", "", "File with the trusted CA certificates (instead of the CA of the store).")
+	verifyCmd.Flags().StringVar(&global.hostname, "hostname", "", "DNS name or IP address the certificate must be valid for.")
+	verifyCmd.Flags().StringSliceVar(&global.usages, "usage", []string{}, "Extended key usages the certificate must be valid for: any, server, client, email, code-signing, ocsp-signing, timestamping.")
+}
+
+func verifyFunc(cmd *cobra.Command, args []string) {
+
+	var (
+		srv      *service.Service
+		certInfo certinfo.CertInfo
+		trusted  certinfo.CertInfo
+		opts     certinfo.VerifyOptions
+		chain    []*x509.Certificate
+		usage    x509.ExtKeyUsage
+		err      error
+	)
+
+	if global.cn == "" && global.filename == "" && global.url == "" {
+		fmt.Println("Select the certificate to verify")
+		fmt.Println()
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	if global.cn != "" || global.bundleFile == "" {
+		srv = buildService()
+		defer srv.Close()
+	}
+
+	switch {
+	case global.cn != "":
+		certInfo = verifyFromStore(srv, global.cn)
+	case global.filename != "":
+		certInfo, err = certinfo.NewFromFile(global.filename)
+		er(err)
+	default:
+		// the server certificate is not verified on the connection, it is verified below
+		certInfo, err = certinfo.NewFromURL(global.url, timeout, true)
+		er(err)
+
+		if global.hostname == "" {
+			global.hostname, err = certinfo.URLHostname(global.url)
+			er(err)
+		}
+	}
+
+	if global.bundleFile != "" {
+		trusted, err = certinfo.NewFromFile(global.bundleFile)
+		er(err)
+	} else {
+		trusted = verifyFromStore(srv, "ca")
+	}
+
+	opts.Roots, opts.Intermediates = certinfo.SplitTrusted(trusted.Certificates())
+	opts.Hostname = global.hostname
+
+	for _, name := range global.usages {
+		usage, err = certinfo.ExtKeyUsage(name)
+		er(err)
+		opts.KeyUsages = append(opts.KeyUsages, usage)
+	}
+
+	if len(opts.KeyUsages) == 0 && opts.Hostname != "" {
+		opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+
+	chain, err = certInfo.Verify(opts)
+	if err != nil {
+		// the reason is always shown, it is the point of the command
+		fmt.Fprintf(os.Stderr, "Verification failed: %s\n", err)
+		os.Exit(1)
+	}
+
+	echo("\nCertificate verified.\n")
+	for i, cert := range chain {
+		echo(fmt.Sprintf("  %*s%s (valid until %s, %s)", i*2, "", cert.Subject.CommonName, cert.NotAfter.Local().Format("02/01/2006 15:04"), certinfo.Remaining(time.Until(cert.NotAfter))))
+	}
+	echo("")
+
+}
+
+// verifyFromStore returns the certificate with its CA chain from the store
+func verifyFromStore(srv *service.Service, cn string) certinfo.CertInfo {
+
+	var (
+		response client.Certificate
+		certInfo certinfo.CertInfo
+		err      error
+	)
+
+	response, err = srv.CertificateGet(context.Background(), collectionOrExit(), cn, 0)
+	er(err)
+
+	certInfo, err = certinfo.NewFromBytes(append(response.Certificate, response.CACertificate...))
+	er(err)
+
+	return certInfo
+
+}
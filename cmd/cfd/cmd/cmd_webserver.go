@@ -50,7 +50,7 @@ func init() {
 	startCmd.AddCommand(webserverCmd)
 	webserverCmd.Flags().StringVar(&global.collection, "ca-id", "", "CA Identifier. (required). [$CFD_CA_ID]")
 	webserverCmd.Flags().StringVar(&global.cn, "cn", "", "Common Name. (required).")
-	webserverCmd.Flags().IntVar(&global.remaining, "renew", 20, "Time (expresed as percent) to be used to determine if the certificate must be renewed (defaults to 20 %). Key remains the same.")
+	webserverCmd.Flags().IntVar(&global.remaining, "renew", 20, "Time (expresed as percent) to be used to determine if the certificate must be renewed (defaults to 20 %). Key remains the same unless the certificate renewal policy is rekey.")
 	webserverCmd.Flags().StringVar(&global.listen, "listen", "0.0.0.0:8443", "IP:TCP Port where the server will be served. Defaults to all network interfaces and port 8443.")
 	webserverCmd.Flags().StringVar(&global.root, "root", ".", "Directory where the files reside, defaults to current (.).")
 	webserverCmd.MarkFlagRequired("cn")
@@ -143,7 +143,8 @@ func buildTLSConfig(cert client.Certificate) (tlsConfig tls.Config) {
 
 	tlsConfig.NextProtos = []string{"h2", "http/1.1"}
 
-	certificate, err = tls.X509KeyPair(cert.Certificate, cert.Key)
+	// serve the full chain so intermediate CAs can be verified by the clients
+	certificate, err = tls.X509KeyPair(append(append([]byte{}, cert.Certificate...), cert.CACertificate...), cert.Key)
 	er(err)
 
 	tlsConfig.Certificates = []tls.Certificate{certificate}
@@ -50,6 +50,7 @@ func certTemplate() (request client.APICertificateRequest) {
 	request.DN.CN = "common-name"
 	request.Key = client.RSA4096
 	request.ExpirationDays = 90
+	request.Renewal = client.RenewalReuseKey
 	return
 
 }
@@ -77,7 +78,7 @@ func interactiveCertificate(request *client.APICertificateRequest, isCertificate
 	er(err)
 	request.DN.OU, err = promptText("Organizational Unit (optional)", request.DN.OU, nil)
 	er(err)
-	request.SAN, err = promptArray("Hosts and IPs. (blank if finish)")
+	request.SAN, err = promptArray("Hosts, IPs, emails and URIs. Type can be forced using dns:, ip:, email:, uri: or upn: as prefix. (blank if finish)")
 	er(err)
 	expires, err = promptText("Expires in (days)", strconv.Itoa(int(request.ExpirationDays)), validationInteger)
 	er(err)
@@ -92,6 +93,7 @@ func interactiveCertificate(request *client.APICertificateRequest, isCertificate
 		"ECDSA (EC-256)",
 		"ECDSA (EC-384)",
 		"ECDSA (EC-521)",
+		"Ed25519",
 	}
 	values := []string{
 		client.RSA2048,
@@ -101,6 +103,7 @@ func interactiveCertificate(request *client.APICertificateRequest, isCertificate
 		client.ECDSA256,
 		client.ECDSA384,
 		client.ECDSA521,
+		client.ED25519,
 	}
 
 	request.Key, err = promptSelection("Key Algorithm", items, values, 2)
@@ -110,6 +113,14 @@ func interactiveCertificate(request *client.APICertificateRequest, isCertificate
 	if isCertificate {
 		request.Client, err = promptTrueFalseBool("Client Certificate?", "Yes", "No", false)
 		er(err)
+		request.Renewal, err = promptSelection("Renewal", []string{"Reuse the key", "New key on each renewal"}, []string{client.RenewalReuseKey, client.RenewalRekey}, 0)
+		er(err)
+	} else {
+		var pathLength string
+		pathLength, err = promptText("Path length (intermediate CAs allowed below, -1: unlimited)", strconv.Itoa(request.PathLength), validationSignedInteger)
+		er(err)
+		request.PathLength, err = strconv.Atoi(pathLength)
+		er(err)
 	}
 
 }
@@ -132,29 +143,39 @@ func collectionOrExit() (collection string) {
 func saveFiles(ca, bytesCert, bytesKey []byte) {
 
 	var (
-		pfxData                          []byte
-		blockCert, blockCaCert, blockKey *pem.Block
-		cert, caCert                     *x509.Certificate
-		key                              interface{}
-		err                              error
+		pfxData             []byte
+		blockCert, blockKey *pem.Block
+		cert                *x509.Certificate
+		caCerts             []*x509.Certificate
+		key                 interface{}
+		err                 error
 	)
 
-	// pkcs12
-	if global.pfxFile != "" {
+	// pkcs12 (certificates signed from a CSR have no key)
+	if global.pfxFile != "" && len(bytesKey) > 0 {
 
 		blockCert, _ = pem.Decode(bytesCert)
 		cert, err = x509.ParseCertificate(blockCert.Bytes)
 		er(err)
 
-		blockCaCert, _ = pem.Decode(ca)
-		caCert, err = x509.ParseCertificate(blockCaCert.Bytes)
-		er(err)
+		// ca holds the full chain, from the issuer to the root
+		for rest := ca; ; {
+			var blockCaCert *pem.Block
+			blockCaCert, rest = pem.Decode(rest)
+			if blockCaCert == nil {
+				break
+			}
+			var caCert *x509.Certificate
+			caCert, err = x509.ParseCertificate(blockCaCert.Bytes)
+			er(err)
+			caCerts = append(caCerts, caCert)
+		}
 
 		blockKey, _ = pem.Decode(bytesKey)
 		key, err = x509.ParsePKCS8PrivateKey(blockKey.Bytes)
 		er(err)
 
-		pfxData, err = pkcs12.Encode(rand.Reader, key, cert, []*x509.Certificate{caCert}, global.pfxPassword)
+		pfxData, err = pkcs12.Encode(rand.Reader, key, cert, caCerts, global.pfxPassword)
 		er(err)
 
 		saveOrShowFile(global.pfxFile, pfxData, 0400)
@@ -162,7 +183,9 @@ func saveFiles(ca, bytesCert, bytesKey []byte) {
 	}
 
 	saveOrShowFile(global.certFile, bytesCert, 0400)
-	saveOrShowFile(global.keyFile, bytesKey, 0400)
+	if len(bytesKey) > 0 {
+		saveOrShowFile(global.keyFile, bytesKey, 0400)
+	}
 	saveOrShowFile(global.bundleFile, append(bytesCert, ca...), 0400)
 	saveOrShowFile(global.caCertFile, ca, 0400)
 
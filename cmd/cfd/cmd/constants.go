@@ -35,24 +35,47 @@ var Version = "snapshot"
 
 // global variables
 type globalConfig struct {
-	home        string // $HOME
-	cfgFile     string // configuration file location
-	filename    string // file to parse/store/execute the action
-	certFile    string // certificate file location
-	caCertFile  string // ca certificate file location
-	bundleFile  string // bundle file location
-	keyFile     string // key file location
-	collection  string // ca id <-> colelction
-	cn          string // common name as argument
-	bool1       bool   // common bool option
-	bool2       bool   // common bool option
-	remaining   int    // remaining percert (integer) for expiration
-	listen      string // webserver ip:port to listen
-	url         string // url to consult for certificates
-	root        string // webserver root
-	quiet       bool   // run in quiet mode
-	pfxFile     string // pfx file to save
-	pfxPassword string // password for pfx file
+	home        string   // $HOME
+	cfgFile     string   // configuration file location
+	filename    string   // file to parse/store/execute the action
+	certFile    string   // certificate file location
+	caCertFile  string   // ca certificate file location
+	bundleFile  string   // bundle file location
+	keyFile     string   // key file location
+	collection  string   // ca id <-> colelction
+	cn          string   // common name as argument
+	bool1       bool     // common bool option
+	bool2       bool     // common bool option
+	remaining   int      // remaining percert (integer) for expiration
+	listen      string   // webserver ip:port to listen
+	url         string   // url to consult for certificates
+	root        string   // webserver root
+	quiet       bool     // run in quiet mode
+	pfxFile     string   // pfx file to save
+	pfxPassword string   // password for pfx file
+	csrFile     string   // certificate signing request to sign
+	reason      string   // revocation reason
+	serial      string   // certificate serial number (hexadecimal)
+	collections []string // ca ids, for commands that operate with several CAs
+	passphrase  string   // key encryption key passphrase
+	kekFile     string   // key encryption key file location
+	keyType     string   // key algorithm
+	days        int64    // expiration days
+	signer      string   // signer plugin uri
+	signerKeyID string   // key id on the signer plugin
+	profile     string   // certificate profile name
+	validity    string   // certificate validity as a duration
+	principals  []string // ssh certificate principals
+	keyID       string   // ssh certificate key id
+	options     []string // ssh certificate critical options (name=value)
+	extensions  []string // ssh certificate extensions (name or name=value)
+	hosts       string   // known_hosts hosts pattern
+	hostname    string   // hostname or ip address to verify the certificate for
+	usages      []string // extended key usages to verify
+	fromType    string   // source store driver
+	from        string   // source store connection
+	toType      string   // destination store driver
+	to          string   // destination store connection
 }
 
 // detect home folder
@@ -105,6 +128,15 @@ var (
 	configDBConnectionString        = "db.connection"
 	configDBConnectionStringEnv     = "CFD_DB_CONNECTION"
 	configDBConnectionStringDefault string
+	configDBKEKPassphrase           = "db.kek.passphrase"
+	configDBKEKPassphraseEnv        = "CFD_DB_KEK_PASSPHRASE"
+	configDBKEKPassphraseDefault    = ""
+	configDBKEKFile                 = "db.kek.file"
+	configDBKEKFileEnv              = "CFD_DB_KEK_FILE"
+	configDBKEKFileDefault          = ""
+	configDBKEKKey                  = "db.kek.key"
+	configDBKEKKeyEnv               = "CFD_DB_KEK"
+	configDBKEKKeyDefault           = ""
 
 	// api config
 	configAPIEnabled          = "api.enabled"
@@ -143,6 +175,21 @@ var (
 	configTLSUseForceEnv                     = "CFD_TLS_FORCE"
 	configTLSUseForceDefault                 = false
 
+	// ca
+	configCACRLNextUpdate        = "ca.crl.next_update"
+	configCACRLNextUpdateEnv     = "CFD_CA_CRL_NEXT_UPDATE"
+	configCACRLNextUpdateDefault = "24h"
+	configCAOCSPURL              = "ca.ocsp.url"
+	configCAOCSPURLEnv           = "CFD_CA_OCSP_URL"
+	configCAOCSPURLDefault       = ""
+	configCAOCSPDelegated        = "ca.ocsp.delegated"
+	configCAOCSPDelegatedEnv     = "CFD_CA_OCSP_DELEGATED"
+	configCAOCSPDelegatedDefault = false
+	configCABackdate             = "ca.backdate"
+	configCABackdateEnv          = "CFD_CA_BACKDATE"
+	configCABackdateDefault      = "0s"
+	configCASigners              = "ca.signers"
+
 	// ca id
 	configCAID        = "ca-id"
 	configCAIDEnv     = "CFD_CA_ID"
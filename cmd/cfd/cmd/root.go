@@ -66,6 +66,12 @@ func initConfig() {
 	viper.BindEnv(configDBType, configDBTypeEnv)
 	viper.SetDefault(configDBConnectionString, configDBConnectionStringDefault)
 	viper.BindEnv(configDBConnectionString, configDBConnectionStringEnv)
+	viper.SetDefault(configDBKEKPassphrase, configDBKEKPassphraseDefault)
+	viper.BindEnv(configDBKEKPassphrase, configDBKEKPassphraseEnv)
+	viper.SetDefault(configDBKEKFile, configDBKEKFileDefault)
+	viper.BindEnv(configDBKEKFile, configDBKEKFileEnv)
+	viper.SetDefault(configDBKEKKey, configDBKEKKeyDefault)
+	viper.BindEnv(configDBKEKKey, configDBKEKKeyEnv)
 
 	// api
 	viper.SetDefault(configAPIAddr, configAPIAddrDefault)
@@ -95,6 +101,16 @@ func initConfig() {
 	viper.SetDefault(configTLSUseForce, configTLSUseForceDefault)
 	viper.BindEnv(configTLSUseForce, configTLSUseForceEnv)
 
+	// ca
+	viper.SetDefault(configCACRLNextUpdate, configCACRLNextUpdateDefault)
+	viper.BindEnv(configCACRLNextUpdate, configCACRLNextUpdateEnv)
+	viper.SetDefault(configCAOCSPURL, configCAOCSPURLDefault)
+	viper.BindEnv(configCAOCSPURL, configCAOCSPURLEnv)
+	viper.SetDefault(configCAOCSPDelegated, configCAOCSPDelegatedDefault)
+	viper.BindEnv(configCAOCSPDelegated, configCAOCSPDelegatedEnv)
+	viper.SetDefault(configCABackdate, configCABackdateDefault)
+	viper.BindEnv(configCABackdate, configCABackdateEnv)
+
 	// ca id
 	viper.SetDefault(configCAID, configCAIDDefault)
 	viper.BindEnv(configCAID, configCAIDEnv)
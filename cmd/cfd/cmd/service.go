@@ -24,11 +24,16 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"io/ioutil"
 
 	_ "github.com/fernandezvara/certsfor/db/badger"    // store driver
+	_ "github.com/fernandezvara/certsfor/db/files"     // store driver
 	_ "github.com/fernandezvara/certsfor/db/firestore" // store driver
+	_ "github.com/fernandezvara/certsfor/db/sql"       // store driver
 	"github.com/fernandezvara/certsfor/db/store"
+	"github.com/fernandezvara/certsfor/internal/manager"
 	"github.com/fernandezvara/certsfor/internal/service"
 	"github.com/fernandezvara/certsfor/pkg/client"
 	"github.com/spf13/viper"
@@ -57,8 +62,49 @@ func buildService() (srv *service.Service) {
 		)
 		er(err)
 		srv = service.NewAsServer(sto, Version)
+		configureService(srv)
 	}
 
 	return
 
 }
+
+// configureService sets the CA settings for a service working as server
+func configureService(srv *service.Service) {
+
+	srv.SetCRLNextUpdate(viper.GetDuration(configCACRLNextUpdate))
+	srv.SetOCSPURL(viper.GetString(configCAOCSPURL))
+	srv.SetOCSPDelegated(viper.GetBool(configCAOCSPDelegated))
+	srv.SetBackdate(viper.GetDuration(configCABackdate))
+
+	er(manager.SetSigners(viper.GetStringMapString(configCASigners)))
+
+	kek, err := buildKEK(srv, viper.GetString(configDBKEKPassphrase), viper.GetString(configDBKEKFile), viper.GetString(configDBKEKKey))
+	er(err)
+	srv.SetKEK(kek)
+
+	// certificates stored before the serial number index existed
+	er(srv.SerialsReindex(context.Background()))
+
+}
+
+// buildKEK returns the key encryption key from a passphrase (derived with the salt of the store), a
+// file holding the key or the key itself (base64 or hexadecimal), nil if none is set
+func buildKEK(srv *service.Service, passphrase, file, key string) (*manager.KEK, error) {
+
+	switch {
+	case passphrase != "":
+		return srv.KEKFromPassphrase(context.Background(), passphrase)
+	case file != "":
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return manager.KEKFromBytes(bytes.TrimSpace(content))
+	case key != "":
+		return manager.KEKFromBytes([]byte(key))
+	}
+
+	return nil, nil
+
+}
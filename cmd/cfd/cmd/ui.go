@@ -195,3 +195,14 @@ func validationInteger(input string) error {
 	return nil
 
 }
+
+// validationSignedInteger validates the string is an integer, negative values allowed
+func validationSignedInteger(input string) error {
+
+	if _, err := strconv.ParseInt(input, 10, 64); err != nil {
+		return errNotInteger
+	}
+
+	return nil
+
+}
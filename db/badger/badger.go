@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/dgraph-io/badger"
 	"github.com/fernandezvara/certsfor/db/store"
@@ -189,6 +190,58 @@ func (b Badger) Delete(ctx context.Context, collection, id string) (ok bool, err
 
 }
 
+// Collections returns the names of all the datasets on the storage
+func (b Badger) Collections(ctx context.Context) (collections []string, err error) {
+
+	var seen = make(map[string]bool)
+
+	err = b.db.View(func(txn *badger.Txn) error {
+
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+
+			collection := strings.SplitN(string(it.Item().Key()), "/", 2)[0]
+			if !seen[collection] {
+				seen[collection] = true
+				collections = append(collections, collection)
+			}
+
+		}
+		return nil
+	})
+
+	return
+
+}
+
+// IDs returns the IDs of all the items of the dataset
+func (b Badger) IDs(ctx context.Context, collection string) (ids []string, err error) {
+
+	var prefix = key(collection, "")
+
+	err = b.db.View(func(txn *badger.Txn) error {
+
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			ids = append(ids, string(it.Item().Key()[len(prefix):]))
+		}
+		return nil
+	})
+
+	return
+
+}
+
 // Ping returns nil (TODO: review)
 func (b Badger) Ping(ctx context.Context) error {
 	return nil
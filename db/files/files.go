@@ -0,0 +1,454 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/certsfor/db/store"
+	"github.com/fernandezvara/rest"
+)
+
+func init() {
+	store.Register("files", &Driver{})
+}
+
+const (
+	lockFile      = ".lock"
+	tempPrefix    = ".tmp-"
+	jsonExtension = ".json"
+	certExtension = ".crt.pem"
+	keyExtension  = ".key.pem"
+)
+
+// gitignore keeps the lock and temporary files out of the repository if the directory is versioned
+var gitignore = []byte(lockFile + "*\n" + tempPrefix + "*\n")
+
+// Driver initializes a Files struct and returns it
+type Driver struct {
+}
+
+// Open creates the Files struct, configures and returns it
+//
+// connection = directory where the data will be stored
+func (f Driver) Open(ctx context.Context, connection string) (store.Store, error) {
+
+	var (
+		store Files
+		err   error
+	)
+
+	store.root = connection
+
+	err = os.MkdirAll(connection, 0750)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = os.Stat(filepath.Join(connection, ".gitignore"))
+	if os.IsNotExist(err) {
+		err = writeFile(filepath.Join(connection, ".gitignore"), gitignore, 0640)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+
+}
+
+// Files is the storage driver to manage data as files, one directory per collection and one JSON
+// file per item. The certificate and key are also written as PEM files next to the item, so the
+// directory can be versioned and its changes reviewed. Writes are atomic (written to a temporary
+// file and renamed) and all the operations hold an advisory lock, so several processes can share it
+// (this configuration is not High Available)
+type Files struct {
+	root string
+}
+
+// Get retrieves a value from the storage and unmarshals it to the required type
+func (f Files) Get(ctx context.Context, collection, id string, value interface{}) (err error) {
+
+	var (
+		v      []byte
+		unlock func() error
+	)
+
+	unlock, err = f.lock(false)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	v, err = ioutil.ReadFile(f.path(collection, id, jsonExtension))
+	if os.IsNotExist(err) {
+		return rest.ErrNotFound
+	}
+
+	if err != nil {
+		return
+	}
+
+	return json.Unmarshal(v, value)
+
+}
+
+// GetAll retrieves all the items for the required dataset
+func (f Files) GetAll(ctx context.Context, collection string) (values []map[string]interface{}, err error) {
+
+	var (
+		names  []string
+		unlock func() error
+	)
+
+	unlock, err = f.lock(false)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	names, err = filepath.Glob(filepath.Join(f.root, escape(collection), "*"+jsonExtension))
+	if err != nil {
+		return
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		var (
+			v     []byte
+			value map[string]interface{} = make(map[string]interface{})
+		)
+
+		if strings.HasPrefix(filepath.Base(name), tempPrefix) {
+			continue
+		}
+
+		v, err = ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(v, &value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		values = append(values, value)
+
+	}
+
+	if values == nil {
+		err = rest.ErrNotFound
+	}
+
+	return
+
+}
+
+// Set inserts/updates a item in the dataset
+func (f Files) Set(ctx context.Context, collection, id string, value interface{}) (err error) {
+
+	var (
+		v      []byte
+		item   pemItem
+		unlock func() error
+	)
+
+	v, err = json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return
+	}
+
+	// certificate and key, if the value has them
+	_ = json.Unmarshal(v, &item)
+
+	unlock, err = f.lock(true)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = os.MkdirAll(filepath.Join(f.root, escape(collection)), 0750)
+	if err != nil {
+		return
+	}
+
+	err = f.setPEM(f.path(collection, id, certExtension), item.Certificate, 0640)
+	if err != nil {
+		return
+	}
+
+	err = f.setPEM(f.path(collection, id, keyExtension), item.Key, 0600)
+	if err != nil {
+		return
+	}
+
+	// the item is written last, it is the one read
+	return writeFile(f.path(collection, id, jsonExtension), append(v, '\n'), 0600)
+
+}
+
+// Delete removes the required ID on the dataset
+func (f Files) Delete(ctx context.Context, collection, id string) (ok bool, err error) {
+
+	var (
+		unlock func() error
+	)
+
+	unlock, err = f.lock(true)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = os.Remove(f.path(collection, id, jsonExtension))
+	if os.IsNotExist(err) {
+		return false, rest.ErrNotFound
+	}
+
+	if err != nil {
+		return
+	}
+
+	for _, extension := range []string{certExtension, keyExtension} {
+		err = os.Remove(f.path(collection, id, extension))
+		if err != nil && !os.IsNotExist(err) {
+			return
+		}
+	}
+
+	// the dataset is gone with its last item, as on the other stores (fails if not empty)
+	_ = os.Remove(filepath.Join(f.root, escape(collection)))
+
+	return true, nil
+
+}
+
+// Collections returns the names of all the datasets on the storage
+func (f Files) Collections(ctx context.Context) (collections []string, err error) {
+
+	var (
+		infos  []os.FileInfo
+		unlock func() error
+	)
+
+	unlock, err = f.lock(false)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	infos, err = ioutil.ReadDir(f.root)
+	if err != nil {
+		return
+	}
+
+	for _, info := range infos {
+
+		var collection string
+
+		if !info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+
+		collection, err = url.PathUnescape(info.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		collections = append(collections, collection)
+
+	}
+
+	return
+
+}
+
+// IDs returns the IDs of all the items of the dataset
+func (f Files) IDs(ctx context.Context, collection string) (ids []string, err error) {
+
+	var (
+		names  []string
+		unlock func() error
+	)
+
+	unlock, err = f.lock(false)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	names, err = filepath.Glob(filepath.Join(f.root, escape(collection), "*"+jsonExtension))
+	if err != nil {
+		return
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		var id string
+
+		name = filepath.Base(name)
+		if strings.HasPrefix(name, tempPrefix) {
+			continue
+		}
+
+		id, err = url.PathUnescape(strings.TrimSuffix(name, jsonExtension))
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+
+	}
+
+	return
+
+}
+
+// Ping returns a non-nil error if the directory is not accessible
+func (f Files) Ping(ctx context.Context) error {
+
+	_, err := os.Stat(f.root)
+	return err
+
+}
+
+// Close releases the resources associated with the Store.
+func (f Files) Close() error {
+	return nil
+}
+
+// pemItem are the values written as PEM files
+type pemItem struct {
+	Certificate []byte `json:"certificate"`
+	Key         []byte `json:"key"`
+}
+
+// setPEM writes the PEM file if the content is PEM encoded, removes it otherwise
+func (f Files) setPEM(name string, content []byte, perm os.FileMode) error {
+
+	if block, _ := pem.Decode(content); block != nil {
+		return writeFile(name, content, perm)
+	}
+
+	err := os.Remove(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+
+}
+
+// Lock holds the exclusive advisory lock of the name, used to serialize the operations that read
+// and write several items (ex: the issuance log appends) between processes
+func (f Files) Lock(ctx context.Context, name string) (unlock func() error, err error) {
+	return f.flock(lockFile+"-"+escape(name), true)
+}
+
+// lock holds the advisory lock of the directory, shared to read or exclusive to write. Returns the
+// function that releases it
+func (f Files) lock(exclusive bool) (func() error, error) {
+	return f.flock(lockFile, exclusive)
+}
+
+// flock holds the advisory lock of the file, created if it does not exist
+func (f Files) flock(name string, exclusive bool) (func() error, error) {
+
+	file, err := os.OpenFile(filepath.Join(f.root, name), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFileHandle(file, exclusive)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return func() error {
+		unlockFileHandle(file)
+		return file.Close()
+	}, nil
+
+}
+
+// path returns the file of the item with the extension
+func (f Files) path(collection, id, extension string) string {
+	return filepath.Join(f.root, escape(collection), escape(id)+extension)
+}
+
+// writeFile writes the content on a temporary file on the same directory and renames it, so
+// readers always see the previous or the new content
+func writeFile(name string, content []byte, perm os.FileMode) (err error) {
+
+	var (
+		temp *os.File
+	)
+
+	temp, err = ioutil.TempFile(filepath.Dir(name), tempPrefix)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	_, err = temp.Write(content)
+	if err != nil {
+		return
+	}
+
+	err = temp.Sync()
+	if err != nil {
+		return
+	}
+
+	err = temp.Chmod(perm)
+	if err != nil {
+		return
+	}
+
+	err = temp.Close()
+	if err != nil {
+		return
+	}
+
+	return os.Rename(temp.Name(), name)
+
+}
+
+// escape returns the name usable as file name, characters not allowed (or with special meaning)
+// on the file systems are encoded as %XX
+func escape(name string) string {
+
+	var builder strings.Builder
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '@':
+			builder.WriteByte(c)
+		case c == '.' && i > 0:
+			builder.WriteByte(c)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+
+	return builder.String()
+
+}
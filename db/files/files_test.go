@@ -0,0 +1,208 @@
+package files
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fernandezvara/certsfor/db/store"
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterfaces(t *testing.T) {
+
+	assert.Implements(t, (*store.Store)(nil), new(Files))
+	assert.Implements(t, (*store.Driver)(nil), new(Driver))
+	assert.Implements(t, (*store.Locker)(nil), new(Files))
+
+}
+
+func TestLock(t *testing.T) {
+
+	var (
+		ctx      context.Context = context.Background()
+		acquired chan bool       = make(chan bool)
+	)
+
+	dir, err := ioutil.TempDir("", "cfd")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sto, err := store.Open(ctx, "files", dir)
+	assert.Nil(t, err)
+
+	unlock, err := sto.(store.Locker).Lock(ctx, "collection.log")
+	assert.Nil(t, err)
+
+	go func() {
+		unlockOther, err := sto.(store.Locker).Lock(ctx, "collection.log")
+		assert.Nil(t, err)
+		acquired <- true
+		assert.Nil(t, unlockOther())
+	}()
+
+	// must block, the lock is held
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// other names are not locked, and the items can be used
+	unlockName, err := sto.(store.Locker).Lock(ctx, "other")
+	assert.Nil(t, err)
+	assert.Nil(t, unlockName())
+	assert.Nil(t, sto.Set(ctx, "collection.log", "a", map[string]string{"a": "a"}))
+
+	assert.Nil(t, unlock())
+	assert.True(t, <-acquired)
+
+}
+
+func TestEscape(t *testing.T) {
+
+	for name, expected := range map[string]string{
+		"ca":                   "ca",
+		"uuid.revocations":     "uuid.revocations",
+		"*.example.com":        "%2A.example.com",
+		"..":                   "%2E.",
+		"a/b\\c":               "a%2Fb%5Cc",
+		"user@example.com":     "user@example.com",
+		"00000000000000000001": "00000000000000000001",
+	} {
+		assert.Equal(t, expected, escape(name))
+	}
+
+}
+
+func TestFiles(t *testing.T) {
+
+	type item struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	var (
+		ctx   context.Context = context.Background()
+		value item
+	)
+
+	dir, err := ioutil.TempDir("", "cfd")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sto, err := store.Open(ctx, "files", filepath.Join(dir, "data"))
+	assert.Nil(t, err)
+	assert.Nil(t, sto.Ping(ctx))
+	assert.FileExists(t, filepath.Join(dir, "data", ".gitignore"))
+
+	// must fail, not found
+	assert.Equal(t, rest.ErrNotFound, sto.Get(ctx, "collection", "a", &value))
+	_, err = sto.GetAll(ctx, "collection")
+	assert.Equal(t, rest.ErrNotFound, err)
+	_, err = sto.Delete(ctx, "collection", "a")
+	assert.Equal(t, rest.ErrNotFound, err)
+
+	assert.Nil(t, sto.Set(ctx, "collection", "a", item{Name: "a", Count: 1}))
+	assert.Nil(t, sto.Set(ctx, "collection", "*.b", item{Name: "b", Count: 2}))
+	assert.Nil(t, sto.Set(ctx, "collection.other", "a", item{Name: "other", Count: 3}))
+
+	// update
+	assert.Nil(t, sto.Set(ctx, "collection", "a", item{Name: "a", Count: 10}))
+	assert.Nil(t, sto.Get(ctx, "collection", "a", &value))
+	assert.Equal(t, item{Name: "a", Count: 10}, value)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "data", "collection", "a.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"name\": \"a\",\n  \"count\": 10\n}\n", string(content))
+
+	values, err := sto.GetAll(ctx, "collection")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []map[string]interface{}{
+		{"name": "a", "count": float64(10)},
+		{"name": "b", "count": float64(2)},
+	}, values)
+
+	ids, err := sto.IDs(ctx, "collection")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"*.b", "a"}, ids)
+
+	collections, err := sto.Collections(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"collection", "collection.other"}, collections)
+
+	ids, err = sto.IDs(ctx, "does-not-exist")
+	assert.Nil(t, err)
+	assert.Len(t, ids, 0)
+
+	// certificates and keys are written as PEM too
+	certPEM, keyPEM, err := manager.New(client.APICertificateRequest{DN: client.APIDN{CN: "pem"}, Key: client.ECDSA256, ExpirationDays: 1})
+	assert.Nil(t, err)
+	assert.Nil(t, sto.Set(ctx, "collection", "pem", client.Certificate{Certificate: certPEM, Key: keyPEM}))
+
+	content, err = ioutil.ReadFile(filepath.Join(dir, "data", "collection", "pem.crt.pem"))
+	assert.Nil(t, err)
+	assert.Equal(t, certPEM, content)
+
+	info, err := os.Stat(filepath.Join(dir, "data", "collection", "pem.key.pem"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the key is removed if the item does not have it anymore
+	assert.Nil(t, sto.Set(ctx, "collection", "pem", client.Certificate{Certificate: certPEM}))
+	assert.NoFileExists(t, filepath.Join(dir, "data", "collection", "pem.key.pem"))
+
+	ok, err := sto.Delete(ctx, "collection", "pem")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.NoFileExists(t, filepath.Join(dir, "data", "collection", "pem.crt.pem"))
+	assert.Equal(t, rest.ErrNotFound, sto.Get(ctx, "collection", "pem", &value))
+
+	// empty datasets are removed
+	ok, err = sto.Delete(ctx, "collection.other", "a")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	collections, err = sto.Collections(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"collection"}, collections)
+	assert.NoDirExists(t, filepath.Join(dir, "data", "collection.other"))
+
+	// and created again with its first item
+	assert.Nil(t, sto.Set(ctx, "collection.other", "a", item{Name: "other", Count: 4}))
+
+	collections, err = sto.Collections(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"collection", "collection.other"}, collections)
+
+	// concurrent writers and readers always see complete items
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				var read item
+				assert.Nil(t, sto.Set(ctx, "concurrent", "item", item{Name: "concurrent", Count: i*100 + j}))
+				assert.Nil(t, sto.Get(ctx, "concurrent", "item", &read))
+				assert.Equal(t, "concurrent", read.Name)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// no temporary files left
+	names, err := filepath.Glob(filepath.Join(dir, "data", "*", tempPrefix+"*"))
+	assert.Nil(t, err)
+	assert.Len(t, names, 0)
+
+	assert.Nil(t, sto.Close())
+
+}
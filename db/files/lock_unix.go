@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package files
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFileHandle blocks until the advisory lock of the file is held
+func lockFileHandle(file *os.File, exclusive bool) error {
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	return syscall.Flock(int(file.Fd()), how)
+
+}
+
+// unlockFileHandle releases the advisory lock of the file
+func unlockFileHandle(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,27 @@
+//go:build windows
+// +build windows
+
+package files
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFileHandle blocks until the lock of the file is held
+func lockFileHandle(file *os.File, exclusive bool) error {
+
+	var flags uint32
+	if exclusive {
+		flags = windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+
+	return windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, &windows.Overlapped{})
+
+}
+
+// unlockFileHandle releases the lock of the file
+func unlockFileHandle(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
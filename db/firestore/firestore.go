@@ -8,6 +8,7 @@ import (
 	firebase "firebase.google.com/go"
 	"github.com/fernandezvara/certsfor/db/store"
 	"github.com/fernandezvara/rest"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -109,6 +110,48 @@ func (f Firestore) Delete(ctx context.Context, collection, id string) (ok bool,
 	return
 }
 
+// Collections returns the names of all the datasets on the storage
+func (f Firestore) Collections(ctx context.Context) (collections []string, err error) {
+
+	var (
+		ref *firestore.CollectionRef
+		it  *firestore.CollectionIterator = f.client.Collections(ctx)
+	)
+
+	for {
+		ref, err = it.Next()
+		if err == iterator.Done {
+			return collections, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, ref.ID)
+	}
+
+}
+
+// IDs returns the IDs of all the items of the dataset
+func (f Firestore) IDs(ctx context.Context, collection string) (ids []string, err error) {
+
+	var (
+		ref *firestore.DocumentRef
+		it  *firestore.DocumentRefIterator = f.client.Collection(collection).DocumentRefs(ctx)
+	)
+
+	for {
+		ref, err = it.Next()
+		if err == iterator.Done {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, ref.ID)
+	}
+
+}
+
 // Ping returns a non-nil error if the Store is not healthy or if the
 // connection to the persistence is compromised.
 func (f Firestore) Ping(ctx context.Context) error { return nil }
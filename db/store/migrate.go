@@ -0,0 +1,221 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+)
+
+// migration errors
+var (
+	ErrDestinationNotEmpty = errors.New("store: destination already has the collection")
+	ErrMigrationMismatch   = errors.New("store: destination does not match the source")
+)
+
+// MigratedCollection is the result of copying a collection, the checksum covers the IDs and the
+// values of all its items
+type MigratedCollection struct {
+	Collection string
+	Items      int
+	Checksum   string
+}
+
+// Migrate copies every collection and item from one store to other and verifies the destination
+// has the same items (counts and checksums). The destination must not have any of the collections.
+// With dryRun the source is read and the checks are done, but nothing is written
+func Migrate(ctx context.Context, from, to Store, dryRun bool) (migrated []MigratedCollection, err error) {
+
+	var (
+		collections []string
+		existing    []string
+		exists      = make(map[string]bool)
+	)
+
+	collections, err = from.Collections(ctx)
+	if err != nil {
+		return
+	}
+	sort.Strings(collections)
+
+	existing, err = to.Collections(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, collection := range existing {
+		exists[collection] = true
+	}
+
+	for _, collection := range collections {
+		if exists[collection] {
+			return nil, fmt.Errorf("%w: %s", ErrDestinationNotEmpty, collection)
+		}
+	}
+
+	for _, collection := range collections {
+
+		var result MigratedCollection
+
+		result, err = migrateCollection(ctx, from, to, collection, dryRun)
+		if err != nil {
+			return
+		}
+
+		migrated = append(migrated, result)
+
+	}
+
+	if dryRun {
+		return
+	}
+
+	for _, result := range migrated {
+
+		var copied MigratedCollection
+
+		copied, err = checksumCollection(ctx, to, result.Collection)
+		if err != nil {
+			return
+		}
+
+		if copied != result {
+			return migrated, fmt.Errorf("%w: %s has %d items (checksum %s), expected %d items (checksum %s)",
+				ErrMigrationMismatch, result.Collection, copied.Items, copied.Checksum, result.Items, result.Checksum)
+		}
+
+	}
+
+	return
+
+}
+
+// migrateCollection copies the items of the collection (if not dryRun) and returns its checksum
+func migrateCollection(ctx context.Context, from, to Store, collection string, dryRun bool) (result MigratedCollection, err error) {
+
+	return walkCollection(ctx, from, collection, func(id string, value Item) error {
+
+		if dryRun {
+			return nil
+		}
+
+		return to.Set(ctx, collection, id, value)
+
+	})
+
+}
+
+// checksumCollection returns the items count and checksum of the collection
+func checksumCollection(ctx context.Context, sto Store, collection string) (MigratedCollection, error) {
+
+	return walkCollection(ctx, sto, collection, func(id string, value Item) error {
+		return nil
+	})
+
+}
+
+// walkCollection reads every item of the collection (sorted by ID) calling fn, returns the items
+// count and checksum
+func walkCollection(ctx context.Context, sto Store, collection string, fn func(id string, value Item) error) (result MigratedCollection, err error) {
+
+	var (
+		ids      []string
+		checksum hash.Hash = sha256.New()
+	)
+
+	ids, err = sto.IDs(ctx, collection)
+	if err != nil {
+		return
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+
+		var (
+			value     Item
+			canonical []byte
+		)
+
+		err = sto.Get(ctx, collection, id, &value)
+		if err != nil {
+			return result, fmt.Errorf("%s/%s: %w", collection, id, err)
+		}
+
+		// maps are marshaled with the keys sorted
+		canonical, err = json.Marshal(value)
+		if err != nil {
+			return
+		}
+
+		fmt.Fprintf(checksum, "%s\x00%s\n", id, canonical)
+
+		err = fn(id, value)
+		if err != nil {
+			return result, fmt.Errorf("%s/%s: %w", collection, id, err)
+		}
+
+	}
+
+	result.Collection = collection
+	result.Items = len(ids)
+	result.Checksum = hex.EncodeToString(checksum.Sum(nil))
+
+	return
+
+}
+
+// Item is a stored value copied between stores. Numbers are decoded as integers when possible, so
+// they are not rounded as float64 and all the stores keep them as numbers
+type Item map[string]interface{}
+
+// UnmarshalJSON decodes the value keeping the integers
+func (i *Item) UnmarshalJSON(data []byte) error {
+
+	var (
+		value   map[string]interface{}
+		decoder *json.Decoder = json.NewDecoder(bytes.NewReader(data))
+	)
+
+	decoder.UseNumber()
+
+	err := decoder.Decode(&value)
+	if err != nil {
+		return err
+	}
+
+	*i = normalize(value).(map[string]interface{})
+
+	return nil
+
+}
+
+// normalize replaces the json.Number values by int64 or float64
+func normalize(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = normalize(element)
+		}
+	case []interface{}:
+		for index, element := range v {
+			v[index] = normalize(element)
+		}
+	case json.Number:
+		if integer, err := v.Int64(); err == nil {
+			return integer
+		}
+		if float, err := v.Float64(); err == nil {
+			return float
+		}
+		return v.String()
+	}
+
+	return value
+
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/fernandezvara/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore keeps the values as JSON, as the stores do
+type memoryStore struct {
+	items   map[string]map[string][]byte
+	discard bool // Set does nothing
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{items: make(map[string]map[string][]byte)}
+}
+
+func (s *memoryStore) Get(ctx context.Context, collection, id string, value interface{}) (err error) {
+	v, ok := s.items[collection][id]
+	if !ok {
+		return rest.ErrNotFound
+	}
+	return json.Unmarshal(v, value)
+}
+func (s *memoryStore) GetAll(ctx context.Context, collection string) (values []map[string]interface{}, err error) {
+	return nil, errors.New("not used")
+}
+func (s *memoryStore) Set(ctx context.Context, collection, id string, value interface{}) (err error) {
+	if s.discard {
+		return nil
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if s.items[collection] == nil {
+		s.items[collection] = make(map[string][]byte)
+	}
+	s.items[collection][id] = v
+	return nil
+}
+func (s *memoryStore) Delete(ctx context.Context, collection, id string) (ok bool, err error) {
+	delete(s.items[collection], id)
+	return true, nil
+}
+func (s *memoryStore) Collections(ctx context.Context) (collections []string, err error) {
+	for collection := range s.items {
+		collections = append(collections, collection)
+	}
+	return
+}
+func (s *memoryStore) IDs(ctx context.Context, collection string) (ids []string, err error) {
+	for id := range s.items[collection] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return
+}
+func (s *memoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+func TestMigrate(t *testing.T) {
+
+	type value struct {
+		Name   string   `json:"name"`
+		Big    int64    `json:"big"`
+		Ratio  float64  `json:"ratio"`
+		Bytes  []byte   `json:"bytes"`
+		Values []string `json:"values"`
+	}
+
+	var (
+		ctx  context.Context = context.Background()
+		from *memoryStore    = newMemoryStore()
+		to   *memoryStore    = newMemoryStore()
+		read value
+	)
+
+	assert.Implements(t, (*Store)(nil), from)
+
+	assert.Nil(t, from.Set(ctx, "ca1", "ca", value{Name: "ca", Big: 1<<62 + 1, Ratio: 0.5, Bytes: []byte("pem")}))
+	assert.Nil(t, from.Set(ctx, "ca1", "leaf", value{Name: "leaf", Values: []string{"a", "b"}}))
+	assert.Nil(t, from.Set(ctx, "ca1.revocations", "01", value{Name: "revoked"}))
+
+	// dry run, nothing is written
+	migrated, err := Migrate(ctx, from, to, true)
+	assert.Nil(t, err)
+	assert.Len(t, migrated, 2)
+	assert.Equal(t, "ca1", migrated[0].Collection)
+	assert.Equal(t, 2, migrated[0].Items)
+	assert.Equal(t, "ca1.revocations", migrated[1].Collection)
+	assert.Equal(t, 1, migrated[1].Items)
+	assert.Len(t, to.items, 0)
+
+	dryRun := migrated
+
+	migrated, err = Migrate(ctx, from, to, false)
+	assert.Nil(t, err)
+	assert.Equal(t, dryRun, migrated)
+
+	assert.Nil(t, to.Get(ctx, "ca1", "ca", &read))
+	assert.Equal(t, value{Name: "ca", Big: 1<<62 + 1, Ratio: 0.5, Bytes: []byte("pem")}, read)
+	assert.Len(t, to.items, len(from.items))
+	for collection, items := range from.items {
+		assert.Len(t, to.items[collection], len(items))
+		for id, v := range items {
+			assert.JSONEq(t, string(v), string(to.items[collection][id]))
+		}
+	}
+
+	// must fail, the destination has the collections
+	_, err = Migrate(ctx, from, to, false)
+	assert.True(t, errors.Is(err, ErrDestinationNotEmpty))
+
+	// must fail, the items are not written
+	discard := newMemoryStore()
+	discard.discard = true
+	_, err = Migrate(ctx, from, discard, false)
+	assert.True(t, errors.Is(err, ErrMigrationMismatch))
+
+}
@@ -63,6 +63,12 @@ type Store interface {
 	// Delete removes the required ID on the dataset
 	Delete(ctx context.Context, collection, id string) (ok bool, err error)
 
+	// Collections returns the names of all the datasets on the storage
+	Collections(ctx context.Context) (collections []string, err error)
+
+	// IDs returns the IDs of all the items of the dataset (empty if the dataset does not exist)
+	IDs(ctx context.Context, collection string) (ids []string, err error)
+
 	// Ping returns a non-nil error if the Store is not healthy or if the
 	// connection to the persistence is compromised.
 	Ping(ctx context.Context) error
@@ -70,3 +76,12 @@ type Store interface {
 	// Close releases the resources associated with the Store.
 	Close() error
 }
+
+// Locker is implemented by the stores that can be shared by several processes (ex: files, or a
+// PostgreSQL database behind several API servers). The lock serializes the operations that read and
+// write several items
+type Locker interface {
+
+	// Lock blocks until the lock of the name is held, returns the function that releases it
+	Lock(ctx context.Context, name string) (unlock func() error, err error)
+}
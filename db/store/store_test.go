@@ -21,6 +21,12 @@ func (s storeMock) Set(ctx context.Context, collection, id string, value interfa
 func (s storeMock) Delete(ctx context.Context, collection, id string) (ok bool, err error) {
 	return true, nil
 }
+func (s storeMock) Collections(ctx context.Context) (collections []string, err error) {
+	return []string{}, nil
+}
+func (s storeMock) IDs(ctx context.Context, collection string) (ids []string, err error) {
+	return []string{}, nil
+}
 func (s storeMock) Ping(ctx context.Context) error {
 	return nil
 }
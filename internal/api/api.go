@@ -48,6 +48,14 @@ func (a *API) Start(apiPort string, tlsCertificate, tlsKey, tlsCaCert string, re
 				Handler: a.getStatus,
 				Matcher: []string{""},
 			},
+			"/v1/ca": {
+				Handler: a.getCAs,
+				Matcher: []string{"", ""},
+			},
+			"/v1/ca/:caid": {
+				Handler: a.getCA,
+				Matcher: []string{"", "", ""},
+			},
 			"/v1/ca/:caid/certificates/:cn": {
 				Handler: a.getCertificate,
 				Matcher: []string{"", "", "", "", "[a-zA-Z0-9.-_]+"},
@@ -56,24 +64,119 @@ func (a *API) Start(apiPort string, tlsCertificate, tlsKey, tlsCaCert string, re
 				Handler: a.getCertificates,
 				Matcher: []string{"", "", "", ""},
 			},
+			"/v1/ca/:caid/serials/:serial": {
+				Handler: a.getCertificateBySerial,
+				Matcher: []string{"", "", "", "", "[a-fA-F0-9:]+"},
+			},
+			"/v1/ca/:caid/crl": {
+				Handler: a.getCRL,
+				Matcher: []string{"", "", "", ""},
+			},
+			"/v1/ca/:caid/ocsp/*request": {
+				Handler: a.getOCSP,
+			},
+			"/v1/ca/:caid/profiles/:name": {
+				Handler: a.getProfile,
+				Matcher: []string{"", "", "", "", "[a-zA-Z0-9._-]+"},
+			},
+			"/v1/ca/:caid/profiles": {
+				Handler: a.getProfiles,
+				Matcher: []string{"", "", "", ""},
+			},
+			"/v1/ca/:caid/policy": {
+				Handler: a.getPolicy,
+				Matcher: []string{"", "", "", ""},
+			},
+			"/v1/ca/:caid/ssh": {
+				Handler: a.getSSHCA,
+				Matcher: []string{"", "", "", ""},
+			},
+			"/v1/ca/:caid/log/sth": {
+				Handler: a.getLogHead,
+				Matcher: []string{"", "", "", "", ""},
+			},
+			"/v1/ca/:caid/log/entries": {
+				Handler: a.getLogEntries,
+				Matcher: []string{"", "", "", "", ""},
+			},
+			"/v1/ca/:caid/log/proof/inclusion": {
+				Handler: a.getLogInclusionProof,
+				Matcher: []string{"", "", "", "", "", ""},
+			},
+			"/v1/ca/:caid/log/proof/consistency": {
+				Handler: a.getLogConsistencyProof,
+				Matcher: []string{"", "", "", "", "", ""},
+			},
 		},
 		"POST": {
 			"/v1/ca": {
 				Handler: a.postCA,
 				Matcher: []string{"", ""},
 			},
+			"/v1/import/ca": {
+				Handler: a.postImportCA,
+				Matcher: []string{"", "", ""},
+			},
+			"/v1/ca/:caid/intermediates": {
+				Handler: a.postIntermediate,
+				Matcher: []string{"", "", "", ""},
+			},
+			"/v1/ca/:caid/csr": {
+				Handler: a.postCSR,
+				Matcher: []string{"", "", "", ""},
+			},
+			"/v1/ca/:caid/certificates/:cn/revoke": {
+				Handler: a.postRevoke,
+				Matcher: []string{"", "", "", "", "[a-zA-Z0-9.-_]+", ""},
+			},
+			"/v1/ca/:caid/ocsp": {
+				Handler: a.postOCSP,
+				Matcher: []string{"", "", "", ""},
+			},
+			"/v1/ca/:caid/rollover": {
+				Handler: a.postRollover,
+				Matcher: []string{"", "", "", ""},
+			},
+			"/v1/ca/:caid/ssh/:type": {
+				Handler: a.postSSHSign,
+				Matcher: []string{"", "", "", "", "^(user|host)$"},
+			},
 		},
 		"PUT": {
 			"/v1/ca/:caid/certificates/:cn": {
 				Handler: a.putCertificate,
 				Matcher: []string{"", "", "", "", "[a-zA-Z0-9.-_]+"},
 			},
+			"/v1/ca/:caid/profiles/:name": {
+				Handler: a.putProfile,
+				Matcher: []string{"", "", "", "", "[a-zA-Z0-9._-]+"},
+			},
+			"/v1/ca/:caid/policy": {
+				Handler: a.putPolicy,
+				Matcher: []string{"", "", "", ""},
+			},
 		},
 		"DELETE": {
+			"/v1/ca/:caid": {
+				Handler: a.deleteCA,
+				Matcher: []string{"", "", ""},
+			},
+			"/v1/ca/:caid/rollover": {
+				Handler: a.deleteRollover,
+				Matcher: []string{"", "", "", ""},
+			},
 			"/v1/ca/:caid/certificates/:cn": {
 				Handler: a.deleteCertificate,
 				Matcher: []string{"", "", "", "", "[a-zA-Z0-9.-_]+"},
 			},
+			"/v1/ca/:caid/profiles/:name": {
+				Handler: a.deleteProfile,
+				Matcher: []string{"", "", "", "", "[a-zA-Z0-9._-]+"},
+			},
+			"/v1/ca/:caid/policy": {
+				Handler: a.deletePolicy,
+				Matcher: []string{"", "", "", ""},
+			},
 		},
 	}
 
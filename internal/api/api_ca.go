@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/internal/service"
 	"github.com/fernandezvara/certsfor/pkg/client"
 	"github.com/fernandezvara/rest"
 	"github.com/julienschmidt/httprouter"
@@ -27,7 +29,166 @@ func (a *API) postCA(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 	response.Request = request
 
 	response.CAID, response.Certificate, response.Key, err = a.srv.CACreate(r.Context(), request)
+	if isRequestError(err) {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	response.CACertificate = response.Certificate
 	rest.Response(w, response, err, http.StatusCreated, fmt.Sprintf("/v1/ca/%s", response.CAID))
 
 }
+
+// postIntermediate POST /v1/ca/:caid/intermediates
+func (a *API) postIntermediate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		request  client.APICertificateRequest
+		response client.Certificate
+		caID     string = ps.ByName("caid")
+		err      error
+	)
+
+	err = rest.GetFromBody(r, &request)
+	if err != nil {
+		rest.BadRequest(w, r, "")
+		return
+	}
+
+	response, err = a.srv.IntermediateCreate(r.Context(), caID, request)
+	if policyViolationResponse(w, err) {
+		return
+	}
+
+	switch {
+	case err == manager.ErrPathLength:
+		rest.ErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	case isRequestError(err):
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.Request = request
+	rest.Response(w, response, err, http.StatusCreated, fmt.Sprintf("/v1/ca/%s", response.CAID))
+
+}
+
+// postImportCA POST /v1/import/ca
+func (a *API) postImportCA(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		request  client.APICAImportRequest
+		response client.Certificate
+		err      error
+	)
+
+	err = rest.GetFromBody(r, &request)
+	if err != nil {
+		rest.BadRequest(w, r, "")
+		return
+	}
+
+	// signer plugins are set up on the server configuration, not by the API callers
+	if manager.IsKeyReference(request.Key) {
+		rest.ErrorResponse(w, http.StatusBadRequest, service.ErrKeyReferenceNotAllowed.Error())
+		return
+	}
+
+	response, err = a.srv.CAImport(r.Context(), request)
+	if isRequestError(err) {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusCreated, fmt.Sprintf("/v1/ca/%s", response.CAID))
+
+}
+
+// postRollover POST /v1/ca/:caid/rollover
+func (a *API) postRollover(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		request  client.APICARolloverRequest
+		response client.Certificate
+		caID     string = ps.ByName("caid")
+		err      error
+	)
+
+	err = rest.GetFromBody(r, &request)
+	if err != nil {
+		rest.BadRequest(w, r, "")
+		return
+	}
+
+	response, err = a.srv.CARollover(r.Context(), caID, request)
+	switch {
+	case err == manager.ErrNotRoot, err == service.ErrRolloverInProgress:
+		rest.ErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	case isRequestError(err):
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusCreated, fmt.Sprintf("/v1/ca/%s", caID))
+
+}
+
+// deleteRollover DELETE /v1/ca/:caid/rollover
+func (a *API) deleteRollover(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		caID string = ps.ByName("caid")
+		err  error
+	)
+
+	err = a.srv.CARolloverFinish(r.Context(), caID)
+	rest.Response(w, nil, err, http.StatusNoContent, "")
+
+}
+
+// getCAs GET /v1/ca
+func (a *API) getCAs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response []client.APICA
+		err      error
+	)
+
+	response, err = a.srv.CAList(r.Context())
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// getCA GET /v1/ca/:caid
+func (a *API) getCA(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response client.APICA
+		caID     string = ps.ByName("caid")
+		err      error
+	)
+
+	response, err = a.srv.CAInfo(r.Context(), caID)
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// deleteCA DELETE /v1/ca/:caid
+func (a *API) deleteCA(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		caID string = ps.ByName("caid")
+		err  error
+	)
+
+	_, err = a.srv.CADelete(r.Context(), caID)
+	if err == service.ErrIntermediatesExist {
+		rest.ErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	rest.Response(w, nil, err, http.StatusNoContent, "")
+
+}
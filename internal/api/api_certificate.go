@@ -1,9 +1,15 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/internal/service"
 	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/certsfor/pkg/merkle"
+	"github.com/fernandezvara/certsfor/pkg/signer"
 	"github.com/fernandezvara/rest"
 	"github.com/julienschmidt/httprouter"
 )
@@ -42,6 +48,75 @@ func (a *API) putCertificate(w http.ResponseWriter, r *http.Request, ps httprout
 	response.Request = request
 
 	response.CACertificate, response.Certificate, response.Key, err = a.srv.CertificateSet(r.Context(), caID, request)
+	if policyViolationResponse(w, err) {
+		return
+	}
+	if isRequestError(err) {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	rest.Response(w, response, err, http.StatusOK, "")
 
 }
+
+// postCSR POST /v1/ca/:caid/csr
+func (a *API) postCSR(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		request  client.APICSRRequest
+		response client.Certificate
+		caID     string = ps.ByName("caid")
+		err      error
+	)
+
+	err = rest.GetFromBody(r, &request)
+	if err != nil {
+		rest.BadRequest(w, r, "")
+		return
+	}
+
+	response, err = a.srv.CertificateSignCSR(r.Context(), caID, request)
+	if policyViolationResponse(w, err) {
+		return
+	}
+	if isRequestError(err) {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusCreated, fmt.Sprintf("/v1/ca/%s/certificates/%s", caID, response.Request.DN.CN))
+
+}
+
+// isRequestError returns true if the error is caused by the contents of the request
+func isRequestError(err error) bool {
+
+	for _, requestError := range []error{
+		manager.ErrUnparseableFile,
+		manager.ErrCSRSignature,
+		manager.ErrCommonNameBlank,
+		manager.ErrExtensionInvalid,
+		manager.ErrNameConstraints,
+		manager.ErrNameConstraintsInvalid,
+		manager.ErrSANInvalid,
+		manager.ErrKeyInvalid,
+		manager.ErrNotCA,
+		manager.ErrKeyMismatch,
+		manager.ErrProfileInvalid,
+		manager.ErrPolicyInvalid,
+		manager.ErrValidityInvalid,
+		manager.ErrSSHPublicKey,
+		manager.ErrSSHRequestInvalid,
+		service.ErrProfileNotFound,
+		signer.ErrURIInvalid,
+		merkle.ErrIndexInvalid,
+	} {
+		if errors.Is(err, requestError) {
+			return true
+		}
+	}
+
+	return false
+
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/fernandezvara/certsfor/internal/manager"
 	"github.com/fernandezvara/certsfor/pkg/client"
 	"github.com/fernandezvara/rest"
 	"github.com/julienschmidt/httprouter"
@@ -18,6 +19,7 @@ func (a *API) getCertificate(w http.ResponseWriter, r *http.Request, ps httprout
 		cn              string = ps.ByName("cn") // certificate common name
 		remainingString string
 		remaining       int
+		rekey           bool
 		err             error
 	)
 
@@ -30,7 +32,39 @@ func (a *API) getCertificate(w http.ResponseWriter, r *http.Request, ps httprout
 		}
 	}
 
-	response, err = a.srv.CertificateGet(r.Context(), caID, cn, remaining)
+	if r.URL.Query().Get("rekey") != "" {
+		rekey, err = strconv.ParseBool(r.URL.Query().Get("rekey"))
+		if err != nil {
+			rest.BadRequest(w, r, "rekey value not allowed")
+			return
+		}
+	}
+
+	response, err = a.srv.CertificateRenew(r.Context(), caID, cn, remaining, rekey)
+	if err == manager.ErrRekeyNotAllowed {
+		rest.ErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	if policyViolationResponse(w, err) {
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// getCertificateBySerial GET /v1/ca/:caid/serials/:serial
+func (a *API) getCertificateBySerial(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response client.Certificate
+		caID     string = ps.ByName("caid")
+		serial   string = ps.ByName("serial") // hexadecimal serial number
+		err      error
+	)
+
+	response, err = a.srv.CertificateGetBySerial(r.Context(), caID, serial)
 	rest.Response(w, response, err, http.StatusOK, "")
 
 }
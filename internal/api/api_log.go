@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+	"github.com/julienschmidt/httprouter"
+)
+
+// queryUint returns the query string value as unsigned integer, 0 if not set
+func queryUint(r *http.Request, name string) (uint64, error) {
+
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(value, 10, 64)
+
+}
+
+// getLogHead GET /v1/ca/:caid/log/sth
+func (a *API) getLogHead(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response client.APISignedTreeHead
+		caID     string = ps.ByName("caid")
+		err      error
+	)
+
+	response, err = a.srv.LogHead(r.Context(), caID)
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// getLogEntries GET /v1/ca/:caid/log/entries?start=&end=
+func (a *API) getLogEntries(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response   []client.APILogEntry
+		caID       string = ps.ByName("caid")
+		start, end uint64
+		err        error
+	)
+
+	for name, value := range map[string]*uint64{"start": &start, "end": &end} {
+		*value, err = queryUint(r, name)
+		if err != nil {
+			rest.BadRequest(w, r, fmt.Sprintf("%s value not allowed", name))
+			return
+		}
+	}
+
+	response, err = a.srv.LogEntries(r.Context(), caID, start, end)
+	if isRequestError(err) {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// getLogInclusionProof GET /v1/ca/:caid/log/proof/inclusion?serial=&tree_size=
+func (a *API) getLogInclusionProof(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response client.APIInclusionProof
+		caID     string = ps.ByName("caid")
+		treeSize uint64
+		err      error
+	)
+
+	treeSize, err = queryUint(r, "tree_size")
+	if err != nil {
+		rest.BadRequest(w, r, "tree_size value not allowed")
+		return
+	}
+
+	response, err = a.srv.LogInclusionProof(r.Context(), caID, r.URL.Query().Get("serial"), treeSize)
+	if isRequestError(err) {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// getLogConsistencyProof GET /v1/ca/:caid/log/proof/consistency?first=&second=
+func (a *API) getLogConsistencyProof(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response      client.APIConsistencyProof
+		caID          string = ps.ByName("caid")
+		first, second uint64
+		err           error
+	)
+
+	for name, value := range map[string]*uint64{"first": &first, "second": &second} {
+		*value, err = queryUint(r, name)
+		if err != nil {
+			rest.BadRequest(w, r, fmt.Sprintf("%s value not allowed", name))
+			return
+		}
+	}
+
+	response, err = a.srv.LogConsistencyProof(r.Context(), caID, first, second)
+	if isRequestError(err) {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
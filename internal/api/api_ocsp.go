@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fernandezvara/rest"
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// max size allowed for an OCSP request
+	ocspRequestMaxSize = 10 * 1024
+)
+
+// getOCSP GET /v1/ca/:caid/ocsp/*request
+//
+// request is the DER encoded OCSP request as base64 (RFC 6960, A.1)
+func (a *API) getOCSP(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		caID    string = ps.ByName("caid")
+		request []byte
+		err     error
+	)
+
+	encoded, err := url.PathUnescape(strings.TrimPrefix(ps.ByName("request"), "/"))
+	if err == nil {
+		request, err = base64.StdEncoding.DecodeString(encoded)
+	}
+	if err != nil {
+		ocspResponse(w, ocsp.MalformedRequestErrorResponse)
+		return
+	}
+
+	a.ocsp(w, r, caID, request)
+
+}
+
+// postOCSP POST /v1/ca/:caid/ocsp
+func (a *API) postOCSP(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		caID    string = ps.ByName("caid")
+		request []byte
+		err     error
+	)
+
+	request, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, ocspRequestMaxSize))
+	if err != nil {
+		ocspResponse(w, ocsp.MalformedRequestErrorResponse)
+		return
+	}
+
+	a.ocsp(w, r, caID, request)
+
+}
+
+func (a *API) ocsp(w http.ResponseWriter, r *http.Request, caID string, request []byte) {
+
+	response, err := a.srv.OCSP(r.Context(), caID, request)
+	if err != nil {
+		rest.Response(w, nil, err, http.StatusOK, "")
+		return
+	}
+
+	ocspResponse(w, response)
+
+}
+
+// ocspResponse writes the OCSP response, errors are also OCSP responses
+func ocspResponse(w http.ResponseWriter, response []byte) {
+
+	w.Header().Set("Content-Type", "application/ocsp-response")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+	"github.com/julienschmidt/httprouter"
+)
+
+// putPolicy PUT /v1/ca/:caid/policy
+func (a *API) putPolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		request  client.APIPolicy
+		response client.APIPolicy
+		caID     string = ps.ByName("caid")
+		err      error
+	)
+
+	err = rest.GetFromBody(r, &request)
+	if err != nil {
+		rest.BadRequest(w, r, "")
+		return
+	}
+
+	response, err = a.srv.PolicySet(r.Context(), caID, request)
+	if isRequestError(err) {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// getPolicy GET /v1/ca/:caid/policy
+func (a *API) getPolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response client.APIPolicy
+		caID     string = ps.ByName("caid")
+		err      error
+	)
+
+	response, err = a.srv.PolicyGet(r.Context(), caID)
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// deletePolicy DELETE /v1/ca/:caid/policy
+func (a *API) deletePolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		caID string = ps.ByName("caid")
+		err  error
+	)
+
+	_, err = a.srv.PolicyDelete(r.Context(), caID)
+	rest.Response(w, nil, err, http.StatusNoContent, "")
+
+}
+
+// policyViolationResponse returns the policy violation with its status code, false if err is
+// not a policy violation
+func policyViolationResponse(w http.ResponseWriter, err error) bool {
+
+	violation, ok := err.(*client.PolicyViolation)
+	if !ok {
+		return false
+	}
+
+	violation.Code = violation.Status()
+	violation.Message = http.StatusText(violation.Code)
+
+	rest.Response(w, violation, nil, violation.Code, "")
+
+	return true
+
+}
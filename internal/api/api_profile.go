@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+	"github.com/julienschmidt/httprouter"
+)
+
+// putProfile PUT /v1/ca/:caid/profiles/:name
+func (a *API) putProfile(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		request  client.APIProfile
+		response client.APIProfile
+		caID     string = ps.ByName("caid")
+		name     string = ps.ByName("name")
+		err      error
+	)
+
+	err = rest.GetFromBody(r, &request)
+	if err != nil {
+		rest.BadRequest(w, r, "")
+		return
+	}
+
+	if request.Name == "" {
+		request.Name = name
+	}
+
+	if request.Name != name {
+		rest.ErrorResponse(w, http.StatusConflict, "Profile name does not match")
+		return
+	}
+
+	response, err = a.srv.ProfileSet(r.Context(), caID, request)
+	if isRequestError(err) {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// getProfile GET /v1/ca/:caid/profiles/:name
+func (a *API) getProfile(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response client.APIProfile
+		caID     string = ps.ByName("caid")
+		name     string = ps.ByName("name")
+		err      error
+	)
+
+	response, err = a.srv.ProfileGet(r.Context(), caID, name)
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// getProfiles GET /v1/ca/:caid/profiles
+func (a *API) getProfiles(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response []client.APIProfile
+		caID     string = ps.ByName("caid")
+		err      error
+	)
+
+	response, err = a.srv.ProfileList(r.Context(), caID)
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// deleteProfile DELETE /v1/ca/:caid/profiles/:name
+func (a *API) deleteProfile(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		caID string = ps.ByName("caid")
+		name string = ps.ByName("name")
+		err  error
+	)
+
+	_, err = a.srv.ProfileDelete(r.Context(), caID, name)
+	rest.Response(w, nil, err, http.StatusNoContent, "")
+
+}
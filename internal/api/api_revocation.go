@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/pem"
+	"net/http"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+	"github.com/julienschmidt/httprouter"
+)
+
+// postRevoke POST /v1/ca/:caid/certificates/:cn/revoke
+func (a *API) postRevoke(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		request  client.APIRevocationRequest
+		response client.Revocation
+		caID     string = ps.ByName("caid")
+		cn       string = ps.ByName("cn") // certificate common name
+		err      error
+	)
+
+	// body is optional, reason defaults to unspecified
+	if r.ContentLength != 0 {
+		err = rest.GetFromBody(r, &request)
+		if err != nil {
+			rest.BadRequest(w, r, "")
+			return
+		}
+	}
+
+	response, err = a.srv.CertificateRevoke(r.Context(), caID, cn, request)
+	if err == manager.ErrReasonInvalid {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
+
+// getCRL GET /v1/ca/:caid/crl
+//
+// returns the CRL DER encoded, or PEM encoded using ?format=pem. The CRL of the previous CA while
+// a rollover is in progress is returned using ?issuer=previous
+func (a *API) getCRL(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		caID string = ps.ByName("caid")
+		crl  []byte
+		err  error
+	)
+
+	switch r.URL.Query().Get("issuer") {
+	case "":
+		crl, err = a.srv.CRL(r.Context(), caID)
+	case "previous":
+		crl, err = a.srv.CRLPrevious(r.Context(), caID)
+	default:
+		err = rest.ErrBadRequest
+	}
+	if err != nil {
+		rest.Response(w, nil, err, http.StatusOK, "")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "pem" {
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		w.WriteHeader(http.StatusOK)
+		pem.Encode(w, &pem.Block{Type: manager.FileCRL, Bytes: crl})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pkix-crl")
+	w.WriteHeader(http.StatusOK)
+	w.Write(crl)
+
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+	"github.com/julienschmidt/httprouter"
+)
+
+// postSSHSign POST /v1/ca/:caid/ssh/:type
+func (a *API) postSSHSign(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		request  client.APISSHRequest
+		response client.APISSHCertificate
+		caID     string = ps.ByName("caid")
+		certType string = ps.ByName("type")
+		err      error
+	)
+
+	err = rest.GetFromBody(r, &request)
+	if err != nil {
+		rest.BadRequest(w, r, "")
+		return
+	}
+
+	response, err = a.srv.SSHSign(r.Context(), caID, certType, request)
+	if policyViolationResponse(w, err) {
+		return
+	}
+	if isRequestError(err) {
+		rest.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.Response(w, response, err, http.StatusCreated, "")
+
+}
+
+// getSSHCA GET /v1/ca/:caid/ssh
+func (a *API) getSSHCA(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var (
+		response client.APISSHCA
+		caID     string = ps.ByName("caid")
+		err      error
+	)
+
+	response, err = a.srv.SSHCA(r.Context(), caID, r.URL.Query().Get("hosts"))
+	rest.Response(w, response, err, http.StatusOK, "")
+
+}
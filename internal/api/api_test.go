@@ -2,7 +2,12 @@ package api_test
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -12,7 +17,10 @@ import (
 	_ "github.com/fernandezvara/certsfor/db/badger" // store driver
 	"github.com/fernandezvara/certsfor/internal/tests"
 	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/certsfor/pkg/merkle"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ocsp"
+	"golang.org/x/crypto/ssh"
 )
 
 var (
@@ -39,8 +47,18 @@ func TestAPI(t *testing.T) {
 	testCreateCA(t)          // POST   /v1/ca
 	testCreateCertificate(t) // PUT    /v1/ca/:caid/certificates/:cn
 	testGetCertificate(t)    // GET    /v1/ca/:caid/certificates/:cn
+	testGetBySerial(t)       // GET    /v1/ca/:caid/serials/:serial
 	testListCertificates(t)  // GET    /v1/ca/:caid/certificates
 	testDeleteCertificate(t) // DELETE /v1/ca/:caid/certificates/:cn
+	testRevokeCertificate(t) // POST   /v1/ca/:caid/certificates/:cn/revoke
+	testGetCRL(t)            // GET    /v1/ca/:caid/crl
+	testOCSP(t)              // GET    /v1/ca/:caid/ocsp/:request, POST /v1/ca/:caid/ocsp
+	testNameConstraints(t)   // POST   /v1/ca, PUT /v1/ca/:caid/certificates/:cn
+	testProfiles(t)          // PUT, GET, DELETE /v1/ca/:caid/profiles/:name, GET /v1/ca/:caid/profiles
+	testPolicy(t)            // PUT, GET, DELETE /v1/ca/:caid/policy
+	testSSH(t)               // GET /v1/ca/:caid/ssh, POST /v1/ca/:caid/ssh/:type
+	testLog(t)               // GET /v1/ca/:caid/log/sth, entries, proof/inclusion, proof/consistency
+	testCAs(t)               // GET /v1/ca, GET, DELETE /v1/ca/:caid
 
 	err := testAPI.StopAPI(t)
 	assert.Nil(t, err)
@@ -164,6 +182,14 @@ func testCreateCertificate(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, http.StatusConflict, status)
 
+	request = requestCertificate(true)
+	request.Extensions = []client.APIExtension{{OID: "not an oid", Value: "value"}}
+
+	// 400 - Bad Request (invalid extension)
+	status, err = sendData(http.MethodPut, certURI, request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
 	request = requestCertificate(true)
 
 	// 200 - OK
@@ -242,6 +268,62 @@ func testGetCertificate(t *testing.T) {
 	assert.NotEqual(t, certCertificate, response.Certificate)
 	assert.Equal(t, certKey, response.Key)
 
+	// 400 - Bad Request (rekey is not a boolean)
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/certificates/cert?rekey=NOT_A_BOOLEAN", caID)))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// 200 - OK + Renew with a new key
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/certificates/cert?rekey=true", caID)))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	err = getFromBody(res, &response)
+	assert.Nil(t, err)
+	assert.NotEqual(t, certCertificate, response.Certificate)
+	assert.NotEqual(t, certKey, response.Key)
+
+}
+
+func testGetBySerial(t *testing.T) {
+
+	var (
+		current  client.Certificate
+		response client.Certificate
+		res      *http.Response
+		err      error
+	)
+
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/certificates/cert", caID)))
+	assert.Nil(t, err)
+	assert.Nil(t, getFromBody(res, &current))
+
+	cert, err := parseCertificate(current.Certificate)
+	assert.Nil(t, err)
+
+	// 200 - OK
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/serials/%s", caID, cert.SerialNumber.Text(16))))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	err = getFromBody(res, &response)
+	assert.Nil(t, err)
+	assert.Equal(t, current.Certificate, response.Certificate)
+	assert.Equal(t, current.Key, response.Key)
+
+	// 404 - Not found (renewed)
+	cert, err = parseCertificate(certCertificate)
+	assert.Nil(t, err)
+
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/serials/%s", caID, cert.SerialNumber.Text(16))))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// 400 - Bad request
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/serials/:", caID)))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
 }
 
 func testListCertificates(t *testing.T) {
@@ -305,6 +387,261 @@ func testDeleteCertificate(t *testing.T) {
 
 }
 
+func testRevokeCertificate(t *testing.T) {
+
+	var (
+		request  client.APICertificateRequest = requestCertificate(true)
+		response client.Revocation
+		status   int
+		err      error
+	)
+
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/certificates/%s", caID, request.DN.CN)), request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// 400 - Bad Request - reason invalid
+	status, err = sendData(http.MethodPost, uri(fmt.Sprintf("/v1/ca/%s/certificates/%s/revoke", caID, request.DN.CN)), client.APIRevocationRequest{Reason: "invalid"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// 404 - Not found - id not found
+	status, err = sendData(http.MethodPost, uri(fmt.Sprintf("/v1/ca/%s/certificates/%s/revoke", caID, "id-no-existent")), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// 200 - OK
+	status, err = sendData(http.MethodPost, uri(fmt.Sprintf("/v1/ca/%s/certificates/%s/revoke", caID, request.DN.CN)), client.APIRevocationRequest{Reason: client.ReasonSuperseded}, &response)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, request.DN.CN, response.CN)
+	assert.Equal(t, client.ReasonSuperseded, response.Reason)
+	assert.NotEmpty(t, response.Serial)
+
+}
+
+func testGetCRL(t *testing.T) {
+
+	var (
+		res  *http.Response
+		body []byte
+		err  error
+	)
+
+	// 404 - Not found - ca not found
+	res, err = http.Get(uri("/v1/ca/ca-non-existent/crl"))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// 200 - OK - DER
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/crl", caID)))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/pkix-crl", res.Header.Get("Content-Type"))
+	body, err = ioutil.ReadAll(res.Body)
+	assert.Nil(t, err)
+	res.Body.Close()
+
+	crl, err := x509.ParseCRL(body)
+	assert.Nil(t, err)
+	// the revoked certificate and the ones issued before for its common name (not expired)
+	assert.Len(t, crl.TBSCertList.RevokedCertificates, 4)
+
+	// 200 - OK - PEM
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/crl?format=pem", caID)))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	body, err = ioutil.ReadAll(res.Body)
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.True(t, bytes.HasPrefix(body, []byte("-----BEGIN X509 CRL-----")))
+
+	// x509.ParseCRL accepts PEM too
+	_, err = x509.ParseCRL(body)
+	assert.Nil(t, err)
+
+}
+
+func testOCSP(t *testing.T) {
+
+	var (
+		res  *http.Response
+		body []byte
+		err  error
+	)
+
+	ca, err := parseCertificate(caCertificate)
+	assert.Nil(t, err)
+
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/certificates/cert", caID)))
+	assert.Nil(t, err)
+	var certificate client.Certificate
+	err = getFromBody(res, &certificate)
+	assert.Nil(t, err)
+
+	cert, err := parseCertificate(certificate.Certificate)
+	assert.Nil(t, err)
+
+	request, err := ocsp.CreateRequest(cert, ca, nil)
+	assert.Nil(t, err)
+
+	// GET, base64 encoded request could include '/'
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/ocsp/%s", caID, base64.StdEncoding.EncodeToString(request))))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/ocsp-response", res.Header.Get("Content-Type"))
+	body, err = ioutil.ReadAll(res.Body)
+	assert.Nil(t, err)
+	res.Body.Close()
+
+	response, err := ocsp.ParseResponseForCert(body, cert, ca)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.Revoked, response.Status)
+	assert.Equal(t, ocsp.Superseded, response.RevocationReason)
+
+	// POST
+	res, err = http.Post(uri(fmt.Sprintf("/v1/ca/%s/ocsp", caID)), "application/ocsp-request", bytes.NewReader(request))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	body, err = ioutil.ReadAll(res.Body)
+	assert.Nil(t, err)
+	res.Body.Close()
+
+	response, err = ocsp.ParseResponseForCert(body, cert, ca)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.Revoked, response.Status)
+
+	// malformed
+	res, err = http.Get(uri(fmt.Sprintf("/v1/ca/%s/ocsp/not-base64", caID)))
+	assert.Nil(t, err)
+	body, err = ioutil.ReadAll(res.Body)
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, ocsp.MalformedRequestErrorResponse, body)
+
+}
+
+func testNameConstraints(t *testing.T) {
+
+	var (
+		request  client.APICertificateRequest
+		response client.Certificate
+		status   int
+		err      error
+	)
+
+	request.DN.CN = "constrained-ca"
+	request.Key = client.ECDSA256
+	request.ExpirationDays = 365
+	request.NameConstraints.PermittedDNS = []string{"team.example.com"}
+
+	// 201 - Created
+	status, err = sendData(http.MethodPost, uri("/v1/ca"), request, &response)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, request.NameConstraints, response.Request.NameConstraints)
+
+	constrainedID := response.CAID
+
+	// 400 - Bad Request (invalid IP range)
+	request.NameConstraints.PermittedIPs = []string{"not a range"}
+	status, err = sendData(http.MethodPost, uri("/v1/ca"), request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	request = requestCertificate(true)
+	request.SAN = []string{"www.team.example.com"}
+
+	// 200 - OK
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/certificates/cert", constrainedID)), request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// 400 - Bad Request (name not allowed)
+	request.SAN = []string{"www.other.example.com"}
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/certificates/cert", constrainedID)), request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+}
+
+func testProfiles(t *testing.T) {
+
+	var (
+		profile  client.APIProfile
+		profiles []client.APIProfile
+		request  client.APICertificateRequest
+		status   int
+		err      error
+	)
+
+	profile.Defaults.Key = client.ECDSA256
+	profile.Defaults.ExpirationDays = 30
+
+	// 200 - OK (name taken from the path)
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/profiles/server", caID)), profile, &profile)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "server", profile.Name)
+
+	// 409 - Conflict (name does not match)
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/profiles/client", caID)), profile, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// 400 - Bad Request (key invalid)
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/profiles/invalid", caID)), client.APIProfile{Fixed: client.APIProfileValues{Key: "invalid"}}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// 404 - Not found (ca not found)
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/profiles/server", "ca-non-existent")), profile, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// 200 - OK
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/profiles", caID)), nil, &profiles)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, profiles, 1)
+
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/profiles/server", caID)), nil, &profile)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(30), profile.Defaults.ExpirationDays)
+
+	request.DN.CN = "profiled"
+	request.Profile = "server"
+
+	// 200 - OK
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/certificates/profiled", caID)), request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// 204 - No content
+	status, err = sendData(http.MethodDelete, uri(fmt.Sprintf("/v1/ca/%s/profiles/server", caID)), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	// 404 - Not found
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/profiles/server", caID)), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// 400 - Bad Request (profile not found)
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/certificates/profiled", caID)), request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+}
+
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+
+	block, _ := pem.Decode(certPEM)
+	return x509.ParseCertificate(block.Bytes)
+
+}
+
 // helpers
 func uri(path string) string {
 	return fmt.Sprintf("http://%s%s", apiIPPort, path)
@@ -322,6 +659,145 @@ func getFromBody(res *http.Response, obj interface{}) error {
 
 }
 
+func testPolicy(t *testing.T) {
+
+	var (
+		policy    client.APIPolicy
+		violation client.PolicyViolation
+		request   client.APICertificateRequest
+		status    int
+		err       error
+	)
+
+	policy.DeniedSANs = []string{"*.internal"}
+	policy.MaxExpirationDays = 90
+
+	// 404 - Not found (no policy)
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/policy", caID)), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// 200 - OK
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/policy", caID)), policy, &policy)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// 400 - Bad Request (pattern invalid)
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/policy", caID)), client.APIPolicy{AllowedSANs: []string{"regex:("}}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// 404 - Not found (ca not found)
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/policy", "ca-non-existent")), policy, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// 200 - OK
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/policy", caID)), nil, &policy)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"*.internal"}, policy.DeniedSANs)
+
+	request.DN.CN = "policy"
+	request.SAN = []string{"db.internal"}
+	request.Key = client.ECDSA256
+	request.ExpirationDays = 30
+
+	// 403 - Forbidden (SAN denied)
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/certificates/policy", caID)), request, &violation)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, client.PolicyRuleDeniedSANs, violation.Rule)
+	assert.Equal(t, "db.internal", violation.Value)
+
+	request.SAN = []string{"www.example.com"}
+	request.ExpirationDays = 365
+
+	// 422 - Unprocessable entity (expiration too long)
+	violation = client.PolicyViolation{}
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/certificates/policy", caID)), request, &violation)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, client.PolicyRuleMaxExp, violation.Rule)
+
+	// 204 - No content
+	status, err = sendData(http.MethodDelete, uri(fmt.Sprintf("/v1/ca/%s/policy", caID)), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	// 404 - Not found
+	status, err = sendData(http.MethodDelete, uri(fmt.Sprintf("/v1/ca/%s/policy", caID)), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// 200 - OK
+	status, err = sendData(http.MethodPut, uri(fmt.Sprintf("/v1/ca/%s/certificates/policy", caID)), request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+}
+
+func testSSH(t *testing.T) {
+
+	var (
+		ca          client.APISSHCA
+		certificate client.APISSHCertificate
+		status      int
+		err         error
+	)
+
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	publicKey, err := ssh.NewPublicKey(public)
+	assert.Nil(t, err)
+
+	request := client.APISSHRequest{
+		PublicKey:  ssh.MarshalAuthorizedKey(publicKey),
+		Principals: []string{"alice"},
+	}
+
+	// 201 - Created
+	status, err = sendData(http.MethodPost, uri(fmt.Sprintf("/v1/ca/%s/ssh/user", caID)), request, &certificate)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "alice", certificate.KeyID)
+
+	status, err = sendData(http.MethodPost, uri(fmt.Sprintf("/v1/ca/%s/ssh/host", caID)), request, &certificate)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+
+	// 400 - Bad Request (host certificates have no extensions)
+	request.Extensions = map[string]string{"permit-pty": ""}
+	status, err = sendData(http.MethodPost, uri(fmt.Sprintf("/v1/ca/%s/ssh/host", caID)), request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// 400 - Bad Request (unknown type)
+	status, err = sendData(http.MethodPost, uri(fmt.Sprintf("/v1/ca/%s/ssh/superuser", caID)), request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// 200 - OK
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/ssh?hosts=*.example.com", caID)), nil, &ca)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, string(ca.KnownHosts), "@cert-authority *.example.com ")
+
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey(certificate.Certificate)
+	assert.Nil(t, err)
+
+	caKey, _, _, _, err := ssh.ParseAuthorizedKey(ca.PublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, caKey.Marshal(), parsed.(*ssh.Certificate).SignatureKey.Marshal())
+
+	// 404 - Not found (ca not found)
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/ssh", "ca-non-existent")), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+}
+
 func sendData(method, uri string, request interface{}, response interface{}) (status int, err error) {
 
 	var (
@@ -362,3 +838,137 @@ func sendData(method, uri string, request interface{}, response interface{}) (st
 	return
 
 }
+
+func testLog(t *testing.T) {
+
+	var (
+		head        client.APISignedTreeHead
+		entries     []client.APILogEntry
+		inclusion   client.APIInclusionProof
+		consistency client.APIConsistencyProof
+		status      int
+		err         error
+	)
+
+	ca, err := parseCertificate(caCertificate)
+	assert.Nil(t, err)
+
+	// 200 - OK
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/log/sth", caID)), nil, &head)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Greater(t, head.TreeSize, uint64(1))
+
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/log/entries?start=0&end=1", caID)), nil, &entries)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, entries, 2)
+
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/log/proof/inclusion?serial=%s", caID, ca.SerialNumber.Text(16))), nil, &inclusion)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, entries[0], inclusion.Entry)
+	assert.True(t, merkle.VerifyInclusion(0, head.TreeSize, inclusion.LeafHash, inclusion.AuditPath, head.RootHash))
+
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/log/proof/consistency?first=1&second=%d", caID, head.TreeSize)), nil, &consistency)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, merkle.VerifyConsistency(1, head.TreeSize, inclusion.LeafHash, head.RootHash, consistency.Consistency))
+
+	// 400 - Bad Request (invalid values or sizes)
+	for _, path := range []string{
+		"entries?start=a",
+		fmt.Sprintf("entries?start=%d", head.TreeSize),
+		"proof/inclusion?serial=zz",
+		fmt.Sprintf("proof/inclusion?serial=%s&tree_size=-1", ca.SerialNumber.Text(16)),
+		fmt.Sprintf("proof/consistency?first=1&second=%d", head.TreeSize+1),
+		"proof/consistency?first=0",
+	} {
+		status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/log/%s", caID, path)), nil, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, status, path)
+	}
+
+	// 404 - Not found (serial not logged, ca not found)
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/log/proof/inclusion?serial=01", caID)), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/log/sth", "ca-non-existent")), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+}
+
+func testCAs(t *testing.T) {
+
+	var (
+		cas          []client.APICA
+		ca           client.APICA
+		root         client.Certificate
+		intermediate client.Certificate
+		request      client.APICertificateRequest
+		status       int
+		err          error
+	)
+
+	// 200 - OK
+	status, err = sendData(http.MethodGet, uri("/v1/ca"), nil, &cas)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Greater(t, len(cas), 0)
+
+	// 404 - Not found
+	status, err = sendData(http.MethodGet, uri("/v1/ca/ca-non-existent"), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// 200 - OK
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s", caID)), nil, &ca)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, caID, ca.CAID)
+	assert.Equal(t, "test-ca", ca.CN)
+	assert.Equal(t, caCertificate, ca.Certificate)
+
+	request.DN.CN = "delete-ca"
+	request.Key = client.ECDSA256
+	request.ExpirationDays = 365
+	request.PathLength = 1
+
+	status, err = sendData(http.MethodPost, uri("/v1/ca"), request, &root)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+
+	request.DN.CN = "delete-intermediate"
+	request.PathLength = 0
+
+	status, err = sendData(http.MethodPost, uri(fmt.Sprintf("/v1/ca/%s/intermediates", root.CAID)), request, &intermediate)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+
+	// 404 - Not found
+	status, err = sendData(http.MethodDelete, uri("/v1/ca/ca-non-existent"), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// 409 - Conflict - the CA has intermediates
+	status, err = sendData(http.MethodDelete, uri(fmt.Sprintf("/v1/ca/%s", root.CAID)), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// 204 - No content - delete done
+	status, err = sendData(http.MethodDelete, uri(fmt.Sprintf("/v1/ca/%s", intermediate.CAID)), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	status, err = sendData(http.MethodDelete, uri(fmt.Sprintf("/v1/ca/%s", root.CAID)), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	// 404 - Not found - ensure CA was deleted
+	status, err = sendData(http.MethodGet, uri(fmt.Sprintf("/v1/ca/%s/certificates", root.CAID)), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+}
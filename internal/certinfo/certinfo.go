@@ -93,6 +93,21 @@ func NewFromFile(filename string) (certinfo CertInfo, err error) {
 
 }
 
+// URLHostname returns the hostname of the URL (or host:port) as NewFromURL connects to it
+func URLHostname(uri string) (hostname string, err error) {
+
+	var ipPort string
+
+	ipPort, err = cleanURI(uri)
+	if err != nil {
+		return
+	}
+
+	hostname, _, err = net.SplitHostPort(ipPort)
+	return
+
+}
+
 func cleanURI(input string) (ipPort string, err error) {
 
 	var (
@@ -122,22 +137,28 @@ type CertInfo struct {
 	TimeFormat string // time format to use
 }
 
+// fromBytes reads every certificate on the PEM bytes, so bundles and chains are read whole
 func (c *CertInfo) fromBytes(certBytes []byte) (err error) {
 
 	var (
 		cert *x509.Certificate
 	)
 
-	block, _ := pem.Decode([]byte(certBytes))
-	if block == nil {
-		return ErrUnparseableFile
+	for block, rest := pem.Decode(certBytes); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return
+		}
+		c.certs = append(c.certs, cert)
 	}
 
-	cert, err = x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return
+	if len(c.certs) == 0 {
+		return ErrUnparseableFile
 	}
-	c.certs = append(c.certs, cert)
 
 	return
 }
@@ -163,7 +184,7 @@ func (c *CertInfo) Show(markdown, csv bool) {
 			expires string
 		)
 		if now.Before(cert.NotAfter) {
-			expires = fmt.Sprintf("%d days (%s)", int64(cert.NotAfter.Sub(now).Hours())/int64(24), cert.NotAfter.Format(c.TimeFormat))
+			expires = fmt.Sprintf("%s (%s)", Remaining(cert.NotAfter.Sub(now)), cert.NotAfter.Format(c.TimeFormat))
 		} else {
 			expires = fmt.Sprintf("Expired (%s)", cert.NotAfter.Format(c.TimeFormat))
 		}
@@ -199,3 +220,18 @@ func (c *CertInfo) Certificates() []*x509.Certificate {
 	return c.certs
 
 }
+
+// Remaining returns the time left as days, or as hours and minutes if it is less than a day
+func Remaining(d time.Duration) string {
+
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%d days", int64(d.Hours())/int64(24))
+	}
+
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int64(d.Minutes()))
+	}
+
+	return fmt.Sprintf("%dh%02dm", int64(d.Hours()), int64(d.Minutes())%60)
+
+}
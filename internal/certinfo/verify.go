@@ -0,0 +1,239 @@
+package certinfo
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// verification errors, returned wrapped with the details of the certificate that failed
+var (
+	ErrNoCertificate = errors.New("no certificate to verify")
+	ErrNoRoots       = errors.New("no trusted CA certificates")
+	ErrNotYetValid   = errors.New("certificate is not valid yet")
+	ErrExpired       = errors.New("certificate has expired")
+	ErrUntrusted     = errors.New("certificate is not signed by a trusted CA")
+	ErrKeyUsage      = errors.New("certificate is not valid for the key usage")
+	ErrHostname      = errors.New("certificate is not valid for the hostname")
+	ErrChainInvalid  = errors.New("certificate chain is invalid")
+)
+
+// extKeyUsages are the names of the extended key usages
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"any":          x509.ExtKeyUsageAny,
+	"server":       x509.ExtKeyUsageServerAuth,
+	"client":       x509.ExtKeyUsageClientAuth,
+	"email":        x509.ExtKeyUsageEmailProtection,
+	"code-signing": x509.ExtKeyUsageCodeSigning,
+	"ocsp-signing": x509.ExtKeyUsageOCSPSigning,
+	"timestamping": x509.ExtKeyUsageTimeStamping,
+}
+
+// ExtKeyUsage returns the extended key usage by its name (any, server, client, email, code-signing,
+// ocsp-signing or timestamping)
+func ExtKeyUsage(name string) (x509.ExtKeyUsage, error) {
+
+	usage, ok := extKeyUsages[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown key usage: '%s'", name)
+	}
+
+	return usage, nil
+
+}
+
+// SplitTrusted returns the self signed certificates as roots and the rest as intermediates. If there
+// are no self signed certificates all of them are roots (trust anchors)
+func SplitTrusted(certs []*x509.Certificate) (roots, intermediates []*x509.Certificate) {
+
+	for _, cert := range certs {
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil {
+			roots = append(roots, cert)
+		} else {
+			intermediates = append(intermediates, cert)
+		}
+	}
+
+	if len(roots) == 0 {
+		return intermediates, nil
+	}
+
+	return
+
+}
+
+// VerifyOptions are the checks done by Verify
+type VerifyOptions struct {
+	Roots         []*x509.Certificate // trusted CA certificates
+	Intermediates []*x509.Certificate // certificates to build the chain besides the ones read with the certificate
+	Hostname      string              // DNS name or IP address the certificate must be valid for (optional)
+	KeyUsages     []x509.ExtKeyUsage  // the certificate must be valid for any of them (all if empty)
+	Time          time.Time           // time to check the validity periods (now if zero)
+}
+
+// Verify builds the chain of the first certificate read up to a trusted CA, the other certificates read
+// (as a file bundle or the chain sent by a server) are used as intermediates. Validity periods, key usages
+// and the hostname are checked. Returns the chain from the certificate to the CA, or the reason the
+// certificate is not valid
+func (c *CertInfo) Verify(opts VerifyOptions) (chain []*x509.Certificate, err error) {
+
+	var (
+		leaf          *x509.Certificate
+		roots         *x509.CertPool = x509.NewCertPool()
+		intermediates *x509.CertPool = x509.NewCertPool()
+		chains        [][]*x509.Certificate
+	)
+
+	if len(c.certs) == 0 {
+		return nil, ErrNoCertificate
+	}
+
+	if len(opts.Roots) == 0 {
+		return nil, ErrNoRoots
+	}
+
+	if opts.Time.IsZero() {
+		opts.Time = time.Now()
+	}
+
+	if len(opts.KeyUsages) == 0 {
+		opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+
+	leaf = c.certs[0]
+
+	for _, cert := range opts.Roots {
+		roots.AddCert(cert)
+	}
+
+	for _, cert := range c.certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	for _, cert := range opts.Intermediates {
+		intermediates.AddCert(cert)
+	}
+
+	// the validity of the certificate is checked first, it is the most common failure
+	err = checkValidity(leaf, opts.Time)
+	if err != nil {
+		return
+	}
+
+	chains, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   opts.Time,
+		KeyUsages:     opts.KeyUsages,
+	})
+	if err != nil {
+		return nil, verifyError(leaf, opts.Time, err)
+	}
+
+	if opts.Hostname != "" {
+		err = leaf.VerifyHostname(opts.Hostname)
+		if err != nil {
+			return nil, fmt.Errorf("%w: '%s' is valid for %s, not '%s'", ErrHostname, name(leaf), strings.Join(names(leaf), ", "), opts.Hostname)
+		}
+	}
+
+	return chains[0], nil
+
+}
+
+// checkValidity returns the reason the certificate is not valid at the time, if any
+func checkValidity(cert *x509.Certificate, at time.Time) error {
+
+	if at.Before(cert.NotBefore) {
+		return fmt.Errorf("%w: '%s' is valid from %s", ErrNotYetValid, name(cert), cert.NotBefore.Local().Format(timeFormat))
+	}
+
+	if at.After(cert.NotAfter) {
+		return fmt.Errorf("%w: '%s' expired on %s", ErrExpired, name(cert), cert.NotAfter.Local().Format(timeFormat))
+	}
+
+	return nil
+
+}
+
+// verifyError returns the reason the chain could not be built, with the certificate that caused it
+func verifyError(leaf *x509.Certificate, at time.Time, err error) error {
+
+	var (
+		authorityError x509.UnknownAuthorityError
+		invalidError   x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.As(err, &authorityError):
+		return fmt.Errorf("%w: '%s' is issued by '%s'", ErrUntrusted, name(leaf), leaf.Issuer.CommonName)
+	case errors.As(err, &invalidError):
+		switch invalidError.Reason {
+		case x509.Expired:
+			if validityErr := checkValidity(invalidError.Cert, at); validityErr != nil {
+				return validityErr
+			}
+		case x509.IncompatibleUsage:
+			return fmt.Errorf("%w: '%s' is only valid for %s", ErrKeyUsage, name(invalidError.Cert), strings.Join(usageNames(invalidError.Cert.ExtKeyUsage), ", "))
+		}
+		return fmt.Errorf("%w: '%s': %s", ErrChainInvalid, name(invalidError.Cert), strings.TrimPrefix(invalidError.Error(), "x509: "))
+	}
+
+	return fmt.Errorf("%w: %s", ErrChainInvalid, strings.TrimPrefix(err.Error(), "x509: "))
+
+}
+
+// name returns the name used to identify the certificate on errors
+func name(cert *x509.Certificate) string {
+
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+
+	return cert.Subject.String()
+
+}
+
+// names returns the DNS names and IP addresses of the certificate
+func names(cert *x509.Certificate) (values []string) {
+
+	values = append(values, cert.DNSNames...)
+
+	for _, ip := range cert.IPAddresses {
+		values = append(values, ip.String())
+	}
+
+	if len(values) == 0 {
+		values = append(values, "no names")
+	}
+
+	return
+
+}
+
+// usageNames returns the names of the extended key usages
+func usageNames(usages []x509.ExtKeyUsage) (values []string) {
+
+	for _, usage := range usages {
+		known := false
+		for usageName, value := range extKeyUsages {
+			if value == usage {
+				values = append(values, usageName)
+				known = true
+			}
+		}
+		if !known {
+			values = append(values, fmt.Sprintf("usage %d", usage))
+		}
+	}
+
+	if len(values) == 0 {
+		values = append(values, "no extended key usages")
+	}
+
+	return
+
+}
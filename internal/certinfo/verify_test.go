@@ -0,0 +1,97 @@
+package certinfo
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCA(t *testing.T, cn string) *manager.CA {
+
+	caCert, caKey, err := manager.New(client.APICertificateRequest{
+		DN:             client.APIDN{CN: cn},
+		Key:            client.ECDSA256,
+		ExpirationDays: 10,
+	})
+	assert.Nil(t, err)
+
+	ca, err := manager.FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+
+	return ca
+
+}
+
+func testLeaf(t *testing.T, ca *manager.CA, request client.APICertificateRequest) CertInfo {
+
+	request.Key = client.ECDSA256
+	request.ExpirationDays = 1
+
+	certPEM, _, err := ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	certInfo, err := NewFromBytes(certPEM)
+	assert.Nil(t, err)
+
+	return certInfo
+
+}
+
+func TestVerify(t *testing.T) {
+
+	var (
+		ca       *manager.CA = testCA(t, "verify ca")
+		other    *manager.CA = testCA(t, "other ca")
+		server   CertInfo    = testLeaf(t, ca, client.APICertificateRequest{DN: client.APIDN{CN: "server"}, SAN: []string{"www.example.com", "127.0.0.1"}})
+		clientCI CertInfo    = testLeaf(t, ca, client.APICertificateRequest{DN: client.APIDN{CN: "client"}, Client: true})
+		roots    []*x509.Certificate
+	)
+
+	caInfo, err := NewFromBytes(ca.CACertificateBytes())
+	assert.Nil(t, err)
+	roots, intermediates := SplitTrusted(caInfo.Certificates())
+	assert.Len(t, roots, 1)
+	assert.Len(t, intermediates, 0)
+
+	chain, err := server.Verify(VerifyOptions{Roots: roots, Hostname: "www.example.com", KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+	assert.Nil(t, err)
+	assert.Len(t, chain, 2)
+	assert.Equal(t, "verify ca", chain[1].Subject.CommonName)
+
+	_, err = server.Verify(VerifyOptions{Roots: roots, Hostname: "127.0.0.1"})
+	assert.Nil(t, err)
+
+	_, err = clientCI.Verify(VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	assert.Nil(t, err)
+
+	for _, test := range []struct {
+		certInfo CertInfo
+		opts     VerifyOptions
+		err      error
+	}{
+		{server, VerifyOptions{}, ErrNoRoots},
+		{CertInfo{}, VerifyOptions{Roots: roots}, ErrNoCertificate},
+		{server, VerifyOptions{Roots: roots, Hostname: "other.example.com"}, ErrHostname},
+		{server, VerifyOptions{Roots: roots, Hostname: "127.0.0.2"}, ErrHostname},
+		{server, VerifyOptions{Roots: roots, Time: time.Now().Add(48 * time.Hour)}, ErrExpired},
+		{server, VerifyOptions{Roots: roots, Time: time.Now().Add(-48 * time.Hour)}, ErrNotYetValid},
+		{server, VerifyOptions{Roots: []*x509.Certificate{other.CACertificate()}}, ErrUntrusted},
+		{clientCI, VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, ErrKeyUsage},
+	} {
+		_, err = test.certInfo.Verify(test.opts)
+		assert.True(t, errors.Is(err, test.err), "expected %v, got %v", test.err, err)
+	}
+
+	usage, err := ExtKeyUsage("Server")
+	assert.Nil(t, err)
+	assert.Equal(t, x509.ExtKeyUsageServerAuth, usage)
+
+	_, err = ExtKeyUsage("unknown")
+	assert.NotNil(t, err)
+
+}
@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// PEM headers of the backups
+const (
+	headerKDF  = "Kdf"
+	headerSalt = "Salt"
+	backupKDF  = "scrypt-32768-8-1"
+)
+
+// SealBackup encrypts the backup with a key derived from the passphrase (scrypt with a random salt)
+// using AES-256-GCM, returned as a `CFD BACKUP` PEM block
+func SealBackup(passphrase string, backup []byte) ([]byte, error) {
+
+	var (
+		salt       []byte = make([]byte, 16)
+		key        []byte
+		ciphertext []byte
+		err        error
+	)
+
+	if passphrase == "" {
+		return []byte{}, ErrPassphraseBlank
+	}
+
+	_, err = io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	key, err = backupKey(passphrase, salt)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	ciphertext, err = seal(key, backup, backupAdditionalData(salt))
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type: FileBackup,
+		Headers: map[string]string{
+			headerKDF:  backupKDF,
+			headerSalt: base64.StdEncoding.EncodeToString(salt),
+		},
+		Bytes: ciphertext,
+	}), nil
+
+}
+
+// OpenBackup returns the backup decrypted from a `CFD BACKUP` PEM block
+func OpenBackup(passphrase string, data []byte) ([]byte, error) {
+
+	var (
+		block     *pem.Block
+		salt, key []byte
+		backup    []byte
+		err       error
+	)
+
+	block, _ = pem.Decode(data)
+	if block == nil || block.Type != FileBackup || block.Headers[headerKDF] != backupKDF {
+		return []byte{}, ErrUnparseableFile
+	}
+
+	salt, err = base64.StdEncoding.DecodeString(block.Headers[headerSalt])
+	if err != nil {
+		return []byte{}, ErrUnparseableFile
+	}
+
+	key, err = backupKey(passphrase, salt)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	backup, err = open(key, block.Bytes, backupAdditionalData(salt))
+	if err != nil {
+		return []byte{}, ErrBackupPassphrase
+	}
+
+	return backup, nil
+
+}
+
+// backupKey derives the key of the backup from the passphrase
+func backupKey(passphrase string, salt []byte) ([]byte, error) {
+
+	if passphrase == "" {
+		return []byte{}, ErrPassphraseBlank
+	}
+
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+
+}
+
+// backupAdditionalData binds the ciphertext to its headers
+func backupAdditionalData(salt []byte) []byte {
+	return append([]byte(FileBackup+backupKDF), salt...)
+}
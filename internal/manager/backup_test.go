@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackup(t *testing.T) {
+
+	backup := []byte("ca, certificates, keys and the issuance log")
+
+	sealed, err := SealBackup("correct horse", backup)
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(sealed, backup))
+
+	// every backup has its own salt
+	other, err := SealBackup("correct horse", backup)
+	assert.Nil(t, err)
+	assert.NotEqual(t, sealed, other)
+
+	opened, err := OpenBackup("correct horse", sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, backup, opened)
+
+	// must fail, wrong passphrase
+	_, err = OpenBackup("battery staple", sealed)
+	assert.Equal(t, ErrBackupPassphrase, err)
+
+	// must fail, modified
+	block, _ := pem.Decode(sealed)
+	block.Bytes[len(block.Bytes)-1] ^= 1
+	_, err = OpenBackup("correct horse", pem.EncodeToMemory(block))
+	assert.Equal(t, ErrBackupPassphrase, err)
+
+	// must fail, not a backup
+	_, err = OpenBackup("correct horse", []byte("not a backup"))
+	assert.Equal(t, ErrUnparseableFile, err)
+
+	// must fail, blank passphrase
+	_, err = SealBackup("", backup)
+	assert.Equal(t, ErrPassphraseBlank, err)
+
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -13,9 +14,6 @@ import (
 	"encoding/asn1"
 	"encoding/pem"
 	"math/big"
-	"net"
-	"net/mail"
-	"net/url"
 	"time"
 
 	"github.com/fernandezvara/certsfor/pkg/client"
@@ -25,24 +23,49 @@ import (
 // CA is the root struct that manages the certificate workflows
 type CA struct {
 	ca               *x509.Certificate
-	caKey            crypto.PrivateKey
+	caKey            crypto.Signer // local key or signer plugin
 	bytesCertificate []byte
+	chain            []*x509.Certificate // issuers of the CA, from its issuer to the root
+	ocspServers      []string            // AIA OCSP URLs for the issued certificates
+	policy           client.APIPolicy    // issuance policy for new certificates
+	backdate         time.Duration       // time NotBefore is set in the past on the issued certificates
+	log              func([]byte) error  // appends the signed certificates (DER) to the issuance log
 }
 
-func newSerial() *big.Int {
+// serialLimit is the upper bound for the serial numbers, 128 bits of randomness are used
+// as the CA/Browser Forum Baseline Requirements ask for at least 64 bits
+var serialLimit = new(big.Int).Lsh(big.NewInt(1), 128)
 
-	return big.NewInt(time.Now().UnixNano())
+// newSerial returns a new random serial number, serial numbers must be positive (RFC 5280 4.1.2.2)
+func newSerial() (serial *big.Int, err error) {
+
+	for serial == nil || serial.Sign() == 0 {
+		serial, err = rand.Int(rand.Reader, serialLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return
 
 }
 
 // APITox509Certificate creates a x509.Certificate from the API request
-func APITox509Certificate(request client.APICertificateRequest) *x509.Certificate {
+func APITox509Certificate(request client.APICertificateRequest) (*x509.Certificate, error) {
 
 	var (
-		cert    x509.Certificate
-		subject pkix.Name
+		cert     x509.Certificate
+		subject  pkix.Name
+		now      time.Time = time.Now()
+		lifetime time.Duration
+		err      error
 	)
 
+	lifetime, err = Lifetime(request)
+	if err != nil {
+		return nil, err
+	}
+
 	subject.CommonName = request.DN.CN
 
 	if request.DN.C != "" {
@@ -69,33 +92,32 @@ func APITox509Certificate(request client.APICertificateRequest) *x509.Certificat
 	}
 
 	cert = x509.Certificate{
-		SerialNumber: newSerial(),
-		Subject:      subject,
-		NotBefore:    time.Now(),
-		NotAfter:     time.Now().Add(time.Duration(request.ExpirationDays*24) * time.Hour),
-	}
-
-	for _, san := range request.SAN {
-		if ip := net.ParseIP(san); ip != nil {
-			cert.IPAddresses = append(cert.IPAddresses, ip)
-		} else {
-			email, err := mail.ParseAddress(san)
-			if email != nil && err == nil {
-				cert.EmailAddresses = append(cert.EmailAddresses, san)
-			} else {
-				// is uri?
-				u, err := url.Parse(san)
-				if u.Scheme != "" && u.Host != "" && err == nil {
-					cert.URIs = append(cert.URIs, u)
-				} else {
-					// then is a DNS name
-					cert.DNSNames = append(cert.DNSNames, san)
-				}
-			}
+		Subject:   subject,
+		NotBefore: now,
+		NotAfter:  now.Add(lifetime),
+	}
+
+	upns, err := setSANs(&cert, request.SAN)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setExtensions(&cert, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// UPNs are otherName SANs, not supported by x509 so the SAN extension is built here
+	if len(upns) > 0 {
+		var ext pkix.Extension
+		ext, err = sanExtension(&cert, upns)
+		if err != nil {
+			return nil, err
 		}
+		cert.ExtraExtensions = append(cert.ExtraExtensions, ext)
 	}
 
-	return &cert
+	return &cert, nil
 
 }
 
@@ -117,6 +139,8 @@ func apiToCryptoKey(request client.APICertificateRequest) (key crypto.PrivateKey
 		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
 	case client.ECDSA521:
 		key, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case client.ED25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
 	default:
 		err = ErrKeyInvalid
 	}
@@ -129,29 +153,40 @@ func apiToCryptoKey(request client.APICertificateRequest) (key crypto.PrivateKey
 func valid(request client.APICertificateRequest) (valid bool) {
 
 	// validation - request has the minimal required values
-	if request.DN.CN != "" || request.ExpirationDays > 0 {
+	if request.DN.CN != "" || request.ExpirationDays > 0 || request.Validity != "" {
 		valid = true
 	}
 
-	var ocurrences int
-	for _, k := range []string{client.RSA2048, client.RSA3072, client.RSA4096, client.ECDSA224, client.ECDSA256, client.ECDSA384, client.ECDSA521} {
-		if k == request.Key {
-			ocurrences++
-		}
+	if !validKey(request.Key) {
+		valid = false
 	}
 
-	if ocurrences != 1 {
+	if request.Renewal != "" && request.Renewal != client.RenewalReuseKey && request.Renewal != client.RenewalRekey {
 		valid = false
 	}
 
 	return
 }
 
+// validKey returns true if the key algorithm is known
+func validKey(key string) bool {
+
+	for _, k := range []string{client.RSA2048, client.RSA3072, client.RSA4096, client.ECDSA224, client.ECDSA256, client.ECDSA384, client.ECDSA521, client.ED25519} {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+
+}
+
 // New creates a new CA certificate/key pair ready to use
 func New(request client.APICertificateRequest) ([]byte, []byte, error) {
 
 	var (
 		ca            CA
+		key           crypto.PrivateKey
 		caCert, caKey []byte
 		err           error
 	)
@@ -161,36 +196,31 @@ func New(request client.APICertificateRequest) ([]byte, []byte, error) {
 		return []byte{}, []byte{}, rest.ErrBadRequest
 	}
 
-	ca.ca = APITox509Certificate(request)
-
-	ca.caKey, err = apiToCryptoKey(request)
+	ca.ca, err = APITox509Certificate(request)
 	if err != nil {
 		return []byte{}, []byte{}, err
 	}
 
-	ca.ca.IsCA = true
-	ca.ca.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
-	ca.ca.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
-	ca.ca.BasicConstraintsValid = true
-	ca.ca.MaxPathLenZero = true
-
-	spkiASN1, err := x509.MarshalPKIXPublicKey(ca.caKey.(crypto.Signer).Public())
+	key, err = apiToCryptoKey(request)
 	if err != nil {
 		return []byte{}, []byte{}, err
 	}
+	ca.caKey = key.(crypto.Signer)
 
-	var spki struct {
-		Algorithm        pkix.AlgorithmIdentifier
-		SubjectPublicKey asn1.BitString
-	}
-	_, err = asn1.Unmarshal(spkiASN1, &spki)
+	setAsCA(ca.ca, request.PathLength)
+
+	err = setNameConstraints(ca.ca, request.NameConstraints)
 	if err != nil {
 		return []byte{}, []byte{}, err
 	}
 
-	skid := sha1.Sum(spki.SubjectPublicKey.Bytes)
+	ca.ca.ExtKeyUsage = appendExtKeyUsage(ca.ca.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+	ca.ca.ExtKeyUsage = appendExtKeyUsage(ca.ca.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
 
-	ca.ca.SubjectKeyId = skid[:]
+	ca.ca.SubjectKeyId, err = subjectKeyID(ca.caKey)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
 
 	caCert, caKey, err = ca.CreateCertificate(ca.ca, ca.caKey)
 	if err != nil {
@@ -203,6 +233,129 @@ func New(request client.APICertificateRequest) ([]byte, []byte, error) {
 
 }
 
+// NewIntermediate creates a new subordinate CA certificate/key pair signed by the CA
+func (c *CA) NewIntermediate(request client.APICertificateRequest) ([]byte, []byte, error) {
+
+	var (
+		cert *x509.Certificate
+		key  crypto.PrivateKey
+		err  error
+	)
+
+	// validation - request has the minimal required values
+	if !valid(request) {
+		return []byte{}, []byte{}, rest.ErrBadRequest
+	}
+
+	if !c.allowsIntermediate(request.PathLength) {
+		return []byte{}, []byte{}, ErrPathLength
+	}
+
+	cert, err = APITox509Certificate(request)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	key, err = apiToCryptoKey(request)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	setAsCA(cert, request.PathLength)
+
+	err = setNameConstraints(cert, request.NameConstraints)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+	err = inheritNameConstraints(cert, c.ca)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	for _, usage := range c.ca.ExtKeyUsage {
+		cert.ExtKeyUsage = appendExtKeyUsage(cert.ExtKeyUsage, usage)
+	}
+	cert.OCSPServer = c.ocspServers
+
+	cert.SubjectKeyId, err = subjectKeyID(key)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	err = CheckPolicy(c.policy, cert, key.(crypto.Signer).Public())
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	// a subordinate CA cannot outlive its issuer
+	c.setValidity(cert)
+
+	return c.CreateCertificate(cert, key)
+
+}
+
+// allowsIntermediate returns true if the CA path length constraint allows to
+// sign a new CA that will allow `pathLength` CAs below it
+func (c *CA) allowsIntermediate(pathLength int) bool {
+
+	// MaxPathLen == -1 means the CA has no path length constraint
+	if c.ca.MaxPathLen < 0 {
+		return true
+	}
+
+	if c.ca.MaxPathLen == 0 {
+		return false
+	}
+
+	// the new CA must be more restrictive than its issuer
+	return pathLength >= 0 && pathLength < c.ca.MaxPathLen
+
+}
+
+// setAsCA fills the x509 fields required for a CA certificate
+//
+// pathLength is the number of intermediate CAs allowed below this CA,
+// 0 means none and a negative value means unlimited
+func setAsCA(cert *x509.Certificate, pathLength int) {
+
+	cert.IsCA = true
+	cert.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	cert.BasicConstraintsValid = true
+
+	switch {
+	case pathLength == 0:
+		cert.MaxPathLenZero = true
+	case pathLength > 0:
+		cert.MaxPathLen = pathLength
+	default:
+		cert.MaxPathLen = -1
+	}
+
+}
+
+// subjectKeyID returns the SHA-1 hash of the public key as described on RFC 5280 (4.2.1.2)
+func subjectKeyID(key crypto.PrivateKey) ([]byte, error) {
+
+	spkiASN1, err := x509.MarshalPKIXPublicKey(key.(crypto.Signer).Public())
+	if err != nil {
+		return []byte{}, err
+	}
+
+	var spki struct {
+		Algorithm        pkix.AlgorithmIdentifier
+		SubjectPublicKey asn1.BitString
+	}
+	_, err = asn1.Unmarshal(spkiASN1, &spki)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	skid := sha1.Sum(spki.SubjectPublicKey.Bytes)
+
+	return skid[:], nil
+
+}
+
 // CACertificateBytes returns the CA certificate []byte
 func (c *CA) CACertificateBytes() []byte {
 
@@ -210,6 +363,20 @@ func (c *CA) CACertificateBytes() []byte {
 
 }
 
+// SetOCSPServer sets the OCSP responder URLs that the certificates issued by the CA will carry
+func (c *CA) SetOCSPServer(urls ...string) {
+
+	c.ocspServers = urls
+
+}
+
+// OCSPServer returns the OCSP responder URLs that the certificates issued by the CA will carry
+func (c *CA) OCSPServer() []string {
+
+	return c.ocspServers
+
+}
+
 // CACertificate returns the x509 CA certificate
 func (c *CA) CACertificate() *x509.Certificate {
 
@@ -232,27 +399,106 @@ func (c *CA) CreateCertificateFromAPI(request client.APICertificateRequest) ([]b
 		return []byte{}, []byte{}, rest.ErrBadRequest
 	}
 
-	cert = APITox509Certificate(request)
+	cert, err = APITox509Certificate(request)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
 
 	key, err = apiToCryptoKey(request)
 	if err != nil {
 		return []byte{}, []byte{}, err
 	}
 
+	setLeafUsages(cert, request.Client, key.(crypto.Signer).Public())
+
+	err = CheckPolicy(c.policy, cert, key.(crypto.Signer).Public())
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	cert.OCSPServer = c.ocspServers
+	c.setValidity(cert)
+
+	return c.CreateCertificate(cert, key)
+
+}
+
+// RenewCertificate signs again the certificate described by request, current is the certificate
+// to renew and keyPEM its key (empty if it was signed from a CSR). If rekey is true a new key is
+// created and returned, otherwise the current key is signed again
+func (c *CA) RenewCertificate(request client.APICertificateRequest, current *x509.Certificate, keyPEM []byte, rekey bool) ([]byte, []byte, error) {
+
+	var (
+		cert    *x509.Certificate
+		key     crypto.PrivateKey
+		certPEM []byte
+		err     error
+	)
+
+	cert, err = APITox509Certificate(request)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+	cert.OCSPServer = c.ocspServers
+	c.setValidity(cert)
+
+	// certificates signed from a CSR have no key, the same public key is signed again
+	if len(keyPEM) == 0 {
+		if rekey {
+			return []byte{}, []byte{}, ErrRekeyNotAllowed
+		}
+
+		setLeafUsages(cert, request.Client, current.PublicKey)
+
+		err = CheckPolicy(c.policy, cert, current.PublicKey)
+		if err != nil {
+			return []byte{}, []byte{}, err
+		}
+
+		certPEM, err = c.SignPublicKey(cert, current.PublicKey)
+		return certPEM, []byte{}, err
+	}
+
+	if rekey {
+		key, err = apiToCryptoKey(request)
+	} else {
+		key, err = PrivateKeyFromPEM(keyPEM)
+	}
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	setLeafUsages(cert, request.Client, key.(crypto.Signer).Public())
+
+	err = CheckPolicy(c.policy, cert, key.(crypto.Signer).Public())
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	return c.CreateCertificate(cert, key)
+
+}
+
+// setLeafUsages fills the key usages of a leaf certificate based on its key and SANs
+func setLeafUsages(cert *x509.Certificate, client bool, publicKey crypto.PublicKey) {
+
 	cert.KeyUsage = x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
 
-	if request.Client {
-		cert.ExtKeyUsage = append(cert.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+	// Ed25519 keys can only be used for signatures
+	if _, ok := publicKey.(ed25519.PublicKey); ok {
+		cert.KeyUsage = x509.KeyUsageDigitalSignature
+	}
+
+	if client {
+		cert.ExtKeyUsage = appendExtKeyUsage(cert.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
 	}
 	if len(cert.IPAddresses) > 0 || len(cert.DNSNames) > 0 || len(cert.URIs) > 0 {
-		cert.ExtKeyUsage = append(cert.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
+		cert.ExtKeyUsage = appendExtKeyUsage(cert.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
 	}
 	if len(cert.EmailAddresses) > 0 {
-		cert.ExtKeyUsage = append(cert.ExtKeyUsage, x509.ExtKeyUsageEmailProtection)
+		cert.ExtKeyUsage = appendExtKeyUsage(cert.ExtKeyUsage, x509.ExtKeyUsageEmailProtection)
 	}
 
-	return c.CreateCertificate(cert, key)
-
 }
 
 // CreateCertificate creates a new certificate from the information passed as request
@@ -260,19 +506,17 @@ func (c *CA) CreateCertificateFromAPI(request client.APICertificateRequest) ([]b
 func (c *CA) CreateCertificate(request *x509.Certificate, key crypto.PrivateKey) ([]byte, []byte, error) {
 
 	var (
-		certBytes []byte
-		err       error
+		certPEM []byte
+		err     error
 	)
 
-	if request.Subject.CommonName == "" {
-		return []byte{}, []byte{}, ErrCommonNameBlank
-	}
-
 	switch key.(type) {
 	case *rsa.PrivateKey:
-		certBytes, err = x509.CreateCertificate(rand.Reader, request, c.ca, &key.(*rsa.PrivateKey).PublicKey, c.caKey)
+		certPEM, err = c.SignPublicKey(request, &key.(*rsa.PrivateKey).PublicKey)
 	case *ecdsa.PrivateKey:
-		certBytes, err = x509.CreateCertificate(rand.Reader, request, c.ca, &key.(*ecdsa.PrivateKey).PublicKey, c.caKey)
+		certPEM, err = c.SignPublicKey(request, &key.(*ecdsa.PrivateKey).PublicKey)
+	case ed25519.PrivateKey:
+		certPEM, err = c.SignPublicKey(request, key.(ed25519.PrivateKey).Public())
 	default:
 		return []byte{}, []byte{}, ErrKeyInvalid
 	}
@@ -281,6 +525,57 @@ func (c *CA) CreateCertificate(request *x509.Certificate, key crypto.PrivateKey)
 		return []byte{}, []byte{}, err
 	}
 
+	certPrivKeyPEM := new(bytes.Buffer)
+	certPrivKeyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	pem.Encode(certPrivKeyPEM, &pem.Block{
+		Type:  FilePrivateKey,
+		Bytes: certPrivKeyBytes,
+	})
+
+	return certPEM, certPrivKeyPEM.Bytes(), nil
+
+}
+
+// SignPublicKey creates a new certificate from the information passed as request for
+// a public key whose private key is not known by the CA, returns the certificate PEM
+//
+// every certificate signed gets a new random serial number and is appended to the issuance log (if set)
+func (c *CA) SignPublicKey(request *x509.Certificate, publicKey crypto.PublicKey) ([]byte, error) {
+
+	var (
+		certBytes []byte
+		err       error
+	)
+
+	if request.Subject.CommonName == "" {
+		return []byte{}, ErrCommonNameBlank
+	}
+
+	// self signed CAs are not constrained by its own names
+	if request != c.ca {
+		err = c.checkNameConstraints(request)
+		if err != nil {
+			return []byte{}, err
+		}
+	}
+
+	request.SerialNumber, err = newSerial()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	certBytes, err = x509.CreateCertificate(rand.Reader, request, c.ca, publicKey, c.caKey)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if c.log != nil {
+		err = c.log(certBytes)
+		if err != nil {
+			return []byte{}, err
+		}
+	}
+
 	certPEM := new(bytes.Buffer)
 
 	err = pem.Encode(certPEM, &pem.Block{
@@ -289,17 +584,10 @@ func (c *CA) CreateCertificate(request *x509.Certificate, key crypto.PrivateKey)
 	})
 
 	if err != nil {
-		return []byte{}, []byte{}, err
+		return []byte{}, err
 	}
 
-	certPrivKeyPEM := new(bytes.Buffer)
-	certPrivKeyBytes, err := x509.MarshalPKCS8PrivateKey(key)
-	pem.Encode(certPrivKeyPEM, &pem.Block{
-		Type:  FilePrivateKey,
-		Bytes: certPrivKeyBytes,
-	})
-
-	return certPEM.Bytes(), certPrivKeyPEM.Bytes(), nil
+	return certPEM.Bytes(), nil
 
 }
 
@@ -314,7 +602,7 @@ func CertificateFromPEM(certPEM []byte) (cert *x509.Certificate, err error) {
 
 }
 
-// PrivateKeyFromPEM returns a crypto.PrivateKey (rsa or ecdsa) from the PEM bytes
+// PrivateKeyFromPEM returns a crypto.PrivateKey (rsa, ecdsa or ed25519) from the PEM bytes
 func PrivateKeyFromPEM(keyPEM []byte) (key crypto.PrivateKey, err error) {
 
 	block, _ := pem.Decode(keyPEM)
@@ -349,10 +637,25 @@ func FromBytes(caCertificate, caKey []byte) (*CA, error) {
 		return nil, err
 	}
 
-	ca.caKey, err = PrivateKeyFromPEM(caKey)
+	// the key is held by a signer plugin, the store only knows how to reach it
+	if IsKeyReference(caKey) {
+		ca.caKey, err = signerFromReference(caKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if !publicKeyEqual(ca.caKey.Public(), ca.ca.PublicKey) {
+			return nil, ErrKeyMismatch
+		}
+
+		return &ca, nil
+	}
+
+	key, err := PrivateKeyFromPEM(caKey)
 	if err != nil {
 		return nil, err
 	}
+	ca.caKey = key.(crypto.Signer)
 
 	return &ca, err
 
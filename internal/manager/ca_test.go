@@ -1,6 +1,10 @@
 package manager
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
 	"net"
 	"net/url"
 	"testing"
@@ -8,6 +12,7 @@ import (
 	"github.com/fernandezvara/certsfor/pkg/client"
 	"github.com/fernandezvara/rest"
 	"github.com/stretchr/testify/assert"
+	"software.sslmate.com/src/go-pkcs12"
 )
 
 func TestNewCA(t *testing.T) {
@@ -39,6 +44,7 @@ func TestNewCA(t *testing.T) {
 	assert.Len(t, newCA2.ca.Issuer.SerialNumber, 0)
 
 	assert.True(t, newCA2.ca.IsCA)
+	assert.Equal(t, x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign|x509.KeyUsageCRLSign, newCA2.ca.KeyUsage)
 
 	assert.Equal(t, newCA2.ca.Subject.CommonName, request.DN.CN)
 
@@ -77,7 +83,7 @@ func TestDifferentKeyTypes(t *testing.T) {
 		err     error
 	)
 
-	for _, typ := range []string{client.RSA2048, client.RSA3072, client.RSA4096, client.ECDSA224, client.ECDSA256, client.ECDSA384, client.ECDSA521} {
+	for _, typ := range []string{client.RSA2048, client.RSA3072, client.RSA4096, client.ECDSA224, client.ECDSA256, client.ECDSA384, client.ECDSA521, client.ED25519} {
 
 		request.DN.CN = "Test"
 		request.ExpirationDays = 90
@@ -194,6 +200,104 @@ func TestSANsAndAPIrequests(t *testing.T) {
 
 }
 
+func TestIntermediateCA(t *testing.T) {
+
+	var (
+		rootRequest, intermediateRequest, leafRequest client.APICertificateRequest
+	)
+
+	rootRequest.DN.CN = "root"
+	rootRequest.ExpirationDays = 90
+	rootRequest.Key = client.ECDSA256
+	rootRequest.PathLength = 1
+
+	rootCert, rootKey, err := New(rootRequest)
+	assert.Nil(t, err)
+
+	root, err := FromBytes(rootCert, rootKey)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, root.ca.MaxPathLen)
+
+	intermediateRequest.DN.CN = "intermediate"
+	intermediateRequest.ExpirationDays = 365 // must be capped to the root expiration
+	intermediateRequest.Key = client.ECDSA256
+
+	intermediateCert, intermediateKey, err := root.NewIntermediate(intermediateRequest)
+	assert.Nil(t, err)
+
+	intermediate, err := FromBytes(intermediateCert, intermediateKey)
+	assert.Nil(t, err)
+	assert.True(t, intermediate.ca.IsCA)
+	assert.True(t, intermediate.ca.MaxPathLenZero)
+	assert.Equal(t, root.ca.SubjectKeyId, intermediate.ca.AuthorityKeyId)
+	assert.False(t, intermediate.ca.NotAfter.After(root.ca.NotAfter))
+
+	// path length 0 does not allow more intermediates
+	_, _, err = intermediate.NewIntermediate(intermediateRequest)
+	assert.Equal(t, ErrPathLength, err)
+
+	leafRequest.DN.CN = "leaf"
+	leafRequest.ExpirationDays = 30
+	leafRequest.Key = client.ECDSA256
+	leafRequest.SAN = []string{"leaf.example.com"}
+
+	leafCert, _, err := intermediate.CreateCertificateFromAPI(leafRequest)
+	assert.Nil(t, err)
+
+	leaf, err := CertificateFromPEM(leafCert)
+	assert.Nil(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.ca)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(intermediate.ca)
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       "leaf.example.com",
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	assert.Nil(t, err)
+
+}
+
+func TestEd25519(t *testing.T) {
+
+	var request client.APICertificateRequest
+
+	request.DN.CN = "ed25519 ca"
+	request.ExpirationDays = 90
+	request.Key = client.ED25519
+
+	caCert, caKey, err := New(request)
+	assert.Nil(t, err)
+
+	ca, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+	assert.Equal(t, x509.Ed25519, ca.CACertificate().PublicKeyAlgorithm)
+
+	request.DN.CN = "ed25519 leaf"
+	request.SAN = []string{"www.example.com"}
+
+	certFile, keyFile, err := ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	cert, err := CertificateFromPEM(certFile)
+	assert.Nil(t, err)
+	assert.Equal(t, x509.PureEd25519, cert.SignatureAlgorithm)
+	assert.Equal(t, x509.KeyUsageDigitalSignature, cert.KeyUsage)
+	assert.Nil(t, cert.CheckSignatureFrom(ca.CACertificate()))
+
+	key, err := PrivateKeyFromPEM(keyFile)
+	assert.Nil(t, err)
+	assert.IsType(t, ed25519.PrivateKey{}, key)
+
+	pfx, err := pkcs12.Encode(rand.Reader, key, cert, []*x509.Certificate{ca.CACertificate()}, "changeit")
+	assert.Nil(t, err)
+	assert.Greater(t, len(pfx), 0)
+
+}
+
 func TestUnparseableFiles(t *testing.T) {
 
 	goodCert := `-----BEGIN CERTIFICATE-----
@@ -373,3 +477,76 @@ nV3Z8XSWsFRPth5prC//U9OnGFAcKy9rHMtGR6vO1BuTdwWsNY+R0LQUJ3C1
 	assert.IsType(t, err, ErrUnparseableFile)
 
 }
+
+func TestRenewCertificate(t *testing.T) {
+
+	var caRequest, request client.APICertificateRequest
+
+	caRequest.DN.CN = "ca"
+	caRequest.ExpirationDays = 90
+	caRequest.Key = client.ECDSA256
+
+	caCert, caKey, err := New(caRequest)
+	assert.Nil(t, err)
+
+	ca, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+
+	request.DN.CN = "renew"
+	request.SAN = []string{"renew.example.com"}
+	request.ExpirationDays = 30
+	request.Key = client.ECDSA256
+	request.Client = true
+
+	certPEM, keyPEM, err := ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	current, err := CertificateFromPEM(certPEM)
+	assert.Nil(t, err)
+
+	// same key, usages are kept
+	renewedPEM, renewedKeyPEM, err := ca.RenewCertificate(request, current, keyPEM, false)
+	assert.Nil(t, err)
+	assert.Equal(t, keyPEM, renewedKeyPEM)
+
+	renewed, err := CertificateFromPEM(renewedPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, current.PublicKey, renewed.PublicKey)
+	assert.NotEqual(t, current.SerialNumber, renewed.SerialNumber)
+	assert.Equal(t, current.KeyUsage, renewed.KeyUsage)
+	assert.Equal(t, current.ExtKeyUsage, renewed.ExtKeyUsage)
+	assert.Nil(t, renewed.CheckSignatureFrom(ca.CACertificate()))
+
+	// new key
+	renewedPEM, renewedKeyPEM, err = ca.RenewCertificate(request, current, keyPEM, true)
+	assert.Nil(t, err)
+	assert.NotEqual(t, keyPEM, renewedKeyPEM)
+
+	renewed, err = CertificateFromPEM(renewedPEM)
+	assert.Nil(t, err)
+	assert.NotEqual(t, current.PublicKey, renewed.PublicKey)
+	assert.Equal(t, current.ExtKeyUsage, renewed.ExtKeyUsage)
+
+	key, err := PrivateKeyFromPEM(renewedKeyPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, key.(crypto.Signer).Public(), renewed.PublicKey)
+
+	// certificates without key (signed from a CSR) sign the public key again
+	renewedPEM, renewedKeyPEM, err = ca.RenewCertificate(request, current, []byte{}, false)
+	assert.Nil(t, err)
+	assert.Len(t, renewedKeyPEM, 0)
+
+	renewed, err = CertificateFromPEM(renewedPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, current.PublicKey, renewed.PublicKey)
+
+	// must fail, certificates without key cannot be rekeyed
+	_, _, err = ca.RenewCertificate(request, current, []byte{}, true)
+	assert.Equal(t, ErrRekeyNotAllowed, err)
+
+	// must fail, unknown renewal policy
+	request.Renewal = "unknown"
+	_, _, err = ca.CreateCertificateFromAPI(request)
+	assert.Equal(t, rest.ErrBadRequest, err)
+
+}
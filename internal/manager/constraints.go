@@ -0,0 +1,330 @@
+package manager
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+)
+
+// setNameConstraints fills the name constraints of a CA certificate, the extension is always
+// marked as critical (RFC 5280 4.2.1.10)
+func setNameConstraints(cert *x509.Certificate, constraints client.APINameConstraints) (err error) {
+
+	cert.PermittedDNSDomainsCritical = true
+	cert.PermittedDNSDomains = constraints.PermittedDNS
+	cert.ExcludedDNSDomains = constraints.ExcludedDNS
+	cert.PermittedEmailAddresses = constraints.PermittedEmails
+	cert.ExcludedEmailAddresses = constraints.ExcludedEmails
+	cert.PermittedURIDomains = constraints.PermittedURIs
+	cert.ExcludedURIDomains = constraints.ExcludedURIs
+
+	cert.PermittedIPRanges, err = parseIPRanges(constraints.PermittedIPs)
+	if err != nil {
+		return
+	}
+
+	cert.ExcludedIPRanges, err = parseIPRanges(constraints.ExcludedIPs)
+
+	return
+
+}
+
+// inheritNameConstraints restricts a subordinate CA to the issuer name constraints, so it cannot be
+// used to issue certificates the issuer is not allowed to. Permitted names of the subordinate CA
+// must be inside the issuer ones (they are inherited if it does not set its own for the type) and
+// the issuer excluded names are always kept
+func inheritNameConstraints(cert, issuer *x509.Certificate) (err error) {
+
+	cert.PermittedDNSDomains, err = inheritPermitted(cert.PermittedDNSDomains, issuer.PermittedDNSDomains, domainWithin)
+	if err != nil {
+		return
+	}
+	cert.PermittedEmailAddresses, err = inheritPermitted(cert.PermittedEmailAddresses, issuer.PermittedEmailAddresses, emailWithin)
+	if err != nil {
+		return
+	}
+	cert.PermittedURIDomains, err = inheritPermitted(cert.PermittedURIDomains, issuer.PermittedURIDomains, domainWithin)
+	if err != nil {
+		return
+	}
+
+	if len(cert.PermittedIPRanges) == 0 {
+		cert.PermittedIPRanges = issuer.PermittedIPRanges
+	}
+	for _, ipNet := range cert.PermittedIPRanges {
+		if !ipNetWithin(ipNet, issuer.PermittedIPRanges) {
+			return fmt.Errorf("%w: %s", ErrNameConstraints, ipNet.String())
+		}
+	}
+
+	cert.ExcludedDNSDomains = append(cert.ExcludedDNSDomains, issuer.ExcludedDNSDomains...)
+	cert.ExcludedEmailAddresses = append(cert.ExcludedEmailAddresses, issuer.ExcludedEmailAddresses...)
+	cert.ExcludedURIDomains = append(cert.ExcludedURIDomains, issuer.ExcludedURIDomains...)
+	cert.ExcludedIPRanges = append(cert.ExcludedIPRanges, issuer.ExcludedIPRanges...)
+
+	return
+
+}
+
+// inheritPermitted returns the permitted subtrees of the subordinate CA, the issuer ones if it has
+// none. ErrNameConstraints is returned if any of them is not inside the issuer permitted subtrees
+func inheritPermitted(permitted, issuer []string, within func(subtree, constraint string) bool) ([]string, error) {
+
+	if len(permitted) == 0 {
+		return issuer, nil
+	}
+
+	if len(issuer) == 0 {
+		return permitted, nil
+	}
+
+	for _, subtree := range permitted {
+		inside := false
+		for _, constraint := range issuer {
+			if within(subtree, constraint) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			return nil, fmt.Errorf("%w: %s", ErrNameConstraints, subtree)
+		}
+	}
+
+	return permitted, nil
+
+}
+
+// domainWithin returns true if every domain allowed by the subtree is allowed by the constraint
+func domainWithin(subtree, constraint string) bool {
+
+	if constraint == "" {
+		return true
+	}
+
+	if strings.HasPrefix(subtree, ".") {
+		domain := strings.TrimPrefix(subtree, ".")
+		return matchDomain(domain, constraint) || strings.EqualFold(domain, strings.TrimPrefix(constraint, "."))
+	}
+
+	return subtree != "" && matchDomain(subtree, constraint)
+
+}
+
+// emailWithin returns true if every email allowed by the subtree is allowed by the constraint
+func emailWithin(subtree, constraint string) bool {
+
+	if strings.Contains(subtree, "@") {
+		return matchEmail(subtree, constraint)
+	}
+
+	if strings.HasPrefix(subtree, ".") {
+		host := strings.TrimPrefix(subtree, ".")
+		// subdomains are only inside subdomain constraints
+		return strings.HasPrefix(constraint, ".") && (matchEmail("@"+host, constraint) || strings.EqualFold(host, strings.TrimPrefix(constraint, ".")))
+	}
+
+	return subtree != "" && matchEmail("@"+subtree, constraint)
+
+}
+
+// ipNetWithin returns true if the range is inside any of the permitted ranges (or there are none)
+func ipNetWithin(ipNet *net.IPNet, permitted []*net.IPNet) bool {
+
+	if len(permitted) == 0 {
+		return true
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	for _, constraint := range permitted {
+		constraintOnes, constraintBits := constraint.Mask.Size()
+		if bits == constraintBits && ones >= constraintOnes && constraint.Contains(ipNet.IP) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+func parseIPRanges(ranges []string) (ipNets []*net.IPNet, err error) {
+
+	for _, r := range ranges {
+		var ipNet *net.IPNet
+		_, ipNet, err = net.ParseCIDR(r)
+		if err != nil {
+			return nil, ErrNameConstraintsInvalid
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+
+	return
+
+}
+
+// checkNameConstraints returns ErrNameConstraints if any of the certificate names is not allowed
+// by the name constraints of the CA or its issuers, the name is added to the error message
+func (c *CA) checkNameConstraints(cert *x509.Certificate) error {
+
+	for _, constraints := range append([]*x509.Certificate{c.ca}, c.chain...) {
+		if err := checkNames(cert, constraints); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// checkNames returns ErrNameConstraints if any of the certificate names is not allowed by the
+// name constraints of the CA certificate
+func checkNames(cert, ca *x509.Certificate) error {
+
+	for _, name := range cert.DNSNames {
+		if !allowedName(name, ca.PermittedDNSDomains, ca.ExcludedDNSDomains, matchDomain) {
+			return fmt.Errorf("%w: %s", ErrNameConstraints, name)
+		}
+	}
+
+	for _, email := range cert.EmailAddresses {
+		if !allowedName(email, ca.PermittedEmailAddresses, ca.ExcludedEmailAddresses, matchEmail) {
+			return fmt.Errorf("%w: %s", ErrNameConstraints, email)
+		}
+	}
+
+	for _, uri := range cert.URIs {
+		// URI constraints are domains, so URIs without domain cannot be checked
+		host := uri.Hostname()
+		constrained := len(ca.PermittedURIDomains) > 0 || len(ca.ExcludedURIDomains) > 0
+		if constrained && (host == "" || net.ParseIP(host) != nil) {
+			return fmt.Errorf("%w: %s", ErrNameConstraints, uri.String())
+		}
+		if !allowedName(host, ca.PermittedURIDomains, ca.ExcludedURIDomains, matchDomain) {
+			return fmt.Errorf("%w: %s", ErrNameConstraints, uri.String())
+		}
+	}
+
+	for _, ip := range cert.IPAddresses {
+		if !allowedIP(ip, ca.PermittedIPRanges, ca.ExcludedIPRanges) {
+			return fmt.Errorf("%w: %s", ErrNameConstraints, ip.String())
+		}
+	}
+
+	return nil
+
+}
+
+// SetChain sets the certificates of the CA issuers (PEM, from its issuer to the root), the names
+// of the certificates signed by the CA must be allowed by their name constraints too
+func (c *CA) SetChain(chainPEM []byte) error {
+
+	var chain []*x509.Certificate
+
+	for block, rest := pem.Decode(chainPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != FileCertificate {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return ErrUnparseableFile
+		}
+		chain = append(chain, cert)
+	}
+
+	c.chain = chain
+
+	return nil
+
+}
+
+// allowedName returns true if the name does not match any excluded constraint and matches a
+// permitted one (if any)
+func allowedName(name string, permitted, excluded []string, match func(name, constraint string) bool) bool {
+
+	for _, constraint := range excluded {
+		if match(name, constraint) {
+			return false
+		}
+	}
+
+	if len(permitted) == 0 {
+		return true
+	}
+
+	for _, constraint := range permitted {
+		if match(name, constraint) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+func allowedIP(ip net.IP, permitted, excluded []*net.IPNet) bool {
+
+	for _, ipNet := range excluded {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+
+	if len(permitted) == 0 {
+		return true
+	}
+
+	for _, ipNet := range permitted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// matchDomain returns true if the domain is the constraint or one of its subdomains,
+// constraints with a leading dot only match subdomains
+func matchDomain(domain, constraint string) bool {
+
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	constraint = strings.ToLower(constraint)
+
+	if constraint == "" {
+		return true
+	}
+
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(domain, constraint)
+	}
+
+	return domain == constraint || strings.HasSuffix(domain, "."+constraint)
+
+}
+
+// matchEmail returns true if the email is the mailbox of the constraint or its host is the
+// constraint domain, constraints with a leading dot match any subdomain
+func matchEmail(email, constraint string) bool {
+
+	if strings.Contains(constraint, "@") {
+		return strings.EqualFold(email, constraint)
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	host := strings.ToLower(email[at+1:])
+
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(host, strings.ToLower(constraint))
+	}
+
+	return host == strings.ToLower(constraint)
+
+}
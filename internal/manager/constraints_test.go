@@ -0,0 +1,137 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameConstraints(t *testing.T) {
+
+	var request client.APICertificateRequest
+
+	request.DN.CN = "team ca"
+	request.ExpirationDays = 90
+	request.Key = client.ECDSA256
+	request.PathLength = 1
+	request.NameConstraints = client.APINameConstraints{
+		PermittedDNS:    []string{"team.example.com"},
+		ExcludedDNS:     []string{"secret.team.example.com"},
+		PermittedIPs:    []string{"10.10.0.0/16"},
+		PermittedEmails: []string{"team.example.com"},
+		PermittedURIs:   []string{".team.example.com"},
+	}
+
+	caCert, caKey, err := New(request)
+	assert.Nil(t, err)
+
+	ca, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+	assert.True(t, ca.CACertificate().PermittedDNSDomainsCritical)
+	assert.Equal(t, []string{"team.example.com"}, ca.CACertificate().PermittedDNSDomains)
+	assert.Len(t, ca.CACertificate().PermittedIPRanges, 1)
+
+	for _, san := range []string{
+		"team.example.com",
+		"www.team.example.com",
+		"10.10.1.1",
+		"john@team.example.com",
+		"spiffe://svc.team.example.com/api",
+	} {
+		leaf := client.APICertificateRequest{DN: client.APIDN{CN: san}, SAN: []string{san}, ExpirationDays: 30, Key: client.ECDSA256}
+		_, _, err = ca.CreateCertificateFromAPI(leaf)
+		assert.Nil(t, err, san)
+	}
+
+	for _, san := range []string{
+		"example.com",
+		"evilteam.example.com",
+		"www.secret.team.example.com",
+		"10.11.0.1",
+		"john@other.example.com",
+		"john@sub.team.example.com",
+		"spiffe://team.example.com/api",
+		"spiffe://10.10.0.1/api",
+	} {
+		leaf := client.APICertificateRequest{DN: client.APIDN{CN: san}, SAN: []string{san}, ExpirationDays: 30, Key: client.ECDSA256}
+		_, _, err = ca.CreateCertificateFromAPI(leaf)
+		assert.True(t, errors.Is(err, ErrNameConstraints), san)
+	}
+
+	// intermediates inherit the constraints
+	request.DN.CN = "sub ca"
+	request.PathLength = 0
+	request.NameConstraints = client.APINameConstraints{
+		PermittedDNS: []string{"www.team.example.com"},
+	}
+
+	subCert, subKey, err := ca.NewIntermediate(request)
+	assert.Nil(t, err)
+
+	sub, err := FromBytes(subCert, subKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"www.team.example.com"}, sub.CACertificate().PermittedDNSDomains)
+	assert.Equal(t, []string{"secret.team.example.com"}, sub.CACertificate().ExcludedDNSDomains)
+	assert.Len(t, sub.CACertificate().PermittedIPRanges, 1)
+
+	_, _, err = sub.CreateCertificateFromAPI(client.APICertificateRequest{DN: client.APIDN{CN: "api"}, SAN: []string{"api.team.example.com"}, ExpirationDays: 30, Key: client.ECDSA256})
+	assert.True(t, errors.Is(err, ErrNameConstraints))
+
+	// intermediates cannot widen the constraints of its issuer
+	for _, constraints := range []client.APINameConstraints{
+		{PermittedDNS: []string{"example.com"}},
+		{PermittedDNS: []string{"www.team.example.org"}},
+		{PermittedDNS: []string{".example.com"}},
+		{PermittedIPs: []string{"10.0.0.0/8"}},
+		{PermittedIPs: []string{"10.11.0.0/24"}},
+		{PermittedEmails: []string{"example.com"}},
+		{PermittedEmails: []string{".team.example.com"}},
+		{PermittedURIs: []string{"team.example.com"}},
+	} {
+		request.DN.CN = "wider"
+		request.NameConstraints = constraints
+		_, _, err = ca.NewIntermediate(request)
+		assert.True(t, errors.Is(err, ErrNameConstraints), constraints)
+	}
+
+	request.DN.CN = "narrower"
+	request.NameConstraints = client.APINameConstraints{
+		PermittedDNS:    []string{".www.team.example.com"},
+		PermittedIPs:    []string{"10.10.1.0/24"},
+		PermittedEmails: []string{"john@team.example.com"},
+		PermittedURIs:   []string{"svc.team.example.com"},
+		ExcludedDNS:     []string{"private.www.team.example.com"},
+	}
+	subCert, _, err = ca.NewIntermediate(request)
+	assert.Nil(t, err)
+
+	narrower, err := CertificateFromPEM(subCert)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"private.www.team.example.com", "secret.team.example.com"}, narrower.ExcludedDNSDomains)
+
+	// the names are checked against the issuers of the CA too
+	request.DN.CN = "unconstrained"
+	request.NameConstraints = client.APINameConstraints{}
+	unconstrainedCert, unconstrainedKey, err := New(request)
+	assert.Nil(t, err)
+
+	unconstrained, err := FromBytes(unconstrainedCert, unconstrainedKey)
+	assert.Nil(t, err)
+	assert.Nil(t, unconstrained.SetChain(caCert))
+
+	_, _, err = unconstrained.CreateCertificateFromAPI(client.APICertificateRequest{DN: client.APIDN{CN: "www"}, SAN: []string{"www.example.org"}, ExpirationDays: 30, Key: client.ECDSA256})
+	assert.True(t, errors.Is(err, ErrNameConstraints))
+
+	_, _, err = unconstrained.CreateCertificateFromAPI(client.APICertificateRequest{DN: client.APIDN{CN: "www"}, SAN: []string{"www.team.example.com"}, ExpirationDays: 30, Key: client.ECDSA256})
+	assert.Nil(t, err)
+
+	// invalid ranges
+	request.DN.CN = "invalid"
+	request.NameConstraints = client.APINameConstraints{PermittedIPs: []string{"10.0.0.1"}}
+
+	_, _, err = New(request)
+	assert.Equal(t, ErrNameConstraintsInvalid, err)
+
+}
@@ -4,13 +4,41 @@ import "errors"
 
 // File literals constants
 const (
-	FilePrivateKey  = "PRIVATE KEY"
-	FileCertificate = "CERTIFICATE"
+	FilePrivateKey   = "PRIVATE KEY"
+	FileCertificate  = "CERTIFICATE"
+	FileCSR          = "CERTIFICATE REQUEST"
+	FileCRL          = "X509 CRL"
+	FileEncryptedKey = "CFD ENCRYPTED KEY"
+	FileKeyReference = "CFD KEY REFERENCE"
+	FileBackup       = "CFD BACKUP"
 )
 
 // Errors
 var (
-	ErrUnparseableFile = errors.New("unparseable file")
-	ErrCommonNameBlank = errors.New("common name cannot be blank")
-	ErrKeyInvalid      = errors.New("key has invalid type")
+	ErrUnparseableFile        = errors.New("unparseable file")
+	ErrCommonNameBlank        = errors.New("common name cannot be blank")
+	ErrKeyInvalid             = errors.New("key has invalid type")
+	ErrPathLength             = errors.New("path length constraint does not allow this intermediate CA")
+	ErrCSRSignature           = errors.New("certificate signing request signature is invalid")
+	ErrReasonInvalid          = errors.New("revocation reason is invalid")
+	ErrExtensionInvalid       = errors.New("extension is invalid")
+	ErrNameConstraints        = errors.New("name is not allowed by the CA name constraints")
+	ErrNameConstraintsInvalid = errors.New("name constraints are invalid")
+	ErrSANInvalid             = errors.New("subject alternative name is invalid")
+	ErrNotCA                  = errors.New("certificate is not a CA")
+	ErrKeyMismatch            = errors.New("key does not match the certificate")
+	ErrKEKInvalid             = errors.New("key encryption key must be 32 bytes or a passphrase")
+	ErrKEKMismatch            = errors.New("key was encrypted with a different key encryption key")
+	ErrKEKRequired            = errors.New("key is encrypted, a key encryption key is required")
+	ErrRekeyNotAllowed        = errors.New("certificates signed from a CSR cannot be rekeyed")
+	ErrNotRoot                = errors.New("certificate is not a root CA")
+	ErrProfileInvalid         = errors.New("profile is invalid")
+	ErrPolicyInvalid          = errors.New("policy is invalid")
+	ErrValidityInvalid        = errors.New("validity must be a positive duration (ex: 6h)")
+	ErrSSHPublicKey           = errors.New("public key must be an OpenSSH public key")
+	ErrSSHRequestInvalid      = errors.New("ssh certificate request is invalid")
+	ErrTreeHeadSignature      = errors.New("log tree head signature is invalid")
+	ErrPassphraseBlank        = errors.New("passphrase cannot be blank")
+	ErrBackupPassphrase       = errors.New("backup passphrase is wrong or the backup is corrupted")
+	ErrSignerUnknown          = errors.New("signer plugin is not configured on the server (ca.signers)")
 )
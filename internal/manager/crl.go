@@ -0,0 +1,136 @@
+package manager
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+)
+
+var (
+	oidExtensionReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+	// reasonCodes maps the reasons with its CRLReason value (RFC 5280, 5.3.1)
+	reasonCodes = map[string]int{
+		client.ReasonUnspecified:          0,
+		client.ReasonKeyCompromise:        1,
+		client.ReasonCACompromise:         2,
+		client.ReasonAffiliationChanged:   3,
+		client.ReasonSuperseded:           4,
+		client.ReasonCessationOfOperation: 5,
+		client.ReasonCertificateHold:      6,
+		client.ReasonPrivilegeWithdrawn:   9,
+		client.ReasonAACompromise:         10,
+	}
+)
+
+// ReasonCode returns the CRLReason code for the reason, an empty reason is unspecified
+func ReasonCode(reason string) (int, error) {
+
+	if reason == "" {
+		return 0, nil
+	}
+
+	if code, ok := reasonCodes[reason]; ok {
+		return code, nil
+	}
+
+	return 0, ErrReasonInvalid
+
+}
+
+// SerialToString returns the representation used to store the serial numbers
+func SerialToString(serial *big.Int) string {
+
+	return serial.Text(16)
+
+}
+
+// SerialFromString parses a serial number as returned by SerialToString, the colon
+// separated format used by openssl is also allowed
+func SerialFromString(serial string) (*big.Int, bool) {
+
+	serial = strings.TrimPrefix(strings.ReplaceAll(serial, ":", ""), "0x")
+	if serial == "" {
+		return nil, false
+	}
+
+	return new(big.Int).SetString(serial, 16)
+
+}
+
+// CRL returns a DER encoded certificate revocation list signed by the CA with the revocations passed
+//
+// The CRL number is taken from the current time so it always increases
+func (c *CA) CRL(revocations []client.Revocation, nextUpdate time.Duration) ([]byte, error) {
+
+	var (
+		now     time.Time = time.Now()
+		revoked []pkix.RevokedCertificate
+		issuer  x509.Certificate
+	)
+
+	for _, revocation := range revocations {
+
+		var (
+			serial *big.Int
+			code   int
+			ok     bool
+			err    error
+		)
+
+		serial, ok = SerialFromString(revocation.Serial)
+		if !ok {
+			return []byte{}, ErrUnparseableFile
+		}
+
+		entry := pkix.RevokedCertificate{
+			SerialNumber:   serial,
+			RevocationTime: revocation.Time,
+		}
+
+		code, err = ReasonCode(revocation.Reason)
+		if err != nil {
+			return []byte{}, err
+		}
+
+		// unspecified reason must not be encoded
+		if code != 0 {
+			value, err := asn1.Marshal(asn1.Enumerated(code))
+			if err != nil {
+				return []byte{}, err
+			}
+			entry.Extensions = append(entry.Extensions, pkix.Extension{Id: oidExtensionReasonCode, Value: value})
+		}
+
+		revoked = append(revoked, entry)
+
+	}
+
+	// CAs created before the CRL sign usage existed can still sign its CRL,
+	// strict clients could reject it
+	issuer = *c.ca
+	issuer.KeyUsage |= x509.KeyUsageCRLSign
+
+	// imported CAs could lack the subject key identifier, required for the CRL authority key identifier
+	if len(issuer.SubjectKeyId) == 0 {
+		var err error
+		issuer.SubjectKeyId, err = subjectKeyID(c.caKey)
+		if err != nil {
+			return []byte{}, err
+		}
+	}
+
+	return x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificates: revoked,
+		Number:              big.NewInt(now.UnixNano()),
+		ThisUpdate:          now,
+		NextUpdate:          now.Add(nextUpdate),
+	}, &issuer, c.caKey)
+
+}
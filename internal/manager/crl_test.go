@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCRL(t *testing.T) {
+
+	var request client.APICertificateRequest
+
+	request.DN.CN = "crl ca"
+	request.ExpirationDays = 90
+	request.Key = client.ECDSA256
+
+	caCert, caKey, err := New(request)
+	assert.Nil(t, err)
+
+	ca, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+
+	revokedAt := time.Now().UTC().Truncate(time.Second)
+
+	crlBytes, err := ca.CRL([]client.Revocation{
+		{Serial: SerialToString(big.NewInt(1000)), CN: "one", Time: revokedAt, Reason: client.ReasonKeyCompromise},
+		{Serial: SerialToString(big.NewInt(2000)), CN: "two", Time: revokedAt, Reason: client.ReasonUnspecified},
+	}, time.Hour)
+	assert.Nil(t, err)
+
+	crl, err := x509.ParseCRL(crlBytes)
+	assert.Nil(t, err)
+	assert.Nil(t, ca.CACertificate().CheckCRLSignature(crl))
+	assert.Len(t, crl.TBSCertList.RevokedCertificates, 2)
+	assert.True(t, crl.TBSCertList.NextUpdate.After(time.Now().Add(59*time.Minute)))
+
+	first := crl.TBSCertList.RevokedCertificates[0]
+	assert.Equal(t, big.NewInt(1000), first.SerialNumber)
+	assert.True(t, revokedAt.Equal(first.RevocationTime))
+	assert.Len(t, first.Extensions, 1)
+
+	var code asn1.Enumerated
+	_, err = asn1.Unmarshal(first.Extensions[0].Value, &code)
+	assert.Nil(t, err)
+	assert.Equal(t, asn1.Enumerated(1), code)
+
+	// unspecified reason is not encoded
+	assert.Len(t, crl.TBSCertList.RevokedCertificates[1].Extensions, 0)
+
+	// empty CRL
+	_, err = ca.CRL([]client.Revocation{}, time.Hour)
+	assert.Nil(t, err)
+
+	_, err = ca.CRL([]client.Revocation{{Serial: "not hex", Time: revokedAt}}, time.Hour)
+	assert.Equal(t, ErrUnparseableFile, err)
+
+	_, err = ca.CRL([]client.Revocation{{Serial: "10", Time: revokedAt, Reason: "invalid"}}, time.Hour)
+	assert.Equal(t, ErrReasonInvalid, err)
+
+}
+
+func TestReasonCode(t *testing.T) {
+
+	code, err := ReasonCode("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, code)
+
+	code, err = ReasonCode(client.ReasonAACompromise)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, code)
+
+	_, err = ReasonCode("removeFromCRL")
+	assert.Equal(t, ErrReasonInvalid, err)
+
+}
+
+func TestSerial(t *testing.T) {
+
+	first, err := newSerial()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, first.Sign())
+	assert.LessOrEqual(t, first.BitLen(), 128)
+
+	second, err := newSerial()
+	assert.Nil(t, err)
+	assert.NotEqual(t, 0, first.Cmp(second))
+
+	serial, ok := SerialFromString(SerialToString(first))
+	assert.True(t, ok)
+	assert.Equal(t, 0, first.Cmp(serial))
+
+	// openssl format
+	serial, ok = SerialFromString("01:0A:ff")
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(0x010aff), serial)
+
+	_, ok = SerialFromString("")
+	assert.False(t, ok)
+
+	_, ok = SerialFromString("not hex")
+	assert.False(t, ok)
+
+}
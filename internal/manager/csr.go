@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+)
+
+// CSRFromPEM returns a x509.CertificateRequest from the PEM bytes once its signature is verified
+func CSRFromPEM(csrPEM []byte) (csr *x509.CertificateRequest, err error) {
+
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != FileCSR {
+		err = ErrUnparseableFile
+		return
+	}
+
+	csr, err = x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, ErrUnparseableFile
+	}
+
+	if csr.CheckSignature() != nil {
+		return nil, ErrCSRSignature
+	}
+
+	return
+
+}
+
+// CSRToAPI returns the API request that represents the subject and SANs (typed) of a CSR
+func CSRToAPI(csr *x509.CertificateRequest) (request client.APICertificateRequest) {
+
+	request.DN = subjectToAPI(csr.Subject)
+	request.SAN = apiSANs(csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, csr.URIs, csr.Extensions)
+
+	return
+
+}
+
+func first(values []string) string {
+
+	if len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+
+}
+
+// CreateCertificateFromCSR signs the certificate signing request, expiration and client usage
+// are taken from the request. Returns the certificate PEM and the request that represents it
+func (c *CA) CreateCertificateFromCSR(request client.APICSRRequest) ([]byte, client.APICertificateRequest, error) {
+
+	var (
+		csr        *x509.CertificateRequest
+		apiRequest client.APICertificateRequest
+		cert       *x509.Certificate
+		certPEM    []byte
+		err        error
+	)
+
+	if request.ExpirationDays <= 0 && request.Validity == "" {
+		return []byte{}, apiRequest, rest.ErrBadRequest
+	}
+
+	csr, err = CSRFromPEM(request.CSR)
+	if err != nil {
+		return []byte{}, apiRequest, err
+	}
+
+	apiRequest = CSRToAPI(csr)
+	apiRequest.ExpirationDays = request.ExpirationDays
+	apiRequest.Validity = request.Validity
+	apiRequest.Client = request.Client
+
+	cert, err = APITox509Certificate(apiRequest)
+	if err != nil {
+		return []byte{}, apiRequest, err
+	}
+
+	// SANs are taken as they come on the CSR, no need to guess its type
+	cert.DNSNames = csr.DNSNames
+	cert.IPAddresses = csr.IPAddresses
+	cert.EmailAddresses = csr.EmailAddresses
+	cert.URIs = csr.URIs
+
+	setLeafUsages(cert, request.Client, csr.PublicKey)
+
+	err = CheckPolicy(c.policy, cert, csr.PublicKey)
+	if err != nil {
+		return []byte{}, apiRequest, err
+	}
+
+	cert.OCSPServer = c.ocspServers
+	c.setValidity(cert)
+
+	certPEM, err = c.SignPublicKey(cert, csr.PublicKey)
+	if err != nil {
+		return []byte{}, apiRequest, err
+	}
+
+	return certPEM, apiRequest, nil
+
+}
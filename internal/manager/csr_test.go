@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateCertificateFromCSR(t *testing.T) {
+
+	var caRequest client.APICertificateRequest
+
+	caRequest.DN.CN = "ca"
+	caRequest.ExpirationDays = 90
+	caRequest.Key = client.ECDSA256
+
+	caCert, caKey, err := New(caRequest)
+	assert.Nil(t, err)
+
+	ca, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	// URIs without host would be guessed as DNS names, UPNs are otherNames not parsed by x509
+	uri, err := url.Parse("urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6")
+	assert.Nil(t, err)
+
+	sanCert := x509.Certificate{
+		DNSNames:       []string{"external.example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("192.168.1.10")},
+		EmailAddresses: []string{"external@example.com"},
+		URIs:           []*url.URL{uri},
+	}
+	sanExt, err := sanExtension(&sanCert, []string{"external@corp.example.com"})
+	assert.Nil(t, err)
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   "external",
+			Organization: []string{"MyOrganization"},
+		},
+		ExtraExtensions: []pkix.Extension{sanExt},
+	}, key)
+	assert.Nil(t, err)
+
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: FileCSR, Bytes: csrDER})
+
+	certPEM, request, err := ca.CreateCertificateFromCSR(client.APICSRRequest{CSR: csrPEM, ExpirationDays: 30, Client: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "external", request.DN.CN)
+	assert.Equal(t, "MyOrganization", request.DN.O)
+	assert.Equal(t, []string{
+		"dns:external.example.com",
+		"ip:192.168.1.10",
+		"email:external@example.com",
+		"uri:urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6",
+		"upn:external@corp.example.com",
+	}, request.SAN)
+	assert.Equal(t, int64(30), request.ExpirationDays)
+
+	cert, err := CertificateFromPEM(certPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, &key.PublicKey, cert.PublicKey)
+	assert.Equal(t, []string{"external.example.com"}, cert.DNSNames)
+	assert.Equal(t, []string{"external@corp.example.com"}, upnsFromCertificate(t, cert))
+	assert.Contains(t, cert.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+	assert.Nil(t, cert.CheckSignatureFrom(ca.CACertificate()))
+	assert.Equal(t, request.SAN, CertificateToAPI(cert).SAN)
+
+	// the renewal keeps the SANs and its types
+	renewedPEM, _, err := ca.RenewCertificate(request, cert, nil, false)
+	assert.Nil(t, err)
+
+	renewed, err := CertificateFromPEM(renewedPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"external.example.com"}, renewed.DNSNames)
+	assert.Len(t, renewed.URIs, 1)
+	assert.Equal(t, uri.String(), renewed.URIs[0].String())
+	assert.Equal(t, []string{"external@corp.example.com"}, upnsFromCertificate(t, renewed))
+	assert.Equal(t, &key.PublicKey, renewed.PublicKey)
+
+	// must fail, expiration is required
+	_, _, err = ca.CreateCertificateFromCSR(client.APICSRRequest{CSR: csrPEM})
+	assert.Equal(t, rest.ErrBadRequest, err)
+
+	// must fail, not a CSR
+	_, _, err = ca.CreateCertificateFromCSR(client.APICSRRequest{CSR: caCert, ExpirationDays: 30})
+	assert.Equal(t, ErrUnparseableFile, err)
+
+	// must fail, signature does not match
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	assert.Nil(t, err)
+	csrDER[len(csrDER)-1] ^= 0xff
+	_, err = CSRFromPEM(pem.EncodeToMemory(&pem.Block{Type: FileCSR, Bytes: csrDER}))
+	assert.Equal(t, ErrCSRSignature, err)
+	assert.Equal(t, "external", CSRToAPI(csr).DN.CN)
+
+}
@@ -0,0 +1,267 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// kekSaltSize is the size of the random salts used to derive the KEKs from passphrases
+const kekSaltSize = 16
+
+// PEM headers of the encrypted keys
+const (
+	headerKEKID   = "Kek-Id"
+	headerKEKSalt = "Kek-Salt"
+	headerDEK     = "Dek"
+)
+
+// KEK is a key encryption key. Private keys are encrypted at rest using envelope encryption:
+// every key is encrypted with its own random data encryption key (DEK) that is stored along
+// with it, wrapped by the KEK. Both are encrypted using AES-256-GCM.
+type KEK struct {
+	key  []byte
+	id   string
+	salt []byte // salt used to derive the key from a passphrase, if any
+}
+
+// NewKEK returns a KEK from a 32 bytes key
+func NewKEK(key []byte) (*KEK, error) {
+
+	if len(key) != 32 {
+		return nil, ErrKEKInvalid
+	}
+
+	sum := sha256.Sum256(key)
+
+	return &KEK{
+		key: append([]byte{}, key...),
+		id:  hex.EncodeToString(sum[:8]),
+	}, nil
+
+}
+
+// NewKEKSalt returns a new random salt to derive KEKs from passphrases, the same salt must be
+// used to derive the KEK again
+func NewKEKSalt() ([]byte, error) {
+
+	salt := make([]byte, kekSaltSize)
+	_, err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return salt, nil
+
+}
+
+// KEKFromPassphrase returns a KEK derived from the passphrase and salt using scrypt, the salt is
+// written on the header of the keys it encrypts
+func KEKFromPassphrase(passphrase string, salt []byte) (*KEK, error) {
+
+	if passphrase == "" || len(salt) < kekSaltSize {
+		return nil, ErrKEKInvalid
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	kek, err := NewKEK(key)
+	if err != nil {
+		return nil, err
+	}
+	kek.salt = append([]byte{}, salt...)
+
+	return kek, nil
+
+}
+
+// KEKFromBytes returns a KEK from the contents of a key file, the key can be 32 raw bytes or
+// its base64 or hexadecimal representation
+func KEKFromBytes(data []byte) (*KEK, error) {
+
+	if len(data) == 32 {
+		return NewKEK(data)
+	}
+
+	data = bytes.TrimSpace(data)
+
+	if key, err := base64.StdEncoding.DecodeString(string(data)); err == nil && len(key) == 32 {
+		return NewKEK(key)
+	}
+
+	if key, err := hex.DecodeString(string(data)); err == nil && len(key) == 32 {
+		return NewKEK(key)
+	}
+
+	return nil, ErrKEKInvalid
+
+}
+
+// ID returns an identifier of the KEK, it is stored with the encrypted keys to know which KEK was used
+func (k *KEK) ID() string {
+
+	return k.id
+
+}
+
+// Encrypt returns the private key PEM encrypted as a `CFD ENCRYPTED KEY` PEM block
+func (k *KEK) Encrypt(keyPEM []byte) ([]byte, error) {
+
+	var (
+		dek        []byte = make([]byte, 32)
+		wrapped    []byte
+		ciphertext []byte
+		err        error
+	)
+
+	_, err = io.ReadFull(rand.Reader, dek)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	wrapped, err = seal(k.key, dek, []byte(k.id))
+	if err != nil {
+		return []byte{}, err
+	}
+
+	ciphertext, err = seal(dek, keyPEM, []byte(k.id))
+	if err != nil {
+		return []byte{}, err
+	}
+
+	headers := map[string]string{
+		headerKEKID: k.id,
+		headerDEK:   base64.StdEncoding.EncodeToString(wrapped),
+	}
+	if len(k.salt) > 0 {
+		headers[headerKEKSalt] = base64.StdEncoding.EncodeToString(k.salt)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:    FileEncryptedKey,
+		Headers: headers,
+		Bytes:   ciphertext,
+	}), nil
+
+}
+
+// Decrypt returns the private key PEM from a `CFD ENCRYPTED KEY` PEM block
+func (k *KEK) Decrypt(encrypted []byte) ([]byte, error) {
+
+	var (
+		block   *pem.Block
+		wrapped []byte
+		dek     []byte
+		err     error
+	)
+
+	block, _ = pem.Decode(encrypted)
+	if block == nil || block.Type != FileEncryptedKey {
+		return []byte{}, ErrUnparseableFile
+	}
+
+	if block.Headers[headerKEKID] != k.id {
+		return []byte{}, ErrKEKMismatch
+	}
+
+	wrapped, err = base64.StdEncoding.DecodeString(block.Headers[headerDEK])
+	if err != nil {
+		return []byte{}, ErrUnparseableFile
+	}
+
+	dek, err = open(k.key, wrapped, []byte(k.id))
+	if err != nil {
+		return []byte{}, ErrKEKMismatch
+	}
+
+	return open(dek, block.Bytes, []byte(k.id))
+
+}
+
+// IsEncryptedKey returns true if the key is encrypted by a KEK
+func IsEncryptedKey(data []byte) bool {
+
+	block, _ := pem.Decode(data)
+	return block != nil && block.Type == FileEncryptedKey
+
+}
+
+// EncryptedKeyKEK returns the ID of the KEK that encrypted the key and the salt used to derive it
+// from its passphrase (empty if it is not derived from a passphrase). The salt allows to derive
+// the KEK again knowing only the passphrase
+func EncryptedKeyKEK(data []byte) (id string, salt []byte, err error) {
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != FileEncryptedKey {
+		return "", []byte{}, ErrUnparseableFile
+	}
+
+	salt, err = base64.StdEncoding.DecodeString(block.Headers[headerKEKSalt])
+	if err != nil {
+		return "", []byte{}, ErrUnparseableFile
+	}
+
+	return block.Headers[headerKEKID], salt, nil
+
+}
+
+// seal encrypts using AES-GCM, the nonce is prepended to the ciphertext
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+
+}
+
+// open decrypts the data encrypted by seal
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return []byte{}, ErrUnparseableFile
+	}
+
+	plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], additionalData)
+	if err != nil {
+		return []byte{}, ErrUnparseableFile
+	}
+
+	return plaintext, nil
+
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+
+}
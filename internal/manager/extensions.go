@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+)
+
+var (
+	// extKeyUsages maps the extended key usage names allowed on the requests
+	extKeyUsages = map[string]x509.ExtKeyUsage{
+		client.ExtKeyUsageAny:             x509.ExtKeyUsageAny,
+		client.ExtKeyUsageServerAuth:      x509.ExtKeyUsageServerAuth,
+		client.ExtKeyUsageClientAuth:      x509.ExtKeyUsageClientAuth,
+		client.ExtKeyUsageCodeSigning:     x509.ExtKeyUsageCodeSigning,
+		client.ExtKeyUsageEmailProtection: x509.ExtKeyUsageEmailProtection,
+		client.ExtKeyUsageTimeStamping:    x509.ExtKeyUsageTimeStamping,
+		client.ExtKeyUsageOCSPSigning:     x509.ExtKeyUsageOCSPSigning,
+	}
+
+	// managedExtensions are set from the request values and the CA, a custom extension would replace
+	// them (making a leaf a CA or adding names out of the name constraints, for example)
+	managedExtensions = []asn1.ObjectIdentifier{
+		{2, 5, 29, 14},              // subject key identifier
+		{2, 5, 29, 15},              // key usage
+		{2, 5, 29, 17},              // subject alternative name
+		{2, 5, 29, 19},              // basic constraints
+		{2, 5, 29, 30},              // name constraints
+		{2, 5, 29, 31},              // CRL distribution points
+		{2, 5, 29, 32},              // certificate policies
+		{2, 5, 29, 35},              // authority key identifier
+		{2, 5, 29, 37},              // extended key usage
+		{1, 3, 6, 1, 5, 5, 7, 1, 1}, // authority information access
+	}
+)
+
+// setExtensions fills the certificate with the extensions passed on the request
+func setExtensions(cert *x509.Certificate, request client.APICertificateRequest) error {
+
+	for _, usage := range request.ExtKeyUsage {
+		if extKeyUsage, ok := extKeyUsages[usage]; ok {
+			cert.ExtKeyUsage = appendExtKeyUsage(cert.ExtKeyUsage, extKeyUsage)
+			continue
+		}
+
+		oid, err := parseOID(usage)
+		if err != nil {
+			return ErrExtensionInvalid
+		}
+		cert.UnknownExtKeyUsage = append(cert.UnknownExtKeyUsage, oid)
+	}
+
+	for _, policy := range request.Policies {
+		oid, err := parseOID(policy)
+		if err != nil {
+			return ErrExtensionInvalid
+		}
+		cert.PolicyIdentifiers = append(cert.PolicyIdentifiers, oid)
+	}
+
+	cert.CRLDistributionPoints = request.CRLDistributionPoints
+	cert.IssuingCertificateURL = request.IssuingCertificateURL
+
+	for _, extension := range request.Extensions {
+		ext, err := apiToExtension(extension)
+		if err != nil {
+			return err
+		}
+		cert.ExtraExtensions = append(cert.ExtraExtensions, ext)
+	}
+
+	return nil
+
+}
+
+// apiToExtension returns the pkix.Extension for the custom extension, only one of value or raw
+// can be used. Extensions set by the manager cannot be used
+func apiToExtension(extension client.APIExtension) (ext pkix.Extension, err error) {
+
+	ext.Id, err = parseOID(extension.OID)
+	if err != nil {
+		return ext, ErrExtensionInvalid
+	}
+
+	for _, managed := range managedExtensions {
+		if ext.Id.Equal(managed) {
+			return ext, ErrExtensionInvalid
+		}
+	}
+
+	ext.Critical = extension.Critical
+
+	switch {
+	case extension.Value != "" && extension.Raw == "":
+		ext.Value, err = asn1.MarshalWithParams(extension.Value, "utf8")
+	case extension.Value == "" && extension.Raw != "":
+		ext.Value, err = base64.StdEncoding.DecodeString(extension.Raw)
+	default:
+		return ext, ErrExtensionInvalid
+	}
+
+	if err != nil {
+		return ext, ErrExtensionInvalid
+	}
+
+	return
+
+}
+
+// appendExtKeyUsage appends the extended key usage if not already present
+func appendExtKeyUsage(usages []x509.ExtKeyUsage, usage x509.ExtKeyUsage) []x509.ExtKeyUsage {
+
+	for _, u := range usages {
+		if u == usage {
+			return usages
+		}
+	}
+
+	return append(usages, usage)
+
+}
+
+// parseOID parses an OID in dot notation (ex: 1.2.3.4)
+func parseOID(value string) (oid asn1.ObjectIdentifier, err error) {
+
+	parts := strings.Split(value, ".")
+	if len(parts) < 2 {
+		return nil, ErrExtensionInvalid
+	}
+
+	for _, part := range parts {
+		var arc int
+		arc, err = strconv.Atoi(part)
+		if err != nil || arc < 0 {
+			return nil, ErrExtensionInvalid
+		}
+		oid = append(oid, arc)
+	}
+
+	return
+
+}
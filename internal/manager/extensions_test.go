@@ -0,0 +1,141 @@
+package manager
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"testing"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtensions(t *testing.T) {
+
+	var request client.APICertificateRequest
+
+	request.DN.CN = "extensions ca"
+	request.ExpirationDays = 90
+	request.Key = client.ECDSA256
+	request.ExtKeyUsage = []string{client.ExtKeyUsageCodeSigning}
+
+	caCert, caKey, err := New(request)
+	assert.Nil(t, err)
+
+	ca, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning, x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}, ca.CACertificate().ExtKeyUsage)
+
+	request = client.APICertificateRequest{
+		DN:             client.APIDN{CN: "signer"},
+		SAN:            []string{"signer.example.com"},
+		ExpirationDays: 90,
+		Key:            client.ECDSA256,
+		ExtKeyUsage: []string{
+			client.ExtKeyUsageCodeSigning,
+			client.ExtKeyUsageTimeStamping,
+			client.ExtKeyUsageServerAuth,
+			"1.3.6.1.4.1.311.10.3.12",
+		},
+		Policies:              []string{"2.23.140.1.2.1"},
+		CRLDistributionPoints: []string{"http://crl.example.com/ca.crl"},
+		IssuingCertificateURL: []string{"http://ca.example.com/ca.crt"},
+		Extensions: []client.APIExtension{
+			{OID: "1.2.3.4", Value: "custom value"},
+			{OID: "1.2.3.5", Raw: "BQA=", Critical: true}, // NULL
+		},
+	}
+
+	certPEM, _, err := ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	cert, err := CertificateFromPEM(certPEM)
+	assert.Nil(t, err)
+
+	// server auth is not duplicated
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning, x509.ExtKeyUsageTimeStamping, x509.ExtKeyUsageServerAuth}, cert.ExtKeyUsage)
+	assert.Equal(t, []asn1.ObjectIdentifier{{1, 3, 6, 1, 4, 1, 311, 10, 3, 12}}, cert.UnknownExtKeyUsage)
+	assert.Equal(t, []asn1.ObjectIdentifier{{2, 23, 140, 1, 2, 1}}, cert.PolicyIdentifiers)
+	assert.Equal(t, request.CRLDistributionPoints, cert.CRLDistributionPoints)
+	assert.Equal(t, request.IssuingCertificateURL, cert.IssuingCertificateURL)
+
+	var found int
+	for _, ext := range cert.Extensions {
+		switch ext.Id.String() {
+		case "1.2.3.4":
+			var value string
+			_, err = asn1.Unmarshal(ext.Value, &value)
+			assert.Nil(t, err)
+			assert.Equal(t, "custom value", value)
+			assert.False(t, ext.Critical)
+			found++
+		case "1.2.3.5":
+			assert.Equal(t, asn1.NullBytes, ext.Value)
+			assert.True(t, ext.Critical)
+			found++
+		}
+	}
+	assert.Equal(t, 2, found)
+
+	// invalid requests
+	for _, invalid := range []client.APICertificateRequest{
+		{ExtKeyUsage: []string{"unknownUsage"}},
+		{Policies: []string{"1"}},
+		{Policies: []string{"1.a.3"}},
+		{Extensions: []client.APIExtension{{OID: "1.2.3.4"}}},
+		{Extensions: []client.APIExtension{{OID: "1.2.3.4", Value: "value", Raw: "BQA="}}},
+		{Extensions: []client.APIExtension{{OID: "1.2.3.4", Raw: "not base64"}}},
+		{Extensions: []client.APIExtension{{OID: "", Value: "value"}}},
+		{Extensions: []client.APIExtension{{OID: "1.3.6.1.5.5.7.1.1", Raw: "MAA="}}},
+		{Extensions: []client.APIExtension{{OID: "2.5.29.37", Raw: "MAoGCCsGAQUFBwMC"}}}, // clientAuth
+	} {
+		invalid.DN.CN = "invalid"
+		invalid.ExpirationDays = 90
+		invalid.Key = client.ECDSA256
+
+		_, _, err = ca.CreateCertificateFromAPI(invalid)
+		assert.Equal(t, ErrExtensionInvalid, err)
+	}
+
+	// must fail, extensions set by the manager cannot be replaced (a leaf would become a CA with
+	// names out of its name constraints)
+	constrainedRequest := client.APICertificateRequest{
+		DN:              client.APIDN{CN: "constrained ca"},
+		ExpirationDays:  90,
+		Key:             client.ECDSA256,
+		PathLength:      1,
+		NameConstraints: client.APINameConstraints{PermittedDNS: []string{"team.com"}},
+	}
+
+	caCert, caKey, err = New(constrainedRequest)
+	assert.Nil(t, err)
+
+	constrained, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+
+	basicConstraints, err := asn1.Marshal(struct{ IsCA bool }{true})
+	assert.Nil(t, err)
+
+	san, err := asn1.Marshal([]asn1.RawValue{{Tag: 2, Class: asn1.ClassContextSpecific, Bytes: []byte("evil.com")}})
+	assert.Nil(t, err)
+
+	for _, extensions := range [][]client.APIExtension{
+		{{OID: "2.5.29.19", Raw: base64.StdEncoding.EncodeToString(basicConstraints), Critical: true}},
+		{{OID: "2.5.29.17", Raw: base64.StdEncoding.EncodeToString(san)}},
+		{
+			{OID: "2.5.29.19", Raw: base64.StdEncoding.EncodeToString(basicConstraints), Critical: true},
+			{OID: "2.5.29.17", Raw: base64.StdEncoding.EncodeToString(san)},
+		},
+	} {
+		leafPEM, _, err := constrained.CreateCertificateFromAPI(client.APICertificateRequest{
+			DN:             client.APIDN{CN: "www.team.com"},
+			SAN:            []string{"www.team.com"},
+			ExpirationDays: 30,
+			Key:            client.ECDSA256,
+			Extensions:     extensions,
+		})
+		assert.Equal(t, ErrExtensionInvalid, err)
+		assert.Len(t, leafPEM, 0)
+	}
+
+}
@@ -0,0 +1,244 @@
+package manager
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// ImportCA parses an existing CA certificate and its key, returning the CA certificate, its key (as
+// PKCS#8) and the chain of its issuers as PEM, as stored for the CAs created by cfd.
+//
+// The certificate can be PEM (the CA followed by its chain, if any) or DER. Keys can be PKCS#1,
+// SEC1 or PKCS#8, as PEM or DER. If no key is passed, the certificate must be a PKCS#12 bundle
+// with the key (password is only used for PKCS#12). Keys held by a signer plugin are passed as a
+// key reference (see KeyReference), which is returned as key.
+func ImportCA(certData, keyData []byte, password string) (certPEM, keyPEM, chainPEM []byte, err error) {
+
+	var (
+		cert  *x509.Certificate
+		chain []*x509.Certificate
+		key   interface{}
+	)
+
+	switch {
+	case IsKeyReference(keyData):
+		chain, err = parseCertificates(certData)
+		if err != nil {
+			return
+		}
+		cert, chain = chain[0], chain[1:]
+
+		key, err = signerFromReference(keyData)
+		if err != nil {
+			return
+		}
+	case len(keyData) == 0:
+		key, cert, chain, err = pkcs12.DecodeChain(certData, password)
+		if err != nil {
+			return nil, nil, nil, ErrUnparseableFile
+		}
+	default:
+		chain, err = parseCertificates(certData)
+		if err != nil {
+			return
+		}
+		cert, chain = chain[0], chain[1:]
+
+		key, err = parsePrivateKey(keyData)
+		if err != nil {
+			return
+		}
+	}
+
+	if !cert.BasicConstraintsValid || !cert.IsCA {
+		return nil, nil, nil, ErrNotCA
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, nil, ErrKeyInvalid
+	}
+
+	if !publicKeyEqual(signer.Public(), cert.PublicKey) {
+		return nil, nil, nil, ErrKeyMismatch
+	}
+
+	if IsKeyReference(keyData) {
+		keyPEM = keyData
+	} else {
+		var keyBytes []byte
+		keyBytes, err = x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: FilePrivateKey, Bytes: keyBytes})
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: FileCertificate, Bytes: cert.Raw})
+	for _, issuer := range chain {
+		chainPEM = append(chainPEM, pem.EncodeToMemory(&pem.Block{Type: FileCertificate, Bytes: issuer.Raw})...)
+	}
+
+	return
+
+}
+
+// parseCertificates returns the certificates from PEM or DER data, at least one is returned
+func parseCertificates(data []byte) (certs []*x509.Certificate, err error) {
+
+	var block *pem.Block
+
+	for rest := bytes.TrimSpace(data); ; {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != FileCertificate {
+			continue
+		}
+
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, ErrUnparseableFile
+		}
+		certs = append(certs, cert)
+	}
+
+	// DER
+	if len(certs) == 0 {
+		certs, err = x509.ParseCertificates(data)
+		if err != nil || len(certs) == 0 {
+			return nil, ErrUnparseableFile
+		}
+	}
+
+	return
+
+}
+
+// parsePrivateKey returns the first private key found on PEM or DER data (PKCS#1, SEC1 or PKCS#8),
+// other PEM blocks (as EC PARAMETERS) are ignored
+func parsePrivateKey(data []byte) (key interface{}, err error) {
+
+	var block *pem.Block
+
+	for rest := data; ; {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case FilePrivateKey:
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, ErrUnparseableFile
+		}
+		return
+	}
+
+	// DER
+	if key, err = x509.ParsePKCS8PrivateKey(data); err == nil {
+		return
+	}
+	if key, err = x509.ParsePKCS1PrivateKey(data); err == nil {
+		return
+	}
+	if key, err = x509.ParseECPrivateKey(data); err == nil {
+		return
+	}
+
+	return nil, ErrUnparseableFile
+
+}
+
+// CertificateToAPI returns the API request that represents an existing certificate
+func CertificateToAPI(cert *x509.Certificate) (request client.APICertificateRequest) {
+
+	request.DN = subjectToAPI(cert.Subject)
+	request.SAN = apiSANs(cert.DNSNames, cert.IPAddresses, cert.EmailAddresses, cert.URIs, cert.Extensions)
+
+	request.Key = keyType(cert.PublicKey)
+	request.ExpirationDays = int64(cert.NotAfter.Sub(cert.NotBefore) / (24 * time.Hour))
+	if request.ExpirationDays == 0 {
+		request.Validity = cert.NotAfter.Sub(cert.NotBefore).String()
+	}
+
+	switch {
+	case cert.MaxPathLen > 0:
+		request.PathLength = cert.MaxPathLen
+	case cert.MaxPathLen == 0 && cert.MaxPathLenZero:
+		request.PathLength = 0
+	default:
+		request.PathLength = -1
+	}
+
+	return
+
+}
+
+func subjectToAPI(subject pkix.Name) (dn client.APIDN) {
+
+	dn.CN = subject.CommonName
+	dn.C = first(subject.Country)
+	dn.L = first(subject.Locality)
+	dn.O = first(subject.Organization)
+	dn.OU = first(subject.OrganizationalUnit)
+	dn.P = first(subject.Province)
+	dn.PC = first(subject.PostalCode)
+	dn.ST = first(subject.StreetAddress)
+
+	return
+
+}
+
+// keyType returns the key short code of the public key, if it is a supported one
+func keyType(publicKey crypto.PublicKey) string {
+
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		keyType := fmt.Sprintf("rsa:%d", k.N.BitLen())
+		for _, supported := range []string{client.RSA2048, client.RSA3072, client.RSA4096} {
+			if keyType == supported {
+				return keyType
+			}
+		}
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P224():
+			return client.ECDSA224
+		case elliptic.P256():
+			return client.ECDSA256
+		case elliptic.P384():
+			return client.ECDSA384
+		case elliptic.P521():
+			return client.ECDSA521
+		}
+	case ed25519.PublicKey:
+		return client.ED25519
+	}
+
+	return ""
+
+}
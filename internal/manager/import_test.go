@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// externalCA creates a CA certificate as an external tool would do
+func externalCA(t *testing.T, key interface{}, isCA bool) *x509.Certificate {
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "external ca", Organization: []string{"external"}},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		MaxPathLen:            -1,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, publicKey(key), key)
+	assert.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	return cert
+
+}
+
+func publicKey(key interface{}) interface{} {
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey
+	}
+
+	return nil
+
+}
+
+func TestImportCA(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	rsaCA := externalCA(t, rsaKey, true)
+	ecCA := externalCA(t, ecKey, true)
+
+	rsaCertPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rsaCA.Raw})
+	ecCertPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ecCA.Raw})
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	sec1Bytes, err := x509.MarshalECPrivateKey(ecKey)
+	assert.Nil(t, err)
+	sec1 := append(pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{6, 8, 42, 134, 72, 206, 61, 3, 1, 7}}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1Bytes})...)
+
+	pkcs8Bytes, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.Nil(t, err)
+
+	pfx, err := pkcs12.Encode(rand.Reader, rsaKey, rsaCA, nil, "changeit")
+	assert.Nil(t, err)
+
+	for name, tc := range map[string]struct {
+		cert, key []byte
+		password  string
+		ca        *x509.Certificate
+	}{
+		"pkcs1":      {cert: rsaCertPEM, key: pkcs1, ca: rsaCA},
+		"sec1":       {cert: ecCertPEM, key: sec1, ca: ecCA},
+		"pkcs8":      {cert: ecCertPEM, key: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Bytes}), ca: ecCA},
+		"der":        {cert: ecCA.Raw, key: pkcs8Bytes, ca: ecCA},
+		"pkcs12":     {cert: pfx, password: "changeit", ca: rsaCA},
+		"with chain": {cert: append(ecCertPEM, rsaCertPEM...), key: sec1, ca: ecCA},
+	} {
+
+		certPEM, keyPEM, chainPEM, err := ImportCA(tc.cert, tc.key, tc.password)
+		assert.Nil(t, err, name)
+
+		ca, err := FromBytes(certPEM, keyPEM)
+		assert.Nil(t, err, name)
+		assert.Equal(t, tc.ca.Raw, ca.CACertificate().Raw, name)
+
+		if name == "with chain" {
+			assert.Equal(t, rsaCertPEM, chainPEM)
+		} else {
+			assert.Len(t, chainPEM, 0, name)
+		}
+
+		// issuing works as with the CAs created by cfd
+		leafPEM, _, err := ca.CreateCertificateFromAPI(client.APICertificateRequest{DN: client.APIDN{CN: "leaf"}, SAN: []string{"leaf.example.com"}, ExpirationDays: 30, Key: client.ECDSA256})
+		assert.Nil(t, err, name)
+
+		leaf, err := CertificateFromPEM(leafPEM)
+		assert.Nil(t, err, name)
+		assert.Nil(t, leaf.CheckSignatureFrom(tc.ca), name)
+
+		_, err = ca.CRL([]client.Revocation{}, time.Hour)
+		assert.Nil(t, err, name)
+
+	}
+
+	request := CertificateToAPI(ecCA)
+	assert.Equal(t, "external ca", request.DN.CN)
+	assert.Equal(t, "external", request.DN.O)
+	assert.Equal(t, client.ECDSA256, request.Key)
+	assert.Equal(t, int64(365), request.ExpirationDays)
+	assert.Equal(t, -1, request.PathLength)
+
+	// key does not match
+	_, _, _, err = ImportCA(rsaCertPEM, sec1, "")
+	assert.Equal(t, ErrKeyMismatch, err)
+
+	// not a CA
+	leafCert := externalCA(t, ecKey, false)
+	_, _, _, err = ImportCA(leafCert.Raw, sec1, "")
+	assert.Equal(t, ErrNotCA, err)
+
+	// unparseable
+	_, _, _, err = ImportCA([]byte("not a certificate"), sec1, "")
+	assert.Equal(t, ErrUnparseableFile, err)
+
+	_, _, _, err = ImportCA(ecCertPEM, []byte("not a key"), "")
+	assert.Equal(t, ErrUnparseableFile, err)
+
+	_, _, _, err = ImportCA(pfx, nil, "wrong password")
+	assert.Equal(t, ErrUnparseableFile, err)
+
+}
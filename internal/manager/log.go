@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/certsfor/pkg/merkle"
+)
+
+// tree head signature input values (RFC 6962 3.5)
+const (
+	treeHeadVersion       byte = 0 // v1
+	treeHeadSignatureType byte = 1 // tree_hash
+)
+
+// SetLog sets the function that appends the certificates signed by the CA to its issuance log,
+// certificates are not returned if they cannot be appended
+func (c *CA) SetLog(log func(certificate []byte) error) {
+	c.log = log
+}
+
+// LogLeafHash returns the hash of the log entry leaf on the Merkle tree
+func LogLeafHash(entry client.APILogEntry) ([]byte, error) {
+
+	block, _ := pem.Decode(entry.Certificate)
+	if block == nil {
+		return nil, ErrUnparseableFile
+	}
+
+	data := make([]byte, 8, 8+len(block.Bytes))
+	binary.BigEndian.PutUint64(data, uint64(entry.Timestamp))
+
+	return merkle.LeafHash(append(data, block.Bytes...)), nil
+
+}
+
+// treeHeadInput returns the data signed for the tree head
+func treeHeadInput(head client.APISignedTreeHead) []byte {
+
+	data := make([]byte, 18, 18+len(head.RootHash))
+	data[0] = treeHeadVersion
+	data[1] = treeHeadSignatureType
+	binary.BigEndian.PutUint64(data[2:], uint64(head.Timestamp))
+	binary.BigEndian.PutUint64(data[10:], head.TreeSize)
+
+	return append(data, head.RootHash...)
+
+}
+
+// SignTreeHead signs the log tree head with the CA key
+func (c *CA) SignTreeHead(head *client.APISignedTreeHead) (err error) {
+
+	var (
+		input []byte = treeHeadInput(*head)
+		hash  [32]byte
+	)
+
+	// ed25519 signs the message itself
+	if _, ok := c.caKey.Public().(ed25519.PublicKey); ok {
+		head.Signature, err = c.caKey.Sign(rand.Reader, input, crypto.Hash(0))
+		return
+	}
+
+	hash = sha256.Sum256(input)
+	head.Signature, err = c.caKey.Sign(rand.Reader, hash[:], crypto.SHA256)
+
+	return
+
+}
+
+// VerifyTreeHead returns ErrTreeHeadSignature if the tree head was not signed by the CA certificate key
+func VerifyTreeHead(caCertificate *x509.Certificate, head client.APISignedTreeHead) error {
+
+	var (
+		input []byte   = treeHeadInput(head)
+		hash  [32]byte = sha256.Sum256(input)
+		valid bool
+	)
+
+	switch publicKey := caCertificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash[:], head.Signature) == nil
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(publicKey, hash[:], head.Signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(publicKey, input, head.Signature)
+	}
+
+	if !valid {
+		return ErrTreeHeadSignature
+	}
+
+	return nil
+
+}
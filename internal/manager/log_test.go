@@ -0,0 +1,79 @@
+package manager
+
+import (
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/certsfor/pkg/merkle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+
+	for _, keyType := range []string{client.RSA2048, client.ECDSA256, client.ED25519} {
+
+		var (
+			caRequest client.APICertificateRequest
+			logged    [][]byte
+			logErr    error
+		)
+
+		caRequest.DN.CN = "ca"
+		caRequest.ExpirationDays = 10
+		caRequest.Key = keyType
+
+		caCert, caKey, err := New(caRequest)
+		assert.Nil(t, err)
+
+		ca, err := FromBytes(caCert, caKey)
+		assert.Nil(t, err)
+
+		ca.SetLog(func(certificate []byte) error {
+			logged = append(logged, certificate)
+			return logErr
+		})
+
+		certPEM, _, err := ca.CreateCertificateFromAPI(client.APICertificateRequest{
+			DN:             client.APIDN{CN: "logged"},
+			Key:            client.ECDSA256,
+			ExpirationDays: 1,
+		})
+		assert.Nil(t, err, keyType)
+
+		block, _ := pem.Decode(certPEM)
+		assert.Equal(t, [][]byte{block.Bytes}, logged)
+
+		entry := client.APILogEntry{Timestamp: time.Now().UnixNano() / int64(time.Millisecond), Certificate: certPEM}
+		leaf, err := LogLeafHash(entry)
+		assert.Nil(t, err)
+
+		head := client.APISignedTreeHead{
+			TreeSize:  1,
+			Timestamp: entry.Timestamp,
+			RootHash:  merkle.RootHash([][]byte{leaf}),
+		}
+		assert.Nil(t, ca.SignTreeHead(&head))
+		assert.Nil(t, VerifyTreeHead(ca.CACertificate(), head), keyType)
+
+		// must fail, tree head modified
+		head.TreeSize = 2
+		assert.Equal(t, ErrTreeHeadSignature, VerifyTreeHead(ca.CACertificate(), head))
+
+		// must fail, certificates are not returned if they cannot be logged
+		logErr = errors.New("log unavailable")
+		_, _, err = ca.CreateCertificateFromAPI(client.APICertificateRequest{
+			DN:             client.APIDN{CN: "not logged"},
+			Key:            client.ECDSA256,
+			ExpirationDays: 1,
+		})
+		assert.Equal(t, logErr, err)
+
+	}
+
+	_, err := LogLeafHash(client.APILogEntry{Certificate: []byte("not a certificate")})
+	assert.Equal(t, ErrUnparseableFile, err)
+
+}
@@ -0,0 +1,117 @@
+package manager
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+var (
+	// id-pkix-ocsp-nocheck (RFC 6960, 4.2.2.2.1)
+	oidExtensionOCSPNoCheck = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+)
+
+// OCSPIssuedBy returns true if the OCSP request asks for a certificate issued by the CA
+func (c *CA) OCSPIssuedBy(request *ocsp.Request) bool {
+
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+
+	if !request.HashAlgorithm.Available() {
+		return false
+	}
+
+	if _, err := asn1.Unmarshal(c.ca.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return false
+	}
+
+	h := request.HashAlgorithm.New()
+	h.Write(publicKeyInfo.PublicKey.RightAlign())
+	if !bytes.Equal(h.Sum(nil), request.IssuerKeyHash) {
+		return false
+	}
+
+	h.Reset()
+	h.Write(c.ca.RawSubject)
+
+	return bytes.Equal(h.Sum(nil), request.IssuerNameHash)
+
+}
+
+// OCSPRequiresDelegation returns true if the CA key cannot sign OCSP responses by itself
+// (Ed25519) so a delegated OCSP signing certificate is needed
+func (c *CA) OCSPRequiresDelegation() bool {
+
+	_, ok := c.ca.PublicKey.(ed25519.PublicKey)
+	return ok
+
+}
+
+// NewOCSPResponder creates a delegated OCSP signing certificate/key pair valid for the days passed
+func (c *CA) NewOCSPResponder(days int64) ([]byte, []byte, error) {
+
+	var (
+		cert *x509.Certificate
+		key  crypto.PrivateKey
+		err  error
+	)
+
+	key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	cert = &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: c.ca.Subject.CommonName + " OCSP Responder",
+		},
+		NotBefore:   time.Now(),
+		NotAfter:    time.Now().Add(time.Duration(days*24) * time.Hour),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+		// the responder does not need to be checked
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidExtensionOCSPNoCheck, Value: asn1.NullBytes},
+		},
+	}
+
+	if cert.NotAfter.After(c.ca.NotAfter) {
+		cert.NotAfter = c.ca.NotAfter
+	}
+
+	return c.CreateCertificate(cert, key)
+
+}
+
+// OCSPResponse returns the DER encoded OCSP response for the request, signed by the responder
+// certificate and key if passed (delegated) or by the CA
+//
+// template must have the status information filled
+func (c *CA) OCSPResponse(request *ocsp.Request, template ocsp.Response, responderCert *x509.Certificate, responderKey crypto.PrivateKey) ([]byte, error) {
+
+	template.SerialNumber = request.SerialNumber
+	template.IssuerHash = request.HashAlgorithm
+
+	if responderCert == nil {
+		if c.OCSPRequiresDelegation() {
+			return []byte{}, ErrKeyInvalid
+		}
+		return ocsp.CreateResponse(c.ca, c.ca, template, c.caKey)
+	}
+
+	template.Certificate = responderCert
+
+	return ocsp.CreateResponse(c.ca, responderCert, template, responderKey.(crypto.Signer))
+
+}
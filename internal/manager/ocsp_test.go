@@ -0,0 +1,95 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestOCSP(t *testing.T) {
+
+	for _, keyType := range []string{client.ECDSA256, client.RSA2048, client.ED25519} {
+
+		var request client.APICertificateRequest
+
+		request.DN.CN = "ocsp ca"
+		request.ExpirationDays = 90
+		request.Key = keyType
+
+		caCert, caKey, err := New(request)
+		assert.Nil(t, err)
+
+		ca, err := FromBytes(caCert, caKey)
+		assert.Nil(t, err)
+		ca.SetOCSPServer("http://ocsp.example.com/v1/ca/id/ocsp")
+
+		request.DN.CN = "leaf"
+		request.Key = client.ECDSA256
+
+		leafCert, _, err := ca.CreateCertificateFromAPI(request)
+		assert.Nil(t, err)
+
+		leaf, err := CertificateFromPEM(leafCert)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"http://ocsp.example.com/v1/ca/id/ocsp"}, leaf.OCSPServer)
+
+		reqBytes, err := ocsp.CreateRequest(leaf, ca.CACertificate(), nil)
+		assert.Nil(t, err)
+		req, err := ocsp.ParseRequest(reqBytes)
+		assert.Nil(t, err)
+		assert.True(t, ca.OCSPIssuedBy(req))
+
+		template := ocsp.Response{
+			Status:     ocsp.Revoked,
+			RevokedAt:  time.Now().Add(-time.Hour).UTC().Truncate(time.Second),
+			ThisUpdate: time.Now(),
+			NextUpdate: time.Now().Add(time.Hour),
+		}
+		template.RevocationReason = ocsp.KeyCompromise
+
+		// signed by the CA
+		if ca.OCSPRequiresDelegation() {
+			assert.Equal(t, client.ED25519, keyType)
+			_, err = ca.OCSPResponse(req, template, nil, nil)
+			assert.Equal(t, ErrKeyInvalid, err)
+		} else {
+			respBytes, err := ca.OCSPResponse(req, template, nil, nil)
+			assert.Nil(t, err)
+			resp, err := ocsp.ParseResponseForCert(respBytes, leaf, ca.CACertificate())
+			assert.Nil(t, err)
+			assert.Equal(t, ocsp.Revoked, resp.Status)
+			assert.Equal(t, ocsp.KeyCompromise, resp.RevocationReason)
+			assert.Equal(t, leaf.SerialNumber, resp.SerialNumber)
+		}
+
+		// signed by a delegated responder
+		responderCert, responderKey, err := ca.NewOCSPResponder(30)
+		assert.Nil(t, err)
+		responder, err := CertificateFromPEM(responderCert)
+		assert.Nil(t, err)
+		assert.Len(t, responder.OCSPServer, 0)
+		key, err := PrivateKeyFromPEM(responderKey)
+		assert.Nil(t, err)
+
+		template.Status = ocsp.Good
+		respBytes, err := ca.OCSPResponse(req, template, responder, key)
+		assert.Nil(t, err)
+		resp, err := ocsp.ParseResponseForCert(respBytes, leaf, ca.CACertificate())
+		assert.Nil(t, err)
+		assert.Equal(t, ocsp.Good, resp.Status)
+		assert.Equal(t, responder.Raw, resp.Certificate.Raw)
+
+		// request for other CA
+		request.DN.CN = "other ca"
+		otherCert, otherKey, err := New(request)
+		assert.Nil(t, err)
+		other, err := FromBytes(otherCert, otherKey)
+		assert.Nil(t, err)
+		assert.False(t, other.OCSPIssuedBy(req))
+
+	}
+
+}
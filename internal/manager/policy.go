@@ -0,0 +1,266 @@
+package manager
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+)
+
+// policyRegexPrefix marks the SAN patterns that are regular expressions instead of globs
+const policyRegexPrefix = "regex:"
+
+var (
+	oidExtensionExtKeyUsage  = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidExtKeyUsageAny        = asn1.ObjectIdentifier{2, 5, 29, 37, 0}
+	oidExtKeyUsageClientAuth = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2}
+)
+
+// SetPolicy sets the issuance policy checked before signing certificates (renewals included)
+func (c *CA) SetPolicy(policy client.APIPolicy) {
+	c.policy = policy
+}
+
+// ValidatePolicy returns ErrPolicyInvalid if any of the policy values is not valid
+func ValidatePolicy(policy client.APIPolicy) error {
+
+	patterns := append(append([]string{}, policy.AllowedSANs...), policy.DeniedSANs...)
+	for _, pattern := range append(patterns, policy.SSHAllowedPrincipals...) {
+		if _, err := matchPattern(pattern, ""); err != nil {
+			return ErrPolicyInvalid
+		}
+	}
+
+	for _, key := range policy.AllowedKeys {
+		if !validKey(key) {
+			return ErrPolicyInvalid
+		}
+	}
+
+	for _, field := range policy.RequiredDN {
+		if _, ok := dnField(client.APIDN{}, field); !ok {
+			return ErrPolicyInvalid
+		}
+	}
+
+	if policy.MaxExpirationDays < 0 {
+		return ErrPolicyInvalid
+	}
+
+	if policy.SSHMaxValidity != "" {
+		if validity, err := time.ParseDuration(policy.SSHMaxValidity); err != nil || validity <= 0 {
+			return ErrPolicyInvalid
+		}
+	}
+
+	return nil
+
+}
+
+// CheckPolicy returns a *client.PolicyViolation with the first rule of the policy that the
+// certificate does not meet, nil if it can be signed. The certificate is checked as it will be
+// signed, so names and usages set by OID or custom extensions are checked too. The client usage
+// is not checked on CA certificates, subordinate CAs inherit the policy and it applies to their
+// certificates
+func CheckPolicy(policy client.APIPolicy, cert *x509.Certificate, publicKey crypto.PublicKey) error {
+
+	names, err := policyNames(cert)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		for _, pattern := range policy.DeniedSANs {
+			if match, _ := matchPattern(pattern, name); match {
+				return violation(client.PolicyRuleDeniedSANs, name, fmt.Sprintf("%s is denied by %s", name, pattern))
+			}
+		}
+
+		if len(policy.AllowedSANs) > 0 && !matchAny(policy.AllowedSANs, name) {
+			return violation(client.PolicyRuleAllowedSANs, name, fmt.Sprintf("%s does not match any of the allowed names", name))
+		}
+	}
+
+	key := keyType(publicKey)
+	if len(policy.AllowedKeys) > 0 && !contains(policy.AllowedKeys, key) {
+		return violation(client.PolicyRuleAllowedKeys, key, fmt.Sprintf("key must be one of: %s", strings.Join(policy.AllowedKeys, ", ")))
+	}
+
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	if policy.MaxExpirationDays > 0 && lifetime > time.Duration(policy.MaxExpirationDays*24)*time.Hour {
+		return violation(client.PolicyRuleMaxExp, lifetime.String(), fmt.Sprintf("certificates cannot be valid for more than %d days", policy.MaxExpirationDays))
+	}
+
+	if policy.DenyClient && !cert.IsCA && clientUsage(cert) {
+		return violation(client.PolicyRuleDenyClient, "", "client certificates are not allowed")
+	}
+
+	dn := subjectToAPI(cert.Subject)
+	for _, field := range policy.RequiredDN {
+		if value, _ := dnField(dn, field); value == "" {
+			return violation(client.PolicyRuleRequiredDN, field, fmt.Sprintf("distinguished name field '%s' is required", field))
+		}
+	}
+
+	return nil
+
+}
+
+// policyNames returns the SANs of the certificate, when the SAN extension is built by hand (UPNs)
+// it replaces the x509 fields so the names are read from it
+func policyNames(cert *x509.Certificate) (names []string, err error) {
+
+	for _, ext := range cert.ExtraExtensions {
+		if !ext.Id.Equal(oidExtensionSubjectAltName) {
+			continue
+		}
+
+		var generalNames []asn1.RawValue
+		_, err = asn1.Unmarshal(ext.Value, &generalNames)
+		if err != nil {
+			return nil, ErrSANInvalid
+		}
+
+		for _, name := range generalNames {
+			switch name.Tag {
+			case nameTypeOther:
+				var upn string
+				_, upn, err = otherName(name)
+				if err != nil {
+					return nil, ErrSANInvalid
+				}
+				names = append(names, upn)
+			case nameTypeIP:
+				names = append(names, net.IP(name.Bytes).String())
+			default:
+				names = append(names, string(name.Bytes))
+			}
+		}
+
+		return names, nil
+	}
+
+	names = append(names, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	names = append(names, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		names = append(names, u.String())
+	}
+
+	return names, nil
+
+}
+
+// clientUsage returns true if the certificate can be used for client authentication, by name or OID
+func clientUsage(cert *x509.Certificate) bool {
+
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageClientAuth || usage == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+
+	usages := cert.UnknownExtKeyUsage
+	for _, ext := range cert.ExtraExtensions {
+		if !ext.Id.Equal(oidExtensionExtKeyUsage) {
+			continue
+		}
+
+		var oids []asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(ext.Value, &oids); err != nil {
+			return true
+		}
+		usages = append(usages, oids...)
+	}
+
+	for _, oid := range usages {
+		if oid.Equal(oidExtKeyUsageClientAuth) || oid.Equal(oidExtKeyUsageAny) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+func violation(rule, value, reason string) *client.PolicyViolation {
+
+	return &client.PolicyViolation{
+		Rule:   rule,
+		Value:  value,
+		Reason: reason,
+	}
+
+}
+
+// matchPattern returns true if the name matches the glob or regular expression (regex: prefix)
+func matchPattern(pattern, name string) (bool, error) {
+
+	if strings.HasPrefix(pattern, policyRegexPrefix) {
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, policyRegexPrefix))
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(name), nil
+	}
+
+	return path.Match(strings.ToLower(pattern), strings.ToLower(name))
+
+}
+
+func matchAny(patterns []string, name string) bool {
+
+	for _, pattern := range patterns {
+		if match, _ := matchPattern(pattern, name); match {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+func contains(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// dnField returns the value of the DN field by its short name (as on the API), false if unknown
+func dnField(dn client.APIDN, field string) (string, bool) {
+
+	switch strings.ToLower(field) {
+	case "c":
+		return dn.C, true
+	case "l":
+		return dn.L, true
+	case "o":
+		return dn.O, true
+	case "ou":
+		return dn.OU, true
+	case "p":
+		return dn.P, true
+	case "pc":
+		return dn.PC, true
+	case "st":
+		return dn.ST, true
+	}
+
+	return "", false
+
+}
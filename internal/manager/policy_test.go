@@ -0,0 +1,167 @@
+package manager
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"net/http"
+	"testing"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+
+	policy := client.APIPolicy{
+		AllowedSANs:       []string{"*.example.com", `regex:^10\.0\.[0-9]+\.[0-9]+$`, "dev@example.com"},
+		DeniedSANs:        []string{"*.prod.example.com"},
+		MaxExpirationDays: 90,
+		AllowedKeys:       []string{client.ECDSA256, client.RSA2048},
+		DenyClient:        true,
+		RequiredDN:        []string{"o"},
+	}
+	assert.Nil(t, ValidatePolicy(policy))
+
+	request := client.APICertificateRequest{
+		DN:             client.APIDN{CN: "service", O: "MyOrganization"},
+		SAN:            []string{"www.example.com", "ip:10.0.1.2", "dev@example.com", "DNS:API.EXAMPLE.COM"},
+		Key:            client.ECDSA256,
+		ExpirationDays: 90,
+	}
+	assert.Nil(t, checkRequest(t, policy, request))
+
+	// an empty policy allows everything
+	assert.Nil(t, checkRequest(t, client.APIPolicy{}, client.APICertificateRequest{SAN: []string{"*.google.com"}, Key: client.ECDSA224, ExpirationDays: 36500, Client: true}))
+
+	for _, tc := range []struct {
+		rule   string
+		status int
+		change func(r *client.APICertificateRequest)
+	}{
+		{client.PolicyRuleDeniedSANs, http.StatusForbidden, func(r *client.APICertificateRequest) { r.SAN = []string{"www.prod.example.com"} }},
+		{client.PolicyRuleAllowedSANs, http.StatusForbidden, func(r *client.APICertificateRequest) { r.SAN = []string{"*.google.com"} }},
+		{client.PolicyRuleAllowedSANs, http.StatusForbidden, func(r *client.APICertificateRequest) { r.SAN = []string{"10.1.0.1"} }},
+		{client.PolicyRuleAllowedKeys, http.StatusUnprocessableEntity, func(r *client.APICertificateRequest) { r.Key = client.ECDSA224 }},
+		{client.PolicyRuleMaxExp, http.StatusUnprocessableEntity, func(r *client.APICertificateRequest) { r.ExpirationDays = 36500 }},
+		{client.PolicyRuleDenyClient, http.StatusForbidden, func(r *client.APICertificateRequest) { r.Client = true }},
+		{client.PolicyRuleDenyClient, http.StatusForbidden, func(r *client.APICertificateRequest) { r.ExtKeyUsage = []string{client.ExtKeyUsageClientAuth} }},
+		{client.PolicyRuleDenyClient, http.StatusForbidden, func(r *client.APICertificateRequest) { r.ExtKeyUsage = []string{"1.3.6.1.5.5.7.3.2"} }},
+		{client.PolicyRuleDenyClient, http.StatusForbidden, func(r *client.APICertificateRequest) { r.ExtKeyUsage = []string{"2.5.29.37.0"} }},
+		{client.PolicyRuleAllowedSANs, http.StatusForbidden, func(r *client.APICertificateRequest) { r.SAN = []string{"www.example.com", "upn:admin@google.com"} }},
+		{client.PolicyRuleRequiredDN, http.StatusUnprocessableEntity, func(r *client.APICertificateRequest) { r.DN.O = "" }},
+	} {
+		changed := request
+		tc.change(&changed)
+
+		err := checkRequest(t, policy, changed)
+		violation, ok := err.(*client.PolicyViolation)
+		assert.True(t, ok, tc.rule)
+		if ok {
+			assert.Equal(t, tc.rule, violation.Rule)
+			assert.Equal(t, tc.status, violation.Status())
+			assert.NotEmpty(t, violation.Reason)
+		}
+	}
+
+	// usages set as a raw extension are checked too
+	cert, err := APITox509Certificate(request)
+	assert.Nil(t, err)
+	key, err := apiToCryptoKey(request)
+	assert.Nil(t, err)
+
+	value, err := asn1.Marshal([]asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 2}})
+	assert.Nil(t, err)
+	cert.ExtraExtensions = append(cert.ExtraExtensions, pkix.Extension{Id: asn1.ObjectIdentifier{2, 5, 29, 37}, Value: value})
+	assert.Equal(t, client.PolicyRuleDenyClient, CheckPolicy(policy, cert, key.(crypto.Signer).Public()).(*client.PolicyViolation).Rule)
+
+	// must fail, invalid policies
+	for _, invalid := range []client.APIPolicy{
+		{AllowedSANs: []string{"[example.com"}},
+		{DeniedSANs: []string{"regex:(unclosed"}},
+		{AllowedKeys: []string{"rsa:1024"}},
+		{RequiredDN: []string{"cn"}},
+		{MaxExpirationDays: -1},
+	} {
+		assert.Equal(t, ErrPolicyInvalid, ValidatePolicy(invalid))
+	}
+
+}
+
+// checkRequest checks the policy on the certificate that the request would sign
+func checkRequest(t *testing.T, policy client.APIPolicy, request client.APICertificateRequest) error {
+
+	cert, err := APITox509Certificate(request)
+	assert.Nil(t, err)
+
+	key, err := apiToCryptoKey(request)
+	assert.Nil(t, err)
+
+	setLeafUsages(cert, request.Client, key.(crypto.Signer).Public())
+
+	return CheckPolicy(policy, cert, key.(crypto.Signer).Public())
+
+}
+
+func TestPolicyOnCA(t *testing.T) {
+
+	var caRequest client.APICertificateRequest
+
+	caRequest.DN.CN = "ca"
+	caRequest.ExpirationDays = 90
+	caRequest.Key = client.ECDSA256
+	caRequest.PathLength = 1
+
+	caCert, caKey, err := New(caRequest)
+	assert.Nil(t, err)
+
+	ca, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+
+	ca.SetPolicy(client.APIPolicy{
+		AllowedSANs: []string{"*.example.com"},
+		AllowedKeys: []string{client.RSA2048},
+	})
+
+	request := client.APICertificateRequest{
+		DN:             client.APIDN{CN: "service"},
+		SAN:            []string{"www.example.com"},
+		Key:            client.RSA2048,
+		ExpirationDays: 30,
+	}
+
+	_, _, err = ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	request.SAN = []string{"www.google.com"}
+	_, _, err = ca.CreateCertificateFromAPI(request)
+	assert.IsType(t, &client.PolicyViolation{}, err)
+
+	// the key of the CSR is checked too
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "external"},
+		DNSNames: []string{"external.example.com"},
+	}, key)
+	assert.Nil(t, err)
+
+	_, _, err = ca.CreateCertificateFromCSR(client.APICSRRequest{CSR: pem.EncodeToMemory(&pem.Block{Type: FileCSR, Bytes: csrDER}), ExpirationDays: 30})
+	assert.Equal(t, client.PolicyRuleAllowedKeys, err.(*client.PolicyViolation).Rule)
+
+	// intermediates are checked too, but the client usage they take from the CA is allowed
+	ca.SetPolicy(client.APIPolicy{AllowedKeys: []string{client.RSA2048}, DenyClient: true})
+
+	_, _, err = ca.NewIntermediate(client.APICertificateRequest{DN: client.APIDN{CN: "sub"}, Key: client.ECDSA256, ExpirationDays: 30})
+	assert.Equal(t, client.PolicyRuleAllowedKeys, err.(*client.PolicyViolation).Rule)
+
+	_, _, err = ca.NewIntermediate(client.APICertificateRequest{DN: client.APIDN{CN: "sub"}, Key: client.RSA2048, ExpirationDays: 30})
+	assert.Nil(t, err)
+
+}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"regexp"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+)
+
+// profileName are the names allowed for the profiles, as they are part of the API paths
+var profileName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// ValidateProfile returns ErrProfileInvalid if the profile name or any of its values are not valid
+func ValidateProfile(profile client.APIProfile) error {
+
+	if !profileName.MatchString(profile.Name) {
+		return ErrProfileInvalid
+	}
+
+	for _, values := range []client.APIProfileValues{profile.Defaults, profile.Fixed} {
+		if values.Key != "" && !validKey(values.Key) {
+			return ErrProfileInvalid
+		}
+
+		if values.ExpirationDays < 0 || values.DN.CN != "" {
+			return ErrProfileInvalid
+		}
+
+		for _, usage := range values.ExtKeyUsage {
+			if _, ok := extKeyUsages[usage]; ok {
+				continue
+			}
+			if _, err := parseOID(usage); err != nil {
+				return ErrProfileInvalid
+			}
+		}
+	}
+
+	return nil
+
+}
+
+// ApplyProfile returns the request with the profile applied, its defaults fill the values left
+// empty on the request and its fixed values replace the ones on the request
+func ApplyProfile(request client.APICertificateRequest, profile client.APIProfile) client.APICertificateRequest {
+
+	applyProfileValues(&request, profile.Defaults, false)
+	applyProfileValues(&request, profile.Fixed, true)
+
+	request.Profile = profile.Name
+
+	return request
+
+}
+
+func applyProfileValues(request *client.APICertificateRequest, values client.APIProfileValues, fixed bool) {
+
+	setString := func(current *string, value string) {
+		if value != "" && (fixed || *current == "") {
+			*current = value
+		}
+	}
+
+	setString(&request.Key, values.Key)
+	setString(&request.DN.C, values.DN.C)
+	setString(&request.DN.L, values.DN.L)
+	setString(&request.DN.O, values.DN.O)
+	setString(&request.DN.OU, values.DN.OU)
+	setString(&request.DN.P, values.DN.P)
+	setString(&request.DN.PC, values.DN.PC)
+	setString(&request.DN.ST, values.DN.ST)
+
+	// validity takes precedence over the expiration days, so fixed days replace it
+	if values.ExpirationDays > 0 && (fixed || (request.ExpirationDays == 0 && request.Validity == "")) {
+		request.ExpirationDays = values.ExpirationDays
+		request.Validity = ""
+	}
+
+	// a request cannot tell if it does not want a client certificate, so defaults only enable it
+	if values.Client != nil && (fixed || *values.Client) {
+		request.Client = *values.Client
+	}
+
+	if len(values.ExtKeyUsage) > 0 && (fixed || len(request.ExtKeyUsage) == 0) {
+		request.ExtKeyUsage = append([]string{}, values.ExtKeyUsage...)
+	}
+
+}
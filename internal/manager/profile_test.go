@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile(t *testing.T) {
+
+	var (
+		enabled  = true
+		disabled = false
+		request  client.APICertificateRequest
+	)
+
+	profile := client.APIProfile{
+		Name: "server",
+		Defaults: client.APIProfileValues{
+			DN:             client.APIDN{O: "Default Org", OU: "Default OU"},
+			Key:            client.ECDSA256,
+			ExpirationDays: 90,
+			ExtKeyUsage:    []string{client.ExtKeyUsageServerAuth},
+		},
+		Fixed: client.APIProfileValues{
+			DN:     client.APIDN{C: "ES"},
+			Client: &disabled,
+		},
+	}
+	assert.Nil(t, ValidateProfile(profile))
+
+	// defaults fill the empty values
+	request.DN.CN = "server"
+	request.DN.C = "FR"
+	request.Client = true
+	request.Profile = profile.Name
+
+	applied := ApplyProfile(request, profile)
+	assert.Equal(t, "server", applied.DN.CN)
+	assert.Equal(t, "Default Org", applied.DN.O)
+	assert.Equal(t, "Default OU", applied.DN.OU)
+	assert.Equal(t, "ES", applied.DN.C)
+	assert.Equal(t, client.ECDSA256, applied.Key)
+	assert.Equal(t, int64(90), applied.ExpirationDays)
+	assert.Equal(t, []string{client.ExtKeyUsageServerAuth}, applied.ExtKeyUsage)
+	assert.False(t, applied.Client)
+	assert.Equal(t, "server", applied.Profile)
+	assert.True(t, valid(applied))
+
+	// requested values are kept over the defaults
+	request.DN.OU = "Requested OU"
+	request.Key = client.RSA2048
+	request.ExpirationDays = 10
+	request.ExtKeyUsage = []string{client.ExtKeyUsageCodeSigning}
+
+	applied = ApplyProfile(request, profile)
+	assert.Equal(t, "Requested OU", applied.DN.OU)
+	assert.Equal(t, client.RSA2048, applied.Key)
+	assert.Equal(t, int64(10), applied.ExpirationDays)
+	assert.Equal(t, []string{client.ExtKeyUsageCodeSigning}, applied.ExtKeyUsage)
+
+	// validity takes precedence over the default days, fixed days replace it
+	request.Validity = "6h"
+	request.ExpirationDays = 0
+	assert.Equal(t, "6h", ApplyProfile(request, profile).Validity)
+
+	profile.Fixed.ExpirationDays = 30
+	applied = ApplyProfile(request, profile)
+	assert.Equal(t, "", applied.Validity)
+	assert.Equal(t, int64(30), applied.ExpirationDays)
+	profile.Fixed.ExpirationDays = 0
+	request.Validity = ""
+
+	// client defaults can only enable client certificates
+	profile.Fixed.Client = nil
+	profile.Defaults.Client = &enabled
+	request.Client = false
+	assert.True(t, ApplyProfile(request, profile).Client)
+
+	profile.Defaults.Client = &disabled
+	request.Client = true
+	assert.True(t, ApplyProfile(request, profile).Client)
+
+	// must fail, invalid profiles
+	for _, invalid := range []client.APIProfile{
+		{Name: ""},
+		{Name: "with spaces"},
+		{Name: "key", Defaults: client.APIProfileValues{Key: "rsa:1024"}},
+		{Name: "cn", Fixed: client.APIProfileValues{DN: client.APIDN{CN: "fixed"}}},
+		{Name: "exp", Fixed: client.APIProfileValues{ExpirationDays: -1}},
+		{Name: "eku", Fixed: client.APIProfileValues{ExtKeyUsage: []string{"invalid"}}},
+	} {
+		assert.Equal(t, ErrProfileInvalid, ValidateProfile(invalid), invalid.Name)
+	}
+
+	// OIDs are allowed as extended key usages
+	assert.Nil(t, ValidateProfile(client.APIProfile{Name: "oid", Fixed: client.APIProfileValues{ExtKeyUsage: []string{"1.3.6.1.5.5.7.3.3"}}}))
+
+}
@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+)
+
+// Rollover holds the certificates created on a CA rollover
+type Rollover struct {
+	Certificate         []byte // new CA certificate (self signed)
+	Key                 []byte // new CA key
+	CrossSigned         []byte // new CA certificate signed by the previous CA key
+	PreviousCrossSigned []byte // previous CA certificate signed by the new CA key
+}
+
+// IsRoot returns true if the CA certificate is self signed
+func (c *CA) IsRoot() bool {
+
+	return bytes.Equal(c.ca.RawIssuer, c.ca.RawSubject) && c.ca.CheckSignatureFrom(c.ca) == nil
+
+}
+
+// Rollover creates a new root CA with the same subject, constraints and usages than the CA, and
+// cross signs both CAs so certificates issued by any of them can be verified trusting the other.
+//
+// keyAlgorithm and expirationDays are the ones of the current CA if empty
+func (c *CA) Rollover(keyAlgorithm string, expirationDays int64) (rollover Rollover, err error) {
+
+	var (
+		key    crypto.PrivateKey
+		next   *CA
+		cert   *x509.Certificate
+		expire time.Duration
+	)
+
+	if !c.IsRoot() {
+		return rollover, ErrNotRoot
+	}
+
+	if keyAlgorithm == "" {
+		keyAlgorithm = keyType(c.ca.PublicKey)
+	}
+
+	expire = time.Duration(expirationDays*24) * time.Hour
+	if expirationDays <= 0 {
+		expire = c.ca.NotAfter.Sub(c.ca.NotBefore)
+	}
+
+	key, err = apiToCryptoKey(client.APICertificateRequest{Key: keyAlgorithm})
+	if err != nil {
+		return
+	}
+
+	cert = caTemplate(c.ca, time.Now().Add(expire))
+	cert.SubjectKeyId, err = subjectKeyID(key)
+	if err != nil {
+		return
+	}
+
+	next = &CA{ca: cert, caKey: key.(crypto.Signer)}
+
+	rollover.Certificate, rollover.Key, err = next.CreateCertificate(cert, key)
+	if err != nil {
+		return
+	}
+
+	next, err = FromBytes(rollover.Certificate, rollover.Key)
+	if err != nil {
+		return
+	}
+
+	// cross signed certificates cannot outlive its issuer
+	rollover.CrossSigned, err = c.SignPublicKey(caTemplate(next.ca, earliest(next.ca.NotAfter, c.ca.NotAfter)), next.ca.PublicKey)
+	if err != nil {
+		return
+	}
+
+	rollover.PreviousCrossSigned, err = next.SignPublicKey(caTemplate(c.ca, earliest(c.ca.NotAfter, next.ca.NotAfter)), c.ca.PublicKey)
+
+	return
+
+}
+
+// caTemplate returns a template to issue again the CA certificate with a new validity
+func caTemplate(ca *x509.Certificate, notAfter time.Time) *x509.Certificate {
+
+	return &x509.Certificate{
+		Subject:                     ca.Subject,
+		NotBefore:                   time.Now(),
+		NotAfter:                    notAfter,
+		SubjectKeyId:                ca.SubjectKeyId,
+		KeyUsage:                    ca.KeyUsage,
+		ExtKeyUsage:                 ca.ExtKeyUsage,
+		UnknownExtKeyUsage:          ca.UnknownExtKeyUsage,
+		BasicConstraintsValid:       ca.BasicConstraintsValid,
+		IsCA:                        ca.IsCA,
+		MaxPathLen:                  ca.MaxPathLen,
+		MaxPathLenZero:              ca.MaxPathLenZero,
+		DNSNames:                    ca.DNSNames,
+		EmailAddresses:              ca.EmailAddresses,
+		IPAddresses:                 ca.IPAddresses,
+		URIs:                        ca.URIs,
+		PermittedDNSDomainsCritical: ca.PermittedDNSDomainsCritical,
+		PermittedDNSDomains:         ca.PermittedDNSDomains,
+		ExcludedDNSDomains:          ca.ExcludedDNSDomains,
+		PermittedIPRanges:           ca.PermittedIPRanges,
+		ExcludedIPRanges:            ca.ExcludedIPRanges,
+		PermittedEmailAddresses:     ca.PermittedEmailAddresses,
+		ExcludedEmailAddresses:      ca.ExcludedEmailAddresses,
+		PermittedURIDomains:         ca.PermittedURIDomains,
+		ExcludedURIDomains:          ca.ExcludedURIDomains,
+		PolicyIdentifiers:           ca.PolicyIdentifiers,
+		CRLDistributionPoints:       ca.CRLDistributionPoints,
+		IssuingCertificateURL:       ca.IssuingCertificateURL,
+		OCSPServer:                  ca.OCSPServer,
+	}
+
+}
+
+// earliest returns the earliest of two times
+func earliest(a, b time.Time) time.Time {
+
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+
+}
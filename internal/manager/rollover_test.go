@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollover(t *testing.T) {
+
+	var caRequest, request client.APICertificateRequest
+
+	caRequest.DN.CN = "ca"
+	caRequest.ExpirationDays = 90
+	caRequest.Key = client.ECDSA256
+	caRequest.PathLength = 1
+	caRequest.NameConstraints.PermittedDNS = []string{"example.com"}
+
+	caCert, caKey, err := New(caRequest)
+	assert.Nil(t, err)
+
+	ca, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+	assert.True(t, ca.IsRoot())
+
+	request.DN.CN = "leaf"
+	request.SAN = []string{"leaf.example.com"}
+	request.ExpirationDays = 30
+	request.Key = client.ECDSA256
+
+	leafPEM, _, err := ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	rollover, err := ca.Rollover(client.ED25519, 365)
+	assert.Nil(t, err)
+
+	next, err := FromBytes(rollover.Certificate, rollover.Key)
+	assert.Nil(t, err)
+	assert.True(t, next.IsRoot())
+	assert.Equal(t, ca.CACertificate().Subject, next.CACertificate().Subject)
+	assert.Equal(t, ca.CACertificate().PermittedDNSDomains, next.CACertificate().PermittedDNSDomains)
+	assert.Equal(t, ca.CACertificate().MaxPathLen, next.CACertificate().MaxPathLen)
+	assert.NotEqual(t, ca.CACertificate().SubjectKeyId, next.CACertificate().SubjectKeyId)
+	assert.Equal(t, "ed25519", keyType(next.CACertificate().PublicKey))
+
+	crossSigned, err := CertificateFromPEM(rollover.CrossSigned)
+	assert.Nil(t, err)
+	assert.Nil(t, crossSigned.CheckSignatureFrom(ca.CACertificate()))
+	assert.False(t, crossSigned.NotAfter.After(ca.CACertificate().NotAfter))
+
+	previousCrossSigned, err := CertificateFromPEM(rollover.PreviousCrossSigned)
+	assert.Nil(t, err)
+	assert.Nil(t, previousCrossSigned.CheckSignatureFrom(next.CACertificate()))
+
+	newLeafPEM, _, err := next.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	// leaves issued by any of the CAs can be verified trusting any of them
+	for _, trusted := range []*x509.Certificate{ca.CACertificate(), next.CACertificate()} {
+		for _, certPEM := range [][]byte{leafPEM, newLeafPEM} {
+			roots := x509.NewCertPool()
+			roots.AddCert(trusted)
+			intermediates := x509.NewCertPool()
+			intermediates.AddCert(crossSigned)
+			intermediates.AddCert(previousCrossSigned)
+
+			leaf, err := CertificateFromPEM(certPEM)
+			assert.Nil(t, err)
+
+			_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, DNSName: "leaf.example.com"})
+			assert.Nil(t, err)
+		}
+	}
+
+	// current key type and lifetime are kept
+	rollover, err = ca.Rollover("", 0)
+	assert.Nil(t, err)
+
+	next, err = FromBytes(rollover.Certificate, rollover.Key)
+	assert.Nil(t, err)
+	assert.Equal(t, "ecdsa:256", keyType(next.CACertificate().PublicKey))
+
+	// must fail, intermediates are not roots
+	request.DN.CN = "intermediate"
+	request.PathLength = 0
+	intermediateCert, intermediateKey, err := ca.NewIntermediate(request)
+	assert.Nil(t, err)
+
+	intermediate, err := FromBytes(intermediateCert, intermediateKey)
+	assert.Nil(t, err)
+	assert.False(t, intermediate.IsRoot())
+
+	_, err = intermediate.Rollover("", 0)
+	assert.Equal(t, ErrNotRoot, err)
+
+	// must fail, invalid key type
+	_, err = ca.Rollover("invalid", 0)
+	assert.Equal(t, ErrKeyInvalid, err)
+
+}
@@ -0,0 +1,238 @@
+package manager
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+)
+
+var (
+	oidExtensionSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidUPN                     = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 3}
+)
+
+// GeneralName tags (RFC 5280 4.2.1.6)
+const (
+	nameTypeOther = 0
+	nameTypeEmail = 1
+	nameTypeDNS   = 2
+	nameTypeURI   = 6
+	nameTypeIP    = 7
+)
+
+// setSANs fills the certificate SANs, typed values (dns:, ip:, email:, uri:, upn:) are set as is
+// while values without prefix are guessed. Returns the UPNs found, they cannot be set on the
+// x509.Certificate fields so the SAN extension must be built with them (see sanExtension)
+func setSANs(cert *x509.Certificate, sans []string) (upns []string, err error) {
+
+	for _, san := range sans {
+
+		prefix, value := sanType(san)
+
+		switch prefix {
+		case client.SANDNS:
+			if value == "" {
+				return nil, ErrSANInvalid
+			}
+			cert.DNSNames = append(cert.DNSNames, value)
+		case client.SANIP:
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, ErrSANInvalid
+			}
+			cert.IPAddresses = append(cert.IPAddresses, ip)
+		case client.SANEmail:
+			email, err := mail.ParseAddress(value)
+			if err != nil || email.Address != value {
+				return nil, ErrSANInvalid
+			}
+			cert.EmailAddresses = append(cert.EmailAddresses, value)
+		case client.SANURI:
+			u, err := url.Parse(value)
+			if err != nil || u.Scheme == "" {
+				return nil, ErrSANInvalid
+			}
+			cert.URIs = append(cert.URIs, u)
+		case client.SANUPN:
+			if !strings.Contains(value, "@") {
+				return nil, ErrSANInvalid
+			}
+			upns = append(upns, value)
+		default:
+			guessSAN(cert, san)
+		}
+
+	}
+
+	return
+
+}
+
+// sanType returns the prefix of the SAN (if any) and its value
+func sanType(san string) (string, string) {
+
+	for _, prefix := range []string{client.SANDNS, client.SANIP, client.SANEmail, client.SANURI, client.SANUPN} {
+		if len(san) >= len(prefix) && strings.EqualFold(san[:len(prefix)], prefix) {
+			return prefix, san[len(prefix):]
+		}
+	}
+
+	return "", san
+
+}
+
+// guessSAN sets the SAN on the field that looks more appropriate: IP, email, URI and DNS
+func guessSAN(cert *x509.Certificate, san string) {
+
+	if ip := net.ParseIP(san); ip != nil {
+		cert.IPAddresses = append(cert.IPAddresses, ip)
+		return
+	}
+
+	email, err := mail.ParseAddress(san)
+	if email != nil && err == nil {
+		cert.EmailAddresses = append(cert.EmailAddresses, san)
+		return
+	}
+
+	// is uri?
+	u, err := url.Parse(san)
+	if err == nil && u.Scheme != "" && u.Host != "" {
+		cert.URIs = append(cert.URIs, u)
+		return
+	}
+
+	// then is a DNS name
+	cert.DNSNames = append(cert.DNSNames, san)
+
+}
+
+// apiSANs returns the SANs with its type prefix, so they are set as they are when the request is
+// used again (renewals). UPNs are read from the SAN extension, x509 does not parse otherNames
+func apiSANs(dnsNames []string, ips []net.IP, emails []string, uris []*url.URL, extensions []pkix.Extension) (sans []string) {
+
+	for _, dns := range dnsNames {
+		sans = append(sans, client.SANDNS+dns)
+	}
+	for _, ip := range ips {
+		sans = append(sans, client.SANIP+ip.String())
+	}
+	for _, email := range emails {
+		sans = append(sans, client.SANEmail+email)
+	}
+	for _, u := range uris {
+		sans = append(sans, client.SANURI+u.String())
+	}
+	for _, upn := range extensionUPNs(extensions) {
+		sans = append(sans, client.SANUPN+upn)
+	}
+
+	return
+
+}
+
+// extensionUPNs returns the UPNs of the SAN extension, other otherNames are ignored
+func extensionUPNs(extensions []pkix.Extension) (upns []string) {
+
+	for _, ext := range extensions {
+		if !ext.Id.Equal(oidExtensionSubjectAltName) {
+			continue
+		}
+
+		var generalNames []asn1.RawValue
+		_, err := asn1.Unmarshal(ext.Value, &generalNames)
+		if err != nil {
+			return
+		}
+
+		for _, name := range generalNames {
+			if name.Tag != nameTypeOther || name.Class != asn1.ClassContextSpecific {
+				continue
+			}
+
+			typeID, value, err := otherName(name)
+			if err == nil && typeID.Equal(oidUPN) {
+				upns = append(upns, value)
+			}
+		}
+	}
+
+	return
+
+}
+
+// otherName returns the type and the (UTF8) value of the otherName general name
+func otherName(name asn1.RawValue) (typeID asn1.ObjectIdentifier, value string, err error) {
+
+	var other struct {
+		TypeID asn1.ObjectIdentifier
+		Value  asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+
+	_, err = asn1.UnmarshalWithParams(name.FullBytes, &other, "tag:0")
+	if err != nil {
+		return
+	}
+
+	_, err = asn1.UnmarshalWithParams(other.Value.Bytes, &value, "utf8")
+
+	return other.TypeID, value, err
+
+}
+
+// sanExtension returns the SAN extension with the certificate SANs and the UPNs as otherName
+func sanExtension(cert *x509.Certificate, upns []string) (ext pkix.Extension, err error) {
+
+	var names []asn1.RawValue
+
+	for _, upn := range upns {
+		var otherName []byte
+		otherName, err = asn1.Marshal(struct {
+			TypeID asn1.ObjectIdentifier
+			Value  string `asn1:"explicit,tag:0,utf8"`
+		}{oidUPN, upn})
+		if err != nil {
+			return
+		}
+
+		// otherName is implicitly tagged, its contents are the sequence contents
+		var sequence asn1.RawValue
+		_, err = asn1.Unmarshal(otherName, &sequence)
+		if err != nil {
+			return
+		}
+
+		names = append(names, asn1.RawValue{Tag: nameTypeOther, Class: asn1.ClassContextSpecific, IsCompound: true, Bytes: sequence.Bytes})
+	}
+
+	for _, email := range cert.EmailAddresses {
+		names = append(names, asn1.RawValue{Tag: nameTypeEmail, Class: asn1.ClassContextSpecific, Bytes: []byte(email)})
+	}
+
+	for _, dns := range cert.DNSNames {
+		names = append(names, asn1.RawValue{Tag: nameTypeDNS, Class: asn1.ClassContextSpecific, Bytes: []byte(dns)})
+	}
+
+	for _, u := range cert.URIs {
+		names = append(names, asn1.RawValue{Tag: nameTypeURI, Class: asn1.ClassContextSpecific, Bytes: []byte(u.String())})
+	}
+
+	for _, ip := range cert.IPAddresses {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		names = append(names, asn1.RawValue{Tag: nameTypeIP, Class: asn1.ClassContextSpecific, Bytes: ip})
+	}
+
+	ext.Id = oidExtensionSubjectAltName
+	ext.Value, err = asn1.Marshal(names)
+
+	return
+
+}
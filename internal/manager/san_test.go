@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"net"
+	"testing"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedSANs(t *testing.T) {
+
+	var request client.APICertificateRequest
+
+	request.DN.CN = "san ca"
+	request.ExpirationDays = 90
+	request.Key = client.ECDSA256
+
+	caCert, caKey, err := New(request)
+	assert.Nil(t, err)
+
+	ca, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+
+	request = client.APICertificateRequest{
+		DN: client.APIDN{CN: "john"},
+		SAN: []string{
+			"dns:user@host",
+			"IP:10.0.0.1",
+			"email:john@example.com",
+			"uri:spiffe://example.org/john",
+			"upn:john@corp.example.com",
+			"www.example.com", // guessed
+			"192.168.1.1",     // guessed
+		},
+		ExpirationDays: 30,
+		Key:            client.ECDSA256,
+		Client:         true,
+	}
+
+	certPEM, _, err := ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	cert, err := CertificateFromPEM(certPEM)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"user@host", "www.example.com"}, cert.DNSNames)
+	assert.Equal(t, []string{"john@example.com"}, cert.EmailAddresses)
+	assert.Len(t, cert.URIs, 1)
+	assert.Equal(t, "spiffe://example.org/john", cert.URIs[0].String())
+	assert.Len(t, cert.IPAddresses, 2)
+	assert.True(t, net.ParseIP("10.0.0.1").Equal(cert.IPAddresses[0]))
+	assert.Equal(t, []string{"john@corp.example.com"}, upnsFromCertificate(t, cert))
+
+	// the certificate must be verifiable
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.CACertificate())
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	assert.Nil(t, err)
+
+	for _, san := range []string{"ip:not an ip", "email:not an email", "uri:no-scheme", "upn:john", "dns:"} {
+		request.SAN = []string{san}
+		_, _, err = ca.CreateCertificateFromAPI(request)
+		assert.Equal(t, ErrSANInvalid, err, san)
+	}
+
+}
+
+func upnsFromCertificate(t *testing.T, cert *x509.Certificate) (upns []string) {
+
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidExtensionSubjectAltName) {
+			continue
+		}
+
+		var names []asn1.RawValue
+		_, err := asn1.Unmarshal(ext.Value, &names)
+		assert.Nil(t, err)
+
+		for _, name := range names {
+			if name.Tag != nameTypeOther {
+				continue
+			}
+
+			var otherName struct {
+				TypeID asn1.ObjectIdentifier
+				Value  asn1.RawValue `asn1:"explicit,tag:0"`
+			}
+			_, err = asn1.UnmarshalWithParams(name.FullBytes, &otherName, "tag:0")
+			assert.Nil(t, err)
+			assert.True(t, otherName.TypeID.Equal(oidUPN))
+
+			var upn string
+			_, err = asn1.UnmarshalWithParams(otherName.Value.Bytes, &upn, "utf8")
+			assert.Nil(t, err)
+			upns = append(upns, upn)
+		}
+	}
+
+	return
+
+}
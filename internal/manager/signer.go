@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"crypto"
+	"encoding/pem"
+	"strings"
+	"sync"
+
+	"github.com/fernandezvara/certsfor/pkg/signer"
+)
+
+// key reference PEM headers
+const (
+	headerSigner = "Signer"
+	headerKeyID  = "Key-Id"
+)
+
+var (
+	signers   = make(map[string]string) // signer plugin URIs by name
+	signersMu sync.RWMutex
+)
+
+// SetSigners sets the signer plugins that the key references can use, by name. The stored references
+// only hold the name, so how the plugins are reached (the command run or the socket) is only decided
+// by the server configuration. Names are case insensitive
+func SetSigners(uris map[string]string) error {
+
+	var configured = make(map[string]string)
+
+	for name, uri := range uris {
+		if !strings.HasPrefix(uri, signer.SchemeExec) && !strings.HasPrefix(uri, signer.SchemeUnix) {
+			return signer.ErrURIInvalid
+		}
+		configured[strings.ToLower(name)] = uri
+	}
+
+	signersMu.Lock()
+	signers = configured
+	signersMu.Unlock()
+
+	return nil
+
+}
+
+// KeyReference returns the PEM block stored instead of the key of CAs whose key is held by a
+// signer plugin (see pkg/signer), name is the plugin as configured (see SetSigners) and keyID the
+// key it must use
+func KeyReference(name, keyID string) []byte {
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type: FileKeyReference,
+		Headers: map[string]string{
+			headerSigner: name,
+			headerKeyID:  keyID,
+		},
+	})
+
+}
+
+// IsKeyReference returns true if the key is a reference to a key held by a signer plugin
+func IsKeyReference(data []byte) bool {
+
+	block, _ := pem.Decode(data)
+	return block != nil && block.Type == FileKeyReference
+
+}
+
+// signerFromReference returns the signer of the plugin referenced
+func signerFromReference(data []byte) (crypto.Signer, error) {
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != FileKeyReference {
+		return nil, ErrUnparseableFile
+	}
+
+	signersMu.RLock()
+	uri, ok := signers[strings.ToLower(block.Headers[headerSigner])]
+	signersMu.RUnlock()
+
+	if !ok {
+		return nil, ErrSignerUnknown
+	}
+
+	return signer.Open(uri, block.Headers[headerKeyID])
+
+}
+
+// publicKeyEqual returns true if both public keys are the same
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+
+	public, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && public.Equal(b)
+
+}
@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/certsfor/pkg/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyReference(t *testing.T) {
+
+	var caRequest, request client.APICertificateRequest
+
+	caRequest.DN.CN = "ca"
+	caRequest.ExpirationDays = 90
+	caRequest.Key = client.ECDSA256
+
+	caCert, caKey, err := New(caRequest)
+	assert.Nil(t, err)
+
+	_, otherKey, err := New(caRequest)
+	assert.Nil(t, err)
+
+	keys := make(map[string]crypto.Signer)
+	for id, keyPEM := range map[string][]byte{"ca": caKey, "other": otherKey} {
+		key, err := PrivateKeyFromPEM(keyPEM)
+		assert.Nil(t, err)
+		keys[id] = key.(crypto.Signer)
+	}
+
+	dir, err := ioutil.TempDir("", "cfd-signer")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "signer.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	go signer.ServeListener(listener, func(keyID string) (crypto.Signer, error) {
+		key, ok := keys[keyID]
+		if !ok {
+			return nil, errors.New("key not found")
+		}
+		return key, nil
+	})
+
+	// must fail, invalid uri
+	assert.Equal(t, signer.ErrURIInvalid, SetSigners(map[string]string{"hsm": "/bin/sh"}))
+
+	assert.Nil(t, SetSigners(map[string]string{"HSM": signer.SchemeUnix + socket}))
+	defer SetSigners(nil)
+
+	reference := KeyReference("hsm", "ca")
+	assert.True(t, IsKeyReference(reference))
+	assert.False(t, IsKeyReference(caKey))
+
+	// the reference is stored as key
+	certPEM, keyPEM, _, err := ImportCA(caCert, reference, "")
+	assert.Nil(t, err)
+	assert.Equal(t, reference, keyPEM)
+
+	ca, err := FromBytes(certPEM, keyPEM)
+	assert.Nil(t, err)
+
+	request.DN.CN = "leaf"
+	request.SAN = []string{"leaf.example.com"}
+	request.ExpirationDays = 30
+	request.Key = client.RSA2048
+
+	leafPEM, _, err := ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	leaf, err := CertificateFromPEM(leafPEM)
+	assert.Nil(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.CACertificate())
+
+	_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: "leaf.example.com"})
+	assert.Nil(t, err)
+
+	// must fail, the plugin key is not the CA key
+	_, _, _, err = ImportCA(caCert, KeyReference("hsm", "other"), "")
+	assert.Equal(t, ErrKeyMismatch, err)
+
+	_, err = FromBytes(caCert, KeyReference("hsm", "other"))
+	assert.Equal(t, ErrKeyMismatch, err)
+
+	// must fail, the plugin does not hold the key
+	_, err = FromBytes(caCert, KeyReference("hsm", "unknown"))
+	assert.NotNil(t, err)
+
+	// must fail, only the signers configured can be used, whatever the reference says
+	pwned := filepath.Join(dir, "pwned")
+
+	_, _, _, err = ImportCA(caCert, KeyReference(signer.SchemeExec+"/usr/bin/touch "+pwned, "ca"), "")
+	assert.Equal(t, ErrSignerUnknown, err)
+
+	_, err = FromBytes(caCert, KeyReference(signer.SchemeUnix+socket, "ca"))
+	assert.Equal(t, ErrSignerUnknown, err)
+
+	_, err = FromBytes(caCert, KeyReference("other", "ca"))
+	assert.Equal(t, ErrSignerUnknown, err)
+	assert.NoFileExists(t, pwned)
+
+}
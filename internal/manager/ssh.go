@@ -0,0 +1,270 @@
+package manager
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHDefaultValidity is the time the SSH certificates are valid if the request does not set it
+const SSHDefaultValidity = 24 * time.Hour
+
+// sshUserExtensions are the extensions of the user certificates if the request does not set any,
+// the same ones that ssh-keygen sets by default
+var sshUserExtensions = []string{
+	"permit-X11-forwarding",
+	"permit-agent-forwarding",
+	"permit-port-forwarding",
+	"permit-pty",
+	"permit-user-rc",
+}
+
+// SSHCA signs OpenSSH user and host certificates
+type SSHCA struct {
+	signer   ssh.Signer
+	backdate time.Duration
+	policy   client.APIPolicy
+}
+
+// NewSSHCA creates a new SSH CA key (Ed25519), returned as PKCS#8 PEM
+func NewSSHCA() ([]byte, error) {
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: FilePrivateKey, Bytes: keyBytes}), nil
+
+}
+
+// SSHFromBytes returns the SSH CA for the key PEM
+func SSHFromBytes(keyPEM []byte) (*SSHCA, error) {
+
+	key, err := PrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.NewSignerFromSigner(key.(crypto.Signer))
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSHCA{signer: signer}, nil
+
+}
+
+// SetBackdate sets how much the certificates are valid before they are signed, so hosts with a
+// skewed clock accept them
+func (s *SSHCA) SetBackdate(backdate time.Duration) {
+
+	if backdate > 0 {
+		s.backdate = backdate
+	}
+
+}
+
+// SetPolicy sets the policy checked before signing the certificates, only its SSH rules apply
+func (s *SSHCA) SetPolicy(policy client.APIPolicy) {
+	s.policy = policy
+}
+
+// PublicKey returns the CA public key in authorized_keys format, as used on TrustedUserCAKeys
+func (s *SSHCA) PublicKey() []byte {
+
+	return ssh.MarshalAuthorizedKey(s.signer.PublicKey())
+
+}
+
+// KnownHosts returns the known_hosts line that trusts the host certificates signed by the CA for
+// the hosts pattern (all hosts if empty)
+func (s *SSHCA) KnownHosts(hosts string) []byte {
+
+	if hosts == "" {
+		hosts = "*"
+	}
+
+	return []byte(fmt.Sprintf("@cert-authority %s %s", hosts, s.PublicKey()))
+
+}
+
+// Sign signs the public key of the request as a user or host certificate (client.SSHUser or
+// client.SSHHost), the certificate is returned in authorized_keys format (as ssh-keygen writes
+// the -cert.pub files)
+func (s *SSHCA) Sign(certType string, request client.APISSHRequest) (response client.APISSHCertificate, err error) {
+
+	var (
+		cert      ssh.Certificate
+		publicKey ssh.PublicKey
+		validity  time.Duration = SSHDefaultValidity
+		now       time.Time     = time.Now()
+		serial    [8]byte
+	)
+
+	publicKey, _, _, _, err = ssh.ParseAuthorizedKey(request.PublicKey)
+	if err != nil {
+		return response, ErrSSHPublicKey
+	}
+
+	if _, ok := publicKey.(*ssh.Certificate); ok {
+		return response, ErrSSHPublicKey
+	}
+
+	if len(request.Principals) == 0 {
+		return response, ErrSSHRequestInvalid
+	}
+
+	if request.Validity != "" {
+		validity, err = time.ParseDuration(request.Validity)
+		if err != nil || validity <= 0 {
+			return response, ErrValidityInvalid
+		}
+	}
+
+	switch certType {
+	case client.SSHUser:
+		cert.CertType = ssh.UserCert
+		cert.CriticalOptions, err = sshCriticalOptions(request.CriticalOptions)
+		if err != nil {
+			return response, err
+		}
+		cert.Extensions = request.Extensions
+		if cert.Extensions == nil {
+			cert.Extensions = make(map[string]string)
+			for _, extension := range sshUserExtensions {
+				cert.Extensions[extension] = ""
+			}
+		}
+	case client.SSHHost:
+		// options and extensions are only defined for user certificates
+		if len(request.CriticalOptions) > 0 || len(request.Extensions) > 0 {
+			return response, ErrSSHRequestInvalid
+		}
+		cert.CertType = ssh.HostCert
+	default:
+		return response, ErrSSHRequestInvalid
+	}
+
+	_, err = rand.Read(serial[:])
+	if err != nil {
+		return response, err
+	}
+
+	cert.Key = publicKey
+	cert.Serial = binary.BigEndian.Uint64(serial[:])
+	cert.KeyId = request.KeyID
+	if cert.KeyId == "" {
+		cert.KeyId = request.Principals[0]
+	}
+	cert.ValidPrincipals = request.Principals
+	cert.ValidAfter = uint64(now.Unix())
+	cert.ValidBefore = uint64(now.Add(validity).Unix())
+
+	// the backdate is not part of the requested validity
+	err = CheckSSHPolicy(s.policy, &cert)
+	if err != nil {
+		return response, err
+	}
+	cert.ValidAfter = uint64(now.Add(-s.backdate).Unix())
+
+	err = cert.SignCert(rand.Reader, s.signer)
+	if err != nil {
+		return response, err
+	}
+
+	response.Certificate = ssh.MarshalAuthorizedKey(&cert)
+	response.Serial = cert.Serial
+	response.KeyID = cert.KeyId
+	response.Principals = cert.ValidPrincipals
+	response.ValidAfter = time.Unix(int64(cert.ValidAfter), 0)
+	response.ValidBefore = time.Unix(int64(cert.ValidBefore), 0)
+
+	return
+
+}
+
+// CheckSSHPolicy returns a *client.PolicyViolation with the first SSH rule of the policy that the
+// certificate does not meet, nil if it can be signed
+func CheckSSHPolicy(policy client.APIPolicy, cert *ssh.Certificate) error {
+
+	if len(policy.SSHAllowedPrincipals) > 0 {
+		for _, principal := range cert.ValidPrincipals {
+			if !matchAny(policy.SSHAllowedPrincipals, principal) {
+				return violation(client.PolicyRuleSSHAllowedPrincipals, principal, fmt.Sprintf("%s does not match any of the allowed principals", principal))
+			}
+		}
+	}
+
+	if len(policy.SSHAllowedExtensions) > 0 {
+		extensions := make([]string, 0, len(cert.Extensions))
+		for extension := range cert.Extensions {
+			extensions = append(extensions, extension)
+		}
+		sort.Strings(extensions)
+
+		for _, extension := range extensions {
+			if !contains(policy.SSHAllowedExtensions, extension) {
+				return violation(client.PolicyRuleSSHAllowedExtensions, extension, fmt.Sprintf("extensions must be any of: %s", strings.Join(policy.SSHAllowedExtensions, ", ")))
+			}
+		}
+	}
+
+	if policy.SSHMaxValidity != "" {
+		maxValidity, err := time.ParseDuration(policy.SSHMaxValidity)
+		if err != nil {
+			return ErrPolicyInvalid
+		}
+
+		validity := time.Duration(cert.ValidBefore-cert.ValidAfter) * time.Second
+		if validity > maxValidity {
+			return violation(client.PolicyRuleSSHMaxValidity, validity.String(), fmt.Sprintf("certificates cannot be valid for more than %s", maxValidity))
+		}
+	}
+
+	return nil
+
+}
+
+// sshCriticalOptions returns the critical options if OpenSSH knows them, hosts reject the
+// certificates with unknown critical options
+func sshCriticalOptions(options map[string]string) (map[string]string, error) {
+
+	for name, value := range options {
+		switch name {
+		case "force-command":
+			if value == "" {
+				return nil, ErrSSHRequestInvalid
+			}
+		case "source-address":
+			for _, address := range strings.Split(value, ",") {
+				if net.ParseIP(address) == nil {
+					if _, _, err := net.ParseCIDR(address); err != nil {
+						return nil, ErrSSHRequestInvalid
+					}
+				}
+			}
+		default:
+			return nil, ErrSSHRequestInvalid
+		}
+	}
+
+	return options, nil
+
+}
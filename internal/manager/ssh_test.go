@@ -0,0 +1,185 @@
+package manager
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHCA(t *testing.T) {
+
+	keyPEM, err := NewSSHCA()
+	assert.Nil(t, err)
+
+	ca, err := SSHFromBytes(keyPEM)
+	assert.Nil(t, err)
+	ca.SetBackdate(time.Minute)
+
+	caKey, _, _, _, err := ssh.ParseAuthorizedKey(ca.PublicKey())
+	assert.Nil(t, err)
+
+	assert.True(t, strings.HasPrefix(string(ca.KnownHosts("")), "@cert-authority * ssh-ed25519 "))
+	assert.True(t, strings.HasPrefix(string(ca.KnownHosts("*.example.com")), "@cert-authority *.example.com ssh-ed25519 "))
+
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	sshPublic, err := ssh.NewPublicKey(public)
+	assert.Nil(t, err)
+
+	request := client.APISSHRequest{
+		PublicKey:       ssh.MarshalAuthorizedKey(sshPublic),
+		Principals:      []string{"alice", "deploy"},
+		Validity:        "8h",
+		CriticalOptions: map[string]string{"source-address": "10.0.0.0/8,192.168.1.1"},
+	}
+
+	// user certificates
+	now := time.Now()
+	response, err := ca.Sign(client.SSHUser, request)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", response.KeyID)
+	assert.WithinDuration(t, now.Add(8*time.Hour), response.ValidBefore, 2*time.Second)
+	assert.WithinDuration(t, now.Add(-time.Minute), response.ValidAfter, 2*time.Second)
+
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey(response.Certificate)
+	assert.Nil(t, err)
+	cert := parsed.(*ssh.Certificate)
+	assert.Equal(t, uint32(ssh.UserCert), cert.CertType)
+	assert.Equal(t, response.Serial, cert.Serial)
+	assert.Contains(t, cert.Extensions, "permit-pty")
+	assert.Equal(t, "10.0.0.0/8,192.168.1.1", cert.CriticalOptions["source-address"])
+
+	checker := ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(caKey.Marshal())
+		},
+		SupportedCriticalOptions: []string{"source-address"},
+	}
+	assert.Nil(t, checker.CheckCert("deploy", cert))
+	assert.NotNil(t, checker.CheckCert("root", cert))
+
+	// extensions requested replace the default ones
+	request.Extensions = map[string]string{"permit-pty": ""}
+	request.KeyID = "alice@laptop"
+	response, err = ca.Sign(client.SSHUser, request)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice@laptop", response.KeyID)
+
+	parsed, _, _, _, err = ssh.ParseAuthorizedKey(response.Certificate)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"permit-pty": ""}, parsed.(*ssh.Certificate).Extensions)
+
+	// host certificates
+	response, err = ca.Sign(client.SSHHost, client.APISSHRequest{PublicKey: request.PublicKey, Principals: []string{"web1.example.com"}})
+	assert.Nil(t, err)
+	assert.WithinDuration(t, now.Add(SSHDefaultValidity), response.ValidBefore, 2*time.Second)
+
+	parsed, _, _, _, err = ssh.ParseAuthorizedKey(response.Certificate)
+	assert.Nil(t, err)
+	cert = parsed.(*ssh.Certificate)
+	assert.Equal(t, uint32(ssh.HostCert), cert.CertType)
+	assert.Len(t, cert.Extensions, 0)
+
+	checker = ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, address string) bool {
+			return string(auth.Marshal()) == string(caKey.Marshal())
+		},
+	}
+	assert.Nil(t, checker.CheckHostKey("web1.example.com:22", nil, cert))
+
+	// must fail, invalid requests
+	for name, invalid := range map[string]client.APISSHRequest{
+		"no principals":   {PublicKey: request.PublicKey},
+		"no public key":   {PublicKey: []byte("not a key"), Principals: []string{"alice"}},
+		"certificate":     {PublicKey: response.Certificate, Principals: []string{"alice"}},
+		"unknown option":  {PublicKey: request.PublicKey, Principals: []string{"alice"}, CriticalOptions: map[string]string{"unknown": "x"}},
+		"invalid address": {PublicKey: request.PublicKey, Principals: []string{"alice"}, CriticalOptions: map[string]string{"source-address": "10.0.0.0/33"}},
+		"validity":        {PublicKey: request.PublicKey, Principals: []string{"alice"}, Validity: "1 day"},
+	} {
+		_, err = ca.Sign(client.SSHUser, invalid)
+		assert.NotNil(t, err, name)
+	}
+
+	_, err = ca.Sign(client.SSHHost, client.APISSHRequest{PublicKey: request.PublicKey, Principals: []string{"web1"}, Extensions: map[string]string{"permit-pty": ""}})
+	assert.Equal(t, ErrSSHRequestInvalid, err)
+
+	_, err = ca.Sign("other", client.APISSHRequest{PublicKey: request.PublicKey, Principals: []string{"web1"}})
+	assert.Equal(t, ErrSSHRequestInvalid, err)
+
+}
+
+func TestSSHPolicy(t *testing.T) {
+
+	keyPEM, err := NewSSHCA()
+	assert.Nil(t, err)
+
+	ca, err := SSHFromBytes(keyPEM)
+	assert.Nil(t, err)
+	ca.SetBackdate(time.Hour)
+
+	policy := client.APIPolicy{
+		SSHMaxValidity:       "8h",
+		SSHAllowedPrincipals: []string{"deploy-*", "regex:^web[0-9]+$"},
+		SSHAllowedExtensions: []string{"permit-pty"},
+	}
+	assert.Nil(t, ValidatePolicy(policy))
+	ca.SetPolicy(policy)
+
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	sshPublic, err := ssh.NewPublicKey(public)
+	assert.Nil(t, err)
+
+	request := client.APISSHRequest{
+		PublicKey:  ssh.MarshalAuthorizedKey(sshPublic),
+		Principals: []string{"deploy-api", "web1"},
+		Validity:   "8h",
+		Extensions: map[string]string{"permit-pty": ""},
+	}
+
+	// the backdate is not part of the validity
+	_, err = ca.Sign(client.SSHUser, request)
+	assert.Nil(t, err)
+
+	for _, tc := range []struct {
+		rule   string
+		status int
+		change func(r *client.APISSHRequest)
+	}{
+		{client.PolicyRuleSSHAllowedPrincipals, http.StatusForbidden, func(r *client.APISSHRequest) { r.Principals = []string{"deploy-api", "root"} }},
+		{client.PolicyRuleSSHAllowedExtensions, http.StatusForbidden, func(r *client.APISSHRequest) {
+			r.Extensions = map[string]string{"permit-pty": "", "permit-agent-forwarding": ""}
+		}},
+		{client.PolicyRuleSSHAllowedExtensions, http.StatusForbidden, func(r *client.APISSHRequest) { r.Extensions = nil }},  // default ones
+		{client.PolicyRuleSSHMaxValidity, http.StatusUnprocessableEntity, func(r *client.APISSHRequest) { r.Validity = "" }}, // default 24h
+	} {
+		changed := request
+		tc.change(&changed)
+
+		_, err = ca.Sign(client.SSHUser, changed)
+		violation, ok := err.(*client.PolicyViolation)
+		if assert.True(t, ok, tc.rule) {
+			assert.Equal(t, tc.rule, violation.Rule)
+			assert.Equal(t, tc.status, violation.Status())
+		}
+	}
+
+	// must fail, invalid policies
+	for _, invalid := range []client.APIPolicy{
+		{SSHMaxValidity: "1 day"},
+		{SSHMaxValidity: "-1h"},
+		{SSHAllowedPrincipals: []string{"regex:("}},
+	} {
+		assert.Equal(t, ErrPolicyInvalid, ValidatePolicy(invalid))
+	}
+
+}
@@ -0,0 +1,48 @@
+package manager
+
+import (
+	"crypto/x509"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+)
+
+// Lifetime returns how long the certificate requested will be valid, validity (a duration) takes
+// precedence over the expiration days. Returns ErrValidityInvalid if validity cannot be parsed or
+// is not positive
+func Lifetime(request client.APICertificateRequest) (time.Duration, error) {
+
+	if request.Validity == "" {
+		return time.Duration(request.ExpirationDays*24) * time.Hour, nil
+	}
+
+	lifetime, err := time.ParseDuration(request.Validity)
+	if err != nil || lifetime <= 0 {
+		return 0, ErrValidityInvalid
+	}
+
+	return lifetime, nil
+
+}
+
+// SetBackdate sets how much NotBefore is set in the past on the certificates issued by the CA,
+// so clients with a skewed clock do not reject them as not valid yet
+func (c *CA) SetBackdate(backdate time.Duration) {
+
+	if backdate > 0 {
+		c.backdate = backdate
+	}
+
+}
+
+// setValidity backdates the certificate NotBefore and caps its NotAfter, a certificate cannot
+// outlive its issuer
+func (c *CA) setValidity(cert *x509.Certificate) {
+
+	cert.NotBefore = cert.NotBefore.Add(-c.backdate)
+
+	if cert.NotAfter.After(c.ca.NotAfter) {
+		cert.NotAfter = c.ca.NotAfter
+	}
+
+}
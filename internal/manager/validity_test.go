@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifetime(t *testing.T) {
+
+	lifetime, err := Lifetime(client.APICertificateRequest{ExpirationDays: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 48*time.Hour, lifetime)
+
+	// validity takes precedence
+	lifetime, err = Lifetime(client.APICertificateRequest{ExpirationDays: 2, Validity: "90m"})
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Minute, lifetime)
+
+	for _, validity := range []string{"6", "1d", "-1h", "0s"} {
+		_, err = Lifetime(client.APICertificateRequest{Validity: validity})
+		assert.Equal(t, ErrValidityInvalid, err, validity)
+	}
+
+}
+
+func TestValidity(t *testing.T) {
+
+	var caRequest client.APICertificateRequest
+
+	caRequest.DN.CN = "ca"
+	caRequest.ExpirationDays = 10
+	caRequest.Key = client.ECDSA256
+
+	caCert, caKey, err := New(caRequest)
+	assert.Nil(t, err)
+
+	ca, err := FromBytes(caCert, caKey)
+	assert.Nil(t, err)
+
+	ca.SetBackdate(5 * time.Minute)
+
+	request := client.APICertificateRequest{
+		DN:       client.APIDN{CN: "short"},
+		Key:      client.ECDSA256,
+		Validity: "6h",
+	}
+
+	now := time.Now()
+	certPEM, _, err := ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	cert, err := CertificateFromPEM(certPEM)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, now.Add(-5*time.Minute), cert.NotBefore, 2*time.Second)
+	assert.WithinDuration(t, now.Add(6*time.Hour), cert.NotAfter, 2*time.Second)
+
+	// renewals keep the validity
+	certPEM, _, err = ca.RenewCertificate(request, cert, nil, false)
+	assert.Nil(t, err)
+
+	cert, err = CertificateFromPEM(certPEM)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, now.Add(6*time.Hour), cert.NotAfter, 2*time.Second)
+
+	// leaves cannot outlive the CA
+	request.DN.CN = "long"
+	request.Validity = ""
+	request.ExpirationDays = 365
+
+	certPEM, _, err = ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	cert, err = CertificateFromPEM(certPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, ca.ca.NotAfter, cert.NotAfter)
+
+	// must fail, validity invalid
+	request.Validity = "6 hours"
+	_, _, err = ca.CreateCertificateFromAPI(request)
+	assert.Equal(t, ErrValidityInvalid, err)
+
+	// CSRs
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "external"},
+	}, key)
+	assert.Nil(t, err)
+
+	certPEM, apiRequest, err := ca.CreateCertificateFromCSR(client.APICSRRequest{CSR: pem.EncodeToMemory(&pem.Block{Type: FileCSR, Bytes: csrDER}), Validity: "30m"})
+	assert.Nil(t, err)
+	assert.Equal(t, "30m", apiRequest.Validity)
+
+	cert, err = CertificateFromPEM(certPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, 35*time.Minute, cert.NotAfter.Sub(cert.NotBefore))
+
+	// policy limits apply to the validity
+	ca.SetPolicy(client.APIPolicy{MaxExpirationDays: 1})
+
+	request.DN.CN = "policy"
+	request.Validity = "25h"
+	_, _, err = ca.CreateCertificateFromAPI(request)
+	assert.Equal(t, client.PolicyRuleMaxExp, err.(*client.PolicyViolation).Rule)
+
+	request.Validity = "23h"
+	_, _, err = ca.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+}
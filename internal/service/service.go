@@ -2,9 +2,13 @@ package service
 
 import (
 	"context"
-	"crypto"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fernandezvara/certsfor/db/store"
@@ -17,18 +21,33 @@ import (
 
 // Service is the struct used for every request
 type Service struct {
-	store   store.Store
-	client  *client.Client
-	server  bool
-	version string
+	store         store.Store
+	client        *client.Client
+	server        bool
+	version       string
+	crlNextUpdate time.Duration
+	ocspURL       string
+	ocspDelegated bool
+	backdate      time.Duration
+	kek           *manager.KEK
+	logMutex      sync.Mutex // serializes the issuance log appends
+	sshMutex      sync.Mutex // serializes the SSH CA keys creation
+	revokeMutex   sync.Mutex // serializes the revocations, a serial is revoked once
+	rolloverMutex sync.Mutex // serializes the CA rollovers
 }
 
+// defaults
+const (
+	DefaultCRLNextUpdate = 24 * time.Hour
+)
+
 // NewAsServer creates a Service instance that handles the store directly
 func NewAsServer(store store.Store, version string) *Service {
 	return &Service{
-		version: version,
-		store:   store,
-		server:  true,
+		version:       version,
+		store:         store,
+		server:        true,
+		crlNextUpdate: DefaultCRLNextUpdate,
 	}
 }
 
@@ -40,6 +59,32 @@ func NewAsClient(client *client.Client, version string) *Service {
 	}
 }
 
+// SetCRLNextUpdate sets the time until the next CRL will be issued, it is
+// used as next update on the CRLs (only applies as server)
+func (s *Service) SetCRLNextUpdate(nextUpdate time.Duration) {
+	if nextUpdate > 0 {
+		s.crlNextUpdate = nextUpdate
+	}
+}
+
+// SetOCSPURL sets the base URL where the API is reachable by the OCSP clients, when set
+// the certificates issued will carry the OCSP responder URL of its CA (only applies as server)
+func (s *Service) SetOCSPURL(url string) {
+	s.ocspURL = strings.TrimSuffix(url, "/")
+}
+
+// SetBackdate sets how much the NotBefore of the certificates issued is set in the past, so clients
+// with a slightly skewed clock accept them (only applies as server)
+func (s *Service) SetBackdate(backdate time.Duration) {
+	s.backdate = backdate
+}
+
+// SetOCSPDelegated sets if the OCSP responses are signed by a delegated OCSP signing
+// certificate instead of the CA key (only applies as server)
+func (s *Service) SetOCSPDelegated(delegated bool) {
+	s.ocspDelegated = delegated
+}
+
 // Server returns true when the library is used as server,
 func (s *Service) Server() bool {
 	return s.server
@@ -53,6 +98,61 @@ func (s *Service) Close() error {
 	return nil
 }
 
+// storeLock holds the lock of the name if the store is shared by several processes, as the files
+// store, returns the function that releases it
+func (s *Service) storeLock(ctx context.Context, name string) (func() error, error) {
+
+	if locker, ok := s.store.(store.Locker); ok {
+		return locker.Lock(ctx, name)
+	}
+
+	return func() error { return nil }, nil
+
+}
+
+// datasetItems unmarshals all the items of the dataset (revocations, profiles, log, ...) on values,
+// a pointer to a slice. Items are read one by one by its ID, GetAll is not used since some stores
+// only return certificates on it (firestore)
+func (s *Service) datasetItems(ctx context.Context, collection string, values interface{}) (err error) {
+
+	var (
+		ids   []string
+		items []store.Item
+		data  []byte
+	)
+
+	ids, err = s.store.IDs(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	items = make([]store.Item, 0, len(ids))
+
+	for _, id := range ids {
+
+		var item store.Item
+
+		err = s.store.Get(ctx, collection, id, &item)
+		if err == rest.ErrNotFound { // removed meanwhile
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		items = append(items, item)
+
+	}
+
+	data, err = json.Marshal(items)
+	if err != nil {
+		return
+	}
+
+	return json.Unmarshal(data, values)
+
+}
+
 // CACreate is responsible of create a new CA struct with its certificate returning its information
 func (s *Service) CACreate(ctx context.Context, request client.APICertificateRequest) (string, []byte, []byte, error) {
 
@@ -100,7 +200,17 @@ func (s *Service) caCreateServer(ctx context.Context, request client.APICertific
 	certificate.Key = key
 	certificate.Request = request
 
-	err = s.store.Set(ctx, id.String(), "ca", certificate)
+	err = s.saveCertificate(ctx, id.String(), "ca", certificate)
+	if err != nil {
+		return "", []byte{}, []byte{}, err
+	}
+
+	err = s.logCA(ctx, id.String(), certificate)
+	if err != nil {
+		return "", []byte{}, []byte{}, err
+	}
+
+	err = s.serialsMarkIndexed(ctx, id.String())
 	if err != nil {
 		return "", []byte{}, []byte{}, err
 	}
@@ -109,82 +219,267 @@ func (s *Service) caCreateServer(ctx context.Context, request client.APICertific
 
 }
 
-// CAGet creates a new CA struct from the collection ID
-func (s *Service) CAGet(collection string) (*manager.CA, error) {
+// IntermediateCreate creates a new subordinate CA signed by the CA on the collection,
+// the new CA will have its own ID
+func (s *Service) IntermediateCreate(ctx context.Context, collection string, request client.APICertificateRequest) (client.Certificate, error) {
+
+	if s.server {
+		return s.intermediateCreateServer(ctx, collection, request)
+	}
+
+	return s.client.IntermediateCreate(collection, request)
+
+}
+
+func (s *Service) intermediateCreateServer(ctx context.Context, collection string, request client.APICertificateRequest) (certificate client.Certificate, err error) {
 
 	var (
-		cert client.Certificate
-		err  error
+		parent    *manager.CA
+		parentCA  client.Certificate
+		cert, key []byte
+		id        uuid.UUID
 	)
 
-	cert, err = s.certificateGetAsServer(context.Background(), collection, "ca", 0)
+	parent, parentCA, err = s.caGet(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	err = s.setPolicy(ctx, collection, parent)
+	if err != nil {
+		return
+	}
+
+	cert, key, err = parent.NewIntermediate(request)
+	if err != nil {
+		return
+	}
+
+	id, err = uuid.NewRandom()
+	if err != nil {
+		return
+	}
+
+	certificate.Certificate = cert
+	certificate.Key = key
+	certificate.CACertificate = caChain(parentCA)
+	certificate.Request = request
+	certificate.ParentCAID = collection
+
+	err = s.saveCertificate(ctx, id.String(), "ca", certificate)
+	if err != nil {
+		return client.Certificate{}, err
+	}
+
+	err = s.inheritPolicy(ctx, collection, id.String())
+	if err != nil {
+		return client.Certificate{}, err
+	}
+
+	err = s.logCA(ctx, id.String(), certificate)
+	if err != nil {
+		return client.Certificate{}, err
+	}
+
+	err = s.serialsMarkIndexed(ctx, id.String())
+	if err != nil {
+		return client.Certificate{}, err
+	}
+
+	certificate.CAID = id.String()
+
+	return
+
+}
+
+// CAImport stores an existing CA with a new ID, so it can be used as the CAs created
+func (s *Service) CAImport(ctx context.Context, request client.APICAImportRequest) (client.Certificate, error) {
+
+	if s.server {
+		return s.caImportServer(ctx, request)
+	}
+
+	return s.client.CAImport(request)
+
+}
+
+func (s *Service) caImportServer(ctx context.Context, request client.APICAImportRequest) (certificate client.Certificate, err error) {
+
+	var (
+		x509Certificate *x509.Certificate
+		id              uuid.UUID
+	)
+
+	certificate.Certificate, certificate.Key, certificate.CACertificate, err = manager.ImportCA(request.Certificate, request.Key, request.Password)
+	if err != nil {
+		return
+	}
+
+	x509Certificate, err = manager.CertificateFromPEM(certificate.Certificate)
+	if err != nil {
+		return
+	}
+
+	certificate.Request = manager.CertificateToAPI(x509Certificate)
+
+	id, err = uuid.NewRandom()
+	if err != nil {
+		return
+	}
+
+	err = s.saveCertificate(ctx, id.String(), "ca", certificate)
+	if err != nil {
+		return client.Certificate{}, err
+	}
+
+	err = s.logCA(ctx, id.String(), certificate)
+	if err != nil {
+		return client.Certificate{}, err
+	}
+
+	err = s.serialsMarkIndexed(ctx, id.String())
 	if err != nil {
-		return nil, err
+		return client.Certificate{}, err
 	}
 
-	return manager.FromBytes(cert.Certificate, cert.Key)
+	// the key is already known by the requester
+	certificate.Key = nil
+	certificate.CAID = id.String()
+
+	return
+
+}
+
+// CAGet creates a new CA struct from the collection ID
+func (s *Service) CAGet(collection string) (*manager.CA, error) {
+
+	ca, _, err := s.caGet(context.Background(), collection)
+	return ca, err
+
+}
+
+// caGet returns the CA struct and the certificate as stored
+func (s *Service) caGet(ctx context.Context, collection string) (ca *manager.CA, caCertificate client.Certificate, err error) {
+
+	err = s.loadCertificate(ctx, collection, "ca", &caCertificate)
+	if err != nil {
+		return
+	}
+
+	ca, err = s.caFromCertificate(ctx, collection, caCertificate)
+
+	return
+
+}
+
+// caFromCertificate returns the CA struct for the CA stored, configured to issue certificates. Every
+// certificate it signs is appended to the CA issuance log
+func (s *Service) caFromCertificate(ctx context.Context, collection string, caCertificate client.Certificate) (ca *manager.CA, err error) {
+
+	ca, err = manager.FromBytes(caCertificate.Certificate, caCertificate.Key)
+	if err != nil {
+		return
+	}
+
+	err = ca.SetChain(caCertificate.CACertificate)
+	if err != nil {
+		return
+	}
+
+	if s.ocspURL != "" {
+		ca.SetOCSPServer(fmt.Sprintf("%s/v1/ca/%s/ocsp", s.ocspURL, collection))
+	}
+	ca.SetBackdate(s.backdate)
+	ca.SetLog(func(certificate []byte) error {
+		return s.logAppend(ctx, collection, ca, pem.EncodeToMemory(&pem.Block{Type: manager.FileCertificate, Bytes: certificate}))
+	})
+
+	return
+
+}
+
+// caChain returns the PEM chain of a stored CA, from the CA itself to the root
+//
+// for CAs, `CACertificate` holds the chain of its issuer (empty on roots)
+func caChain(caCertificate client.Certificate) []byte {
+
+	return append(append([]byte{}, caCertificate.Certificate...), caCertificate.CACertificate...)
 
 }
 
 // CertificateGet returns the certificate and its key information
 func (s *Service) CertificateGet(ctx context.Context, collection, id string, remaining int) (client.Certificate, error) {
 
+	return s.CertificateRenew(ctx, collection, id, remaining, false)
+
+}
+
+// CertificateRenew returns the certificate and its key information, renewed if its remaining lifetime
+// (percent) is lower than remaining. If rekey is true (or the certificate renewal policy is rekey) the
+// renewed certificate will have a new key. Rekeying without remaining percent renews the certificate now
+func (s *Service) CertificateRenew(ctx context.Context, collection, id string, remaining int, rekey bool) (client.Certificate, error) {
+
 	if s.server {
-		return s.certificateGetAsServer(ctx, collection, id, remaining)
+		return s.certificateGetAsServer(ctx, collection, id, remaining, rekey)
 	}
 
-	return s.certificateGetAsClient(ctx, collection, id, remaining)
+	return s.certificateGetAsClient(ctx, collection, id, remaining, rekey)
 
 }
 
-func (s *Service) certificateGetAsServer(ctx context.Context, collection, id string, remaining int) (certificate client.Certificate, err error) {
+func (s *Service) certificateGetAsServer(ctx context.Context, collection, id string, remaining int, rekey bool) (certificate client.Certificate, err error) {
 
 	var (
 		caCertificate client.Certificate
 	)
 
 	// get the CA
-	err = s.store.Get(ctx, collection, "ca", &caCertificate)
+	err = s.loadCertificate(ctx, collection, "ca", &caCertificate)
 	if err != nil {
 		return
 	}
 
-	err = s.store.Get(ctx, collection, id, &certificate)
+	err = s.loadCertificate(ctx, collection, id, &certificate)
 	if err != nil {
 		return
 	}
 
 	certificate.X509Certificate, err = manager.CertificateFromPEM(certificate.Certificate)
-	certificate.CACertificate = caCertificate.Certificate
+	certificate.CACertificate = caChain(caCertificate)
 
-	if remaining > 0 {
-		if s.IsNearToExpire(certificate, remaining) {
+	if rekey && remaining <= 0 {
+		remaining = 100
+	}
+
+	// CA certificates cannot be renewed signing them as leaves, revoked ones must not be renewed
+	if remaining > 0 && id != "ca" {
+		if s.IsNearToExpire(certificate, remaining) && !s.isRevoked(ctx, collection, manager.SerialToString(certificate.X509Certificate.SerialNumber)) {
 
-			var (
-				ca             *manager.CA
-				key            crypto.PrivateKey
-				newCertificate *x509.Certificate
-			)
+			var ca *manager.CA
 
-			ca, err = manager.FromBytes(caCertificate.Certificate, caCertificate.Key)
+			ca, err = s.caFromCertificate(ctx, collection, caCertificate)
 			if err != nil {
 				return
 			}
 
-			// get current Key
-			key, err = manager.PrivateKeyFromPEM(certificate.Key)
+			err = s.setPolicy(ctx, collection, ca)
 			if err != nil {
 				return
 			}
 
-			newCertificate = manager.APITox509Certificate(certificate.Request)
+			rekey = rekey || certificate.Request.Renewal == client.RenewalRekey
 
-			certificate.Certificate, _, err = ca.CreateCertificate(newCertificate, key)
+			certificate.Certificate, certificate.Key, err = ca.RenewCertificate(certificate.Request, certificate.X509Certificate, certificate.Key, rekey)
 			if err != nil {
 				return
 			}
 
-			err = s.store.Set(ctx, collection, id, certificate)
+			err = s.certificateStore(ctx, collection, id, certificate)
+			if err != nil {
+				return
+			}
+
+			certificate.X509Certificate, err = manager.CertificateFromPEM(certificate.Certificate)
 
 		}
 
@@ -194,30 +489,36 @@ func (s *Service) certificateGetAsServer(ctx context.Context, collection, id str
 
 }
 
-func (s *Service) certificateGetAsClient(ctx context.Context, collection, id string, remaining int) (certificate client.Certificate, err error) {
+func (s *Service) certificateGetAsClient(ctx context.Context, collection, id string, remaining int, rekey bool) (certificate client.Certificate, err error) {
 
 	// api must have a ?renew=20 to return the certificate autorenewed in the API!
-	certificate, err = s.client.CertificateGet(collection, id, remaining)
+	certificate, err = s.client.CertificateRenew(collection, id, remaining, rekey)
 	return
 
 }
 
-// IsNearToExpire returns true if certificate is already expired or remaining days are less than (percent/100)
+// IsNearToExpire returns true if certificate is already expired or its remaining lifetime is less
+// than percent of its whole lifetime. A percent of 100 (or more) is always near to expire
 func (s *Service) IsNearToExpire(certificate client.Certificate, percent int) bool {
 
 	var (
-		remainingDays    int64
-		maxRemainingDays int64
+		lifetime  time.Duration
+		remaining time.Duration
 	)
 
-	maxRemainingDays = certificate.Request.ExpirationDays * (int64(percent) / 100)
-	remainingDays = int64(certificate.X509Certificate.NotAfter.Sub(time.Now()).Hours()) / 24
+	if percent >= 100 {
+		return true
+	}
+
+	lifetime = certificate.X509Certificate.NotAfter.Sub(certificate.X509Certificate.NotBefore)
+	remaining = time.Until(certificate.X509Certificate.NotAfter)
 
-	return remainingDays < maxRemainingDays
+	return remaining < lifetime/100*time.Duration(percent)
 
 }
 
-// CertificateSet creates a new certificate and stores in the store (if server) or POST to the API
+// CertificateSet creates a new certificate and stores in the store (if server) or POST to the API.
+// Requests that do not meet the CA issuance policy are rejected with a *client.PolicyViolation
 func (s *Service) CertificateSet(ctx context.Context, collection string, request client.APICertificateRequest) ([]byte, []byte, []byte, error) {
 
 	if s.server {
@@ -243,18 +544,31 @@ func (s *Service) certificateSetAsClient(ctx context.Context, collection string,
 func (s *Service) certificateSetAsServer(ctx context.Context, collection string, request client.APICertificateRequest) ([]byte, []byte, []byte, error) {
 
 	var (
-		certificate client.Certificate
-		ca          *manager.CA
-		err         error
+		certificate   client.Certificate
+		caCertificate client.Certificate
+		ca            *manager.CA
+		err           error
 	)
 
-	ca, err = s.CAGet(collection)
+	ca, caCertificate, err = s.caGet(ctx, collection)
 	if err != nil {
 		return []byte{}, []byte{}, []byte{}, err
 	}
 
-	if request.DN.CN == ca.CACertificate().Issuer.CommonName {
-		return []byte{}, []byte{}, []byte{}, rest.ErrConflict
+	err = reservedID(ca, request.DN.CN)
+	if err != nil {
+		return []byte{}, []byte{}, []byte{}, err
+	}
+
+	// the request is stored with the profile applied, so renewals do not depend on it
+	request, err = s.applyProfile(ctx, collection, request)
+	if err != nil {
+		return []byte{}, []byte{}, []byte{}, err
+	}
+
+	err = s.setPolicy(ctx, collection, ca)
+	if err != nil {
+		return []byte{}, []byte{}, []byte{}, err
 	}
 
 	certificate.Certificate, certificate.Key, err = ca.CreateCertificateFromAPI(request)
@@ -264,12 +578,81 @@ func (s *Service) certificateSetAsServer(ctx context.Context, collection string,
 
 	certificate.Request = request
 
-	err = s.store.Set(ctx, collection, request.DN.CN, certificate)
+	err = s.certificateStore(ctx, collection, request.DN.CN, certificate)
 	if err != nil {
 		return []byte{}, []byte{}, []byte{}, err
 	}
 
-	return ca.CACertificateBytes(), certificate.Certificate, certificate.Key, err
+	return caChain(caCertificate), certificate.Certificate, certificate.Key, err
+}
+
+// reservedID returns rest.ErrConflict if a certificate stored as id would replace the CA: ids are
+// the certificate CN and the CA is stored as `ca`
+func reservedID(ca *manager.CA, id string) error {
+
+	if id == "ca" || id == ca.CACertificate().Subject.CommonName {
+		return rest.ErrConflict
+	}
+
+	return nil
+
+}
+
+// CertificateSignCSR signs a certificate signing request, only the certificate is stored
+// since its private key is never known
+func (s *Service) CertificateSignCSR(ctx context.Context, collection string, request client.APICSRRequest) (client.Certificate, error) {
+
+	if s.server {
+		return s.certificateSignCSRAsServer(ctx, collection, request)
+	}
+
+	return s.client.CertificateSignCSR(collection, request)
+
+}
+
+func (s *Service) certificateSignCSRAsServer(ctx context.Context, collection string, request client.APICSRRequest) (certificate client.Certificate, err error) {
+
+	var (
+		caCertificate client.Certificate
+		ca            *manager.CA
+		csr           *x509.CertificateRequest
+	)
+
+	ca, caCertificate, err = s.caGet(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	// the certificate is stored by its CN, so it is checked before anything is signed
+	csr, err = manager.CSRFromPEM(request.CSR)
+	if err != nil {
+		return
+	}
+
+	err = reservedID(ca, manager.CSRToAPI(csr).DN.CN)
+	if err != nil {
+		return
+	}
+
+	err = s.setPolicy(ctx, collection, ca)
+	if err != nil {
+		return
+	}
+
+	certificate.Certificate, certificate.Request, err = ca.CreateCertificateFromCSR(request)
+	if err != nil {
+		return client.Certificate{}, err
+	}
+
+	err = s.certificateStore(ctx, collection, certificate.Request.DN.CN, certificate)
+	if err != nil {
+		return client.Certificate{}, err
+	}
+
+	certificate.CACertificate = caChain(caCertificate)
+
+	return
+
 }
 
 // CertificateList returns an array of certificates and its x509 representation
@@ -320,6 +703,11 @@ func (s *Service) certificateListAsServer(ctx context.Context, collection string
 			return
 		}
 
+		err = s.openKey(&certificate)
+		if err != nil {
+			return
+		}
+
 		certificate.X509Certificate, err = manager.CertificateFromPEM(certificate.Certificate)
 		if err != nil {
 			return
@@ -336,7 +724,7 @@ func (s *Service) certificateListAsServer(ctx context.Context, collection string
 func (s *Service) CertificateDelete(ctx context.Context, collection, cn string) (ok bool, err error) {
 
 	if s.server {
-		if cn == "ca" {
+		if cn == "ca" { // CAs are removed with CADelete
 			err = rest.ErrConflict
 			return
 		}
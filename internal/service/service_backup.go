@@ -0,0 +1,417 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/fernandezvara/certsfor/db/store"
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+)
+
+// errors
+var (
+	ErrBackupInvalid      = errors.New("backup is invalid")
+	ErrBackupKeyReference = errors.New("keys held by signer plugins cannot be backed up, import the CA again")
+)
+
+// backupVersion is the format of the backups, increased on incompatible changes
+const backupVersion = 1
+
+// backup is the content of a CA backup: every item of the CA collections (certificates and their
+// requests, revocations, issuance log, profiles, policy, ...) by collection and ID
+type backup struct {
+	Version     int                              `json:"version"`
+	CAID        string                           `json:"ca_id"`
+	Created     int64                            `json:"created"` // unix time in milliseconds
+	CFDVersion  string                           `json:"cfd_version"`
+	Collections map[string]map[string]store.Item `json:"collections"`
+}
+
+// backupItem is the location of an item restored from a backup
+type backupItem struct {
+	collection string
+	id         string
+}
+
+// BackupExport returns the CA and all its data as a backup encrypted with the passphrase. Keys are
+// decrypted with the KEK, so the backup can be restored knowing only its passphrase
+func (s *Service) BackupExport(ctx context.Context, collection, passphrase string) (sealed []byte, err error) {
+
+	var (
+		content     backup
+		collections []string
+		ca          client.Certificate
+		archive     bytes.Buffer
+		writer      *gzip.Writer = gzip.NewWriter(&archive)
+	)
+
+	if !s.server {
+		return []byte{}, ErrServerOnly
+	}
+
+	// must exist
+	err = s.store.Get(ctx, collection, "ca", &ca)
+	if err != nil {
+		return
+	}
+
+	collections, err = s.store.Collections(ctx)
+	if err != nil {
+		return
+	}
+
+	content.Version = backupVersion
+	content.CAID = collection
+	content.Created = time.Now().UnixNano() / int64(time.Millisecond)
+	content.CFDVersion = s.version
+	content.Collections = make(map[string]map[string]store.Item)
+
+	for _, name := range collections {
+
+		var ids []string
+
+		if !caCollection(collection, name) {
+			continue
+		}
+
+		ids, err = s.store.IDs(ctx, name)
+		if err != nil {
+			return
+		}
+
+		content.Collections[name] = make(map[string]store.Item)
+
+		for _, id := range ids {
+
+			var value store.Item
+
+			err = s.store.Get(ctx, name, id, &value)
+			if err != nil {
+				return
+			}
+
+			err = s.backupKey(value, func(certificate *client.Certificate) (err error) {
+				err = s.openKey(certificate)
+				if err == nil && manager.IsKeyReference(certificate.Key) {
+					err = ErrBackupKeyReference
+				}
+				return
+			})
+			if err != nil {
+				return
+			}
+
+			content.Collections[name][id] = value
+
+		}
+
+	}
+
+	err = json.NewEncoder(writer).Encode(content)
+	if err != nil {
+		return
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return
+	}
+
+	return manager.SealBackup(passphrase, archive.Bytes())
+
+}
+
+// BackupImport restores a CA from a backup encrypted with the passphrase, keys are encrypted with
+// the KEK if it is set. The CA keeps its ID, it must not exist. Returns the CA ID. The CA certificate
+// is written last and, if anything fails, the items already written are removed so the import can
+// be retried
+func (s *Service) BackupImport(ctx context.Context, sealed []byte, passphrase string) (caID string, err error) {
+
+	var (
+		content     backup
+		archive     []byte
+		reader      *gzip.Reader
+		collections []string
+		written     []backupItem
+		unlock      func() error
+	)
+
+	if !s.server {
+		return "", ErrServerOnly
+	}
+
+	archive, err = manager.OpenBackup(passphrase, sealed)
+	if err != nil {
+		return
+	}
+
+	reader, err = gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return "", ErrBackupInvalid
+	}
+
+	archive, err = ioutil.ReadAll(reader)
+	if err != nil {
+		return "", ErrBackupInvalid
+	}
+
+	err = json.Unmarshal(archive, &content)
+	if err != nil {
+		return "", ErrBackupInvalid
+	}
+
+	err = validateBackup(content)
+	if err != nil {
+		return
+	}
+
+	// keys must be restored as are, a key reference would run the signer plugin it names
+	for _, items := range content.Collections {
+		for _, value := range items {
+			err = s.backupKey(value, func(certificate *client.Certificate) error {
+				if manager.IsKeyReference(certificate.Key) {
+					return ErrBackupKeyReference
+				}
+				return nil
+			})
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	// backups taken before the serial number index existed are indexed as are restored
+	if _, ok := content.Collections[metaCollection(content.CAID)][serialsIndexed]; !ok {
+		err = backupIndex(&content)
+		if err != nil {
+			return
+		}
+	}
+
+	// concurrent imports of the same CA (processes sharing the store)
+	unlock, err = s.storeLock(ctx, content.CAID)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	collections, err = s.store.Collections(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, name := range collections {
+		if caCollection(content.CAID, name) {
+			return "", rest.ErrConflict
+		}
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, item := range written {
+			s.store.Delete(ctx, item.collection, item.id)
+		}
+	}()
+
+	names := make([]string, 0, len(content.Collections))
+	for name := range content.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for id, value := range content.Collections[name] {
+
+			// the CA goes last, it is not listed until all its data is restored
+			if name == content.CAID && id == "ca" {
+				continue
+			}
+
+			err = s.backupRestore(ctx, name, id, value)
+			if err != nil {
+				return
+			}
+
+			written = append(written, backupItem{collection: name, id: id})
+
+		}
+	}
+
+	err = s.backupRestore(ctx, content.CAID, "ca", content.Collections[content.CAID]["ca"])
+	if err != nil {
+		return
+	}
+
+	return content.CAID, nil
+
+}
+
+// backupIndex adds the certificates of the backup to the serial number index of the CA
+func backupIndex(content *backup) (err error) {
+
+	var (
+		data    []byte
+		entries = make(map[string]store.Item)
+	)
+
+	// serials already indexed are kept, certificates renewed do not hold them anymore
+	for serial, entry := range content.Collections[serialsCollection(content.CAID)] {
+		entries[serial] = entry
+	}
+
+	for id, value := range content.Collections[content.CAID] {
+
+		var (
+			certificate client.Certificate
+			cert        *x509.Certificate
+			entry       store.Item
+		)
+
+		if id == "ca" {
+			continue
+		}
+
+		data, err = json.Marshal(value)
+		if err != nil {
+			return
+		}
+
+		err = json.Unmarshal(data, &certificate)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrBackupInvalid, err)
+		}
+
+		cert, err = manager.CertificateFromPEM(certificate.Certificate)
+		if err != nil {
+			return fmt.Errorf("%w: certificate %s: %s", ErrBackupInvalid, id, err)
+		}
+
+		if cert.IsCA {
+			continue
+		}
+
+		data, err = json.Marshal(newSerialEntry(id, cert))
+		if err != nil {
+			return
+		}
+
+		err = json.Unmarshal(data, &entry)
+		if err != nil {
+			return
+		}
+
+		entries[manager.SerialToString(cert.SerialNumber)] = entry
+
+	}
+
+	content.Collections[serialsCollection(content.CAID)] = entries
+	content.Collections[metaCollection(content.CAID)] = map[string]store.Item{
+		serialsIndexed: {"time": time.Now().UnixNano() / int64(time.Millisecond)},
+	}
+
+	return
+
+}
+
+// backupRestore writes the backup item, its key (if any) is encrypted with the KEK
+func (s *Service) backupRestore(ctx context.Context, collection, id string, value store.Item) (err error) {
+
+	err = s.backupKey(value, func(certificate *client.Certificate) (err error) {
+		certificate.Key, err = sealKey(s.kek, certificate.Key)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	return s.store.Set(ctx, collection, id, value)
+
+}
+
+// backupKey applies fn to the key of the item, if it has one (certificates of the CA, OCSP responder,
+// SSH CA, ...)
+func (s *Service) backupKey(value store.Item, fn func(certificate *client.Certificate) error) (err error) {
+
+	var (
+		encoded     string
+		ok          bool
+		certificate client.Certificate
+	)
+
+	encoded, ok = value["key"].(string)
+	if !ok || encoded == "" {
+		return
+	}
+
+	certificate.Key, err = base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: key is not base64", ErrBackupInvalid)
+	}
+
+	err = fn(&certificate)
+	if err != nil {
+		return
+	}
+
+	value["key"] = base64.StdEncoding.EncodeToString(certificate.Key)
+
+	return
+
+}
+
+// validateBackup checks the backup holds a CA and only its collections
+func validateBackup(content backup) error {
+
+	var (
+		data []byte
+		ca   client.Certificate
+		cert *x509.Certificate
+		err  error
+	)
+
+	if content.Version != backupVersion {
+		return fmt.Errorf("%w: version %d is not supported", ErrBackupInvalid, content.Version)
+	}
+
+	for name := range content.Collections {
+		if content.CAID == "" || !caCollection(content.CAID, name) {
+			return fmt.Errorf("%w: collection %s does not belong to the CA", ErrBackupInvalid, name)
+		}
+	}
+
+	data, err = json.Marshal(content.Collections[content.CAID]["ca"])
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, &ca)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBackupInvalid, err)
+	}
+
+	block, _ := pem.Decode(ca.Certificate)
+	if block != nil {
+		cert, err = x509.ParseCertificate(block.Bytes)
+	}
+
+	if block == nil || err != nil || !cert.IsCA {
+		return fmt.Errorf("%w: CA certificate not found", ErrBackupInvalid)
+	}
+
+	return nil
+
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+)
+
+// errors
+var (
+	ErrIntermediatesExist = errors.New("the CA has intermediate CAs, delete them first")
+)
+
+// caCollection returns true if the collection holds data of the CA, its certificates or any of its
+// datasets (revocations, profiles, log, ...)
+func caCollection(caID, collection string) bool {
+	return collection == caID || strings.HasPrefix(collection, caID+".")
+}
+
+// CAList returns the information of all the CAs on the store, sorted by common name
+func (s *Service) CAList(ctx context.Context) ([]client.APICA, error) {
+
+	if s.server {
+		return s.caListAsServer(ctx)
+	}
+
+	return s.client.CAList()
+
+}
+
+func (s *Service) caListAsServer(ctx context.Context) (cas []client.APICA, err error) {
+
+	var (
+		collections []string
+	)
+
+	cas = []client.APICA{}
+
+	collections, err = s.store.Collections(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, collection := range collections {
+
+		var ca client.APICA
+
+		// CA datasets are named <caid>.<dataset>
+		if strings.Contains(collection, ".") {
+			continue
+		}
+
+		ca, err = s.caInfoAsServer(ctx, collection, false)
+		if err == rest.ErrNotFound { // not a CA
+			err = nil
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		cas = append(cas, ca)
+
+	}
+
+	sort.Slice(cas, func(i, j int) bool {
+		if cas[i].CN == cas[j].CN {
+			return cas[i].CAID < cas[j].CAID
+		}
+		return cas[i].CN < cas[j].CN
+	})
+
+	return
+
+}
+
+// CAInfo returns the information of the CA with its certificate
+func (s *Service) CAInfo(ctx context.Context, collection string) (client.APICA, error) {
+
+	if s.server {
+		return s.caInfoAsServer(ctx, collection, true)
+	}
+
+	return s.client.CAInfo(collection)
+
+}
+
+func (s *Service) caInfoAsServer(ctx context.Context, collection string, details bool) (ca client.APICA, err error) {
+
+	var (
+		caCertificate client.Certificate
+		previous      client.Certificate
+		revocations   []client.Revocation
+		ids           []string
+	)
+
+	// the key is not needed
+	err = s.store.Get(ctx, collection, "ca", &caCertificate)
+	if err != nil {
+		return
+	}
+
+	caCertificate.X509Certificate, err = manager.CertificateFromPEM(caCertificate.Certificate)
+	if err != nil {
+		return
+	}
+
+	ids, err = s.store.IDs(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	revocations, err = s.revocations(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	ca.CAID = collection
+	ca.ParentCAID = caCertificate.ParentCAID
+	ca.CN = caCertificate.X509Certificate.Subject.CommonName
+	ca.Subject = caCertificate.X509Certificate.Subject.String()
+	ca.Serial = manager.SerialToString(caCertificate.X509Certificate.SerialNumber)
+	ca.NotBefore = caCertificate.X509Certificate.NotBefore
+	ca.NotAfter = caCertificate.X509Certificate.NotAfter
+	ca.Certificates = len(ids) - 1 // without the CA itself
+	ca.Revoked = len(revocations)
+	ca.Rollover = s.store.Get(ctx, rolloverCollection(collection), "previous", &previous) == nil
+
+	if details {
+		ca.Certificate = caCertificate.Certificate
+		ca.Chain = caCertificate.CACertificate
+	}
+
+	return
+
+}
+
+// CADelete removes the CA from the store, with all the certificates it issued and its datasets. CAs
+// with intermediates are not removed, since the intermediates would lose their parent
+func (s *Service) CADelete(ctx context.Context, collection string) (bool, error) {
+
+	if s.server {
+		return s.caDeleteAsServer(ctx, collection)
+	}
+
+	return s.client.CADelete(collection)
+
+}
+
+func (s *Service) caDeleteAsServer(ctx context.Context, collection string) (ok bool, err error) {
+
+	var (
+		caCertificate client.Certificate
+		cas           []client.APICA
+		collections   []string
+	)
+
+	// must exist
+	err = s.store.Get(ctx, collection, "ca", &caCertificate)
+	if err != nil {
+		return
+	}
+
+	cas, err = s.caListAsServer(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, ca := range cas {
+		if ca.ParentCAID == collection {
+			err = ErrIntermediatesExist
+			return
+		}
+	}
+
+	collections, err = s.store.Collections(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, name := range collections {
+
+		var ids []string
+
+		if !caCollection(collection, name) {
+			continue
+		}
+
+		ids, err = s.store.IDs(ctx, name)
+		if err != nil {
+			return
+		}
+
+		for _, id := range ids {
+
+			// the CA goes last, so it can be found again if something fails
+			if name == collection && id == "ca" {
+				continue
+			}
+
+			_, err = s.store.Delete(ctx, name, id)
+			if err != nil {
+				return
+			}
+
+		}
+
+	}
+
+	return s.store.Delete(ctx, collection, "ca")
+
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+)
+
+// errors
+var (
+	ErrServerOnly             = errors.New("operation only allowed with direct access to the store")
+	ErrKeyReferenceNotAllowed = errors.New("keys held by signer plugins can only be imported with direct access to the store")
+)
+
+// storeCollection holds the metadata of the store, it is not a CA
+const storeCollection = "cfd.store"
+
+// kekMetadata is the store metadata of the keys encryption, every store has its own salt to
+// derive the KEKs from passphrases
+type kekMetadata struct {
+	Salt []byte `json:"salt"`
+}
+
+// KEKFromPassphrase returns the KEK derived from the passphrase with the salt of the store, a random
+// salt is created and kept with the store metadata the first time. The salt is written on the header
+// of every key encrypted too, so the KEK can be derived again from any of them if the metadata is lost
+func (s *Service) KEKFromPassphrase(ctx context.Context, passphrase string) (*manager.KEK, error) {
+
+	if !s.server {
+		return nil, ErrServerOnly
+	}
+
+	salt, err := s.kekSalt(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.KEKFromPassphrase(passphrase, salt)
+
+}
+
+// kekSalt returns the salt of the store, created if it does not exist yet
+func (s *Service) kekSalt(ctx context.Context) (salt []byte, err error) {
+
+	var (
+		metadata kekMetadata
+		unlock   func() error
+	)
+
+	unlock, err = s.storeLock(ctx, storeCollection)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = s.store.Get(ctx, storeCollection, "kek", &metadata)
+	switch err {
+	case nil:
+		return metadata.Salt, nil
+	case rest.ErrNotFound:
+	default:
+		return
+	}
+
+	metadata.Salt, err = manager.NewKEKSalt()
+	if err != nil {
+		return
+	}
+
+	err = s.store.Set(ctx, storeCollection, "kek", metadata)
+	if err != nil {
+		return
+	}
+
+	return metadata.Salt, nil
+
+}
+
+// SetKEK sets the key encryption key used to encrypt the private keys at rest (only applies as server).
+// Keys stored without encryption can still be read, they are encrypted once written again
+func (s *Service) SetKEK(kek *manager.KEK) {
+	s.kek = kek
+}
+
+// loadCertificate reads a certificate from the store, its key is decrypted if needed
+func (s *Service) loadCertificate(ctx context.Context, collection, id string, certificate *client.Certificate) (err error) {
+
+	err = s.store.Get(ctx, collection, id, certificate)
+	if err != nil {
+		return
+	}
+
+	return s.openKey(certificate)
+
+}
+
+// saveCertificate writes a certificate to the store, its key is encrypted if a KEK is set
+func (s *Service) saveCertificate(ctx context.Context, collection, id string, certificate client.Certificate) (err error) {
+
+	certificate.Key, err = sealKey(s.kek, certificate.Key)
+	if err != nil {
+		return
+	}
+
+	return s.store.Set(ctx, collection, id, certificate)
+
+}
+
+// openKey decrypts the certificate key if it is encrypted
+func (s *Service) openKey(certificate *client.Certificate) (err error) {
+
+	if !manager.IsEncryptedKey(certificate.Key) {
+		return
+	}
+
+	if s.kek == nil {
+		return manager.ErrKEKRequired
+	}
+
+	certificate.Key, err = s.kek.Decrypt(certificate.Key)
+
+	return
+
+}
+
+// sealKey encrypts the key with the KEK (if any), already encrypted keys are returned as are
+func sealKey(kek *manager.KEK, key []byte) ([]byte, error) {
+
+	if kek == nil || len(key) == 0 || manager.IsEncryptedKey(key) {
+		return key, nil
+	}
+
+	return kek.Encrypt(key)
+
+}
+
+// storedCertificate is a certificate with its location on the store
+type storedCertificate struct {
+	collection  string
+	id          string
+	certificate client.Certificate
+}
+
+// RekeyStore encrypts again every key of the CAs with a new KEK, keys stored without encryption
+// are encrypted too. If newKEK is nil keys are stored decrypted. Returns the number of keys rewritten
+//
+// every key is decrypted before writing any of them, keys already encrypted with the new KEK are kept
+// as are, so if it fails while writing it can be run again with the same new KEK to finish it
+func (s *Service) RekeyStore(ctx context.Context, collections []string, newKEK *manager.KEK) (count int, err error) {
+
+	var stored []storedCertificate
+
+	if !s.server {
+		return 0, ErrServerOnly
+	}
+
+	for _, collection := range collections {
+
+		var ids []string
+
+		// must exist
+		err = s.store.Get(ctx, collection, "ca", &client.Certificate{})
+		if err != nil {
+			return
+		}
+
+		ids, err = s.store.IDs(ctx, collection)
+		if err != nil {
+			return
+		}
+
+		locations := []storedCertificate{}
+		for _, id := range ids {
+			locations = append(locations, storedCertificate{collection: collection, id: id})
+		}
+
+		// ocsp responders, ssh CA and previous CA while a rollover is in progress
+		locations = append(locations,
+			storedCertificate{collection: ocspCollection(collection), id: ocspCurrentResponder},
+			storedCertificate{collection: ocspCollection(collection), id: ocspPreviousResponder},
+			storedCertificate{collection: sshCollection(collection), id: "ca"},
+			storedCertificate{collection: rolloverCollection(collection), id: "previous"},
+		)
+
+		for _, location := range locations {
+
+			var rekey bool
+
+			rekey, err = s.loadForRekey(ctx, location.collection, location.id, &location.certificate, newKEK)
+			switch {
+			case err == rest.ErrNotFound:
+				err = nil
+			case err != nil:
+				return
+			case rekey:
+				stored = append(stored, location)
+			}
+
+		}
+
+	}
+
+	for _, item := range stored {
+
+		item.certificate.Key, err = sealKey(newKEK, item.certificate.Key)
+		if err != nil {
+			return
+		}
+
+		err = s.store.Set(ctx, item.collection, item.id, item.certificate)
+		if err != nil {
+			return
+		}
+
+		count++
+
+	}
+
+	s.kek = newKEK
+
+	return
+
+}
+
+// loadForRekey reads the certificate with its key decrypted, returns false if its key does not need
+// to be written again: there is no key or it is already encrypted with the new KEK
+func (s *Service) loadForRekey(ctx context.Context, collection, id string, certificate *client.Certificate, newKEK *manager.KEK) (bool, error) {
+
+	err := s.store.Get(ctx, collection, id, certificate)
+	if err != nil {
+		return false, err
+	}
+
+	if len(certificate.Key) == 0 {
+		return false, nil
+	}
+
+	if manager.IsEncryptedKey(certificate.Key) {
+		kekID, _, err := manager.EncryptedKeyKEK(certificate.Key)
+		if err != nil {
+			return false, err
+		}
+		if newKEK != nil && kekID == newKEK.ID() {
+			return false, nil
+		}
+	} else if newKEK == nil {
+		return false, nil
+	}
+
+	return true, s.openKey(certificate)
+
+}
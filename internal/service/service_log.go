@@ -0,0 +1,512 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/certsfor/pkg/merkle"
+	"github.com/fernandezvara/rest"
+)
+
+// errors
+var (
+	ErrLogInconsistent = errors.New("issuance log does not match its signed tree head, run log verify")
+)
+
+// logCollection returns the collection where the issuance log entries of a CA are stored
+func logCollection(collection string) string {
+	return collection + ".log"
+}
+
+// logHeadCollection returns the collection where the signed tree head of the issuance log of a CA is stored
+func logHeadCollection(collection string) string {
+	return collection + ".sth"
+}
+
+// logID returns the id of the log entry, zero padded so entries are sorted by index
+func logID(index uint64) string {
+	return fmt.Sprintf("%020d", index)
+}
+
+// logState is the signed tree head item, with the frontier of the tree (the roots of its perfect
+// subtrees) so entries are appended without reading the whole log. Both are written at once
+type logState struct {
+	client.APISignedTreeHead
+	Frontier [][]byte `json:"frontier,omitempty"`
+}
+
+// logAppend appends the certificates (PEM) to the issuance log of the CA and signs the new tree head
+// with the CA, appends are serialized (also between processes sharing the store) so the tree head
+// always covers every entry
+func (s *Service) logAppend(ctx context.Context, collection string, ca *manager.CA, certificates ...[]byte) (err error) {
+
+	var (
+		state  logState
+		unlock func() error
+	)
+
+	s.logMutex.Lock()
+	defer s.logMutex.Unlock()
+
+	unlock, err = s.storeLock(ctx, logCollection(collection))
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	state, err = s.logState(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	for _, certPEM := range certificates {
+		for block, remaining := pem.Decode(certPEM); block != nil; block, remaining = pem.Decode(remaining) {
+
+			var (
+				cert  *x509.Certificate
+				entry client.APILogEntry
+				leaf  []byte
+			)
+
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return
+			}
+
+			// entries written after the tree head (a failed append) are overwritten
+			entry.Index = state.TreeSize
+			entry.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+			entry.Serial = manager.SerialToString(cert.SerialNumber)
+			entry.Certificate = pem.EncodeToMemory(block)
+
+			leaf, err = manager.LogLeafHash(entry)
+			if err != nil {
+				return
+			}
+
+			err = s.store.Set(ctx, logCollection(collection), logID(entry.Index), entry)
+			if err != nil {
+				return
+			}
+
+			state.Frontier = merkle.AppendFrontier(state.Frontier, state.TreeSize, leaf)
+			state.TreeSize++
+			state.Timestamp = entry.Timestamp
+
+		}
+	}
+
+	state.RootHash = merkle.FrontierRootHash(state.Frontier)
+
+	err = ca.SignTreeHead(&state.APISignedTreeHead)
+	if err != nil {
+		return
+	}
+
+	return s.store.Set(ctx, logHeadCollection(collection), "head", state)
+
+}
+
+// logState returns the signed tree head of the issuance log of the CA with its frontier. Tree heads
+// stored without the frontier get it from the entries (once, it is stored on the next append)
+func (s *Service) logState(ctx context.Context, collection string) (state logState, err error) {
+
+	var leaves [][]byte
+
+	err = s.store.Get(ctx, logHeadCollection(collection), "head", &state)
+	switch {
+	case err == rest.ErrNotFound:
+		return logState{}, nil
+	case err != nil:
+		return
+	case state.TreeSize == 0:
+		return
+	case len(state.Frontier) > 0:
+		if !bytes.Equal(merkle.FrontierRootHash(state.Frontier), state.RootHash) {
+			err = ErrLogInconsistent
+		}
+		return
+	}
+
+	_, _, leaves, err = s.logTree(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	for i, leaf := range leaves {
+		state.Frontier = merkle.AppendFrontier(state.Frontier, uint64(i), leaf)
+	}
+
+	return
+
+}
+
+// logCA appends the CA certificate to its own issuance log, it is the first entry of the CA logs
+func (s *Service) logCA(ctx context.Context, collection string, certificate client.Certificate) error {
+
+	ca, err := s.caFromCertificate(ctx, collection, certificate)
+	if err != nil {
+		return err
+	}
+
+	return s.logAppend(ctx, collection, ca, certificate.Certificate)
+
+}
+
+// logEntries returns the entries of the issuance log of the CA sorted by index
+func (s *Service) logEntries(ctx context.Context, collection string) (entries []client.APILogEntry, err error) {
+
+	entries = []client.APILogEntry{}
+
+	err = s.datasetItems(ctx, logCollection(collection), &entries)
+	if err != nil {
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Index < entries[j].Index
+	})
+
+	return
+
+}
+
+// logLeaves returns the leaf hashes of the log entries
+func logLeaves(entries []client.APILogEntry) (leaves [][]byte, err error) {
+
+	leaves = make([][]byte, len(entries))
+
+	for i, entry := range entries {
+		leaves[i], err = manager.LogLeafHash(entry)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+
+}
+
+// logTree returns the signed tree head of the issuance log of the CA with its entries and their leaf hashes,
+// ErrLogInconsistent is returned if the entries do not match the tree head
+func (s *Service) logTree(ctx context.Context, collection string) (head client.APISignedTreeHead, entries []client.APILogEntry, leaves [][]byte, err error) {
+
+	err = s.store.Get(ctx, logHeadCollection(collection), "head", &head)
+	if err != nil {
+		return
+	}
+
+	entries, err = s.logEntries(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	if uint64(len(entries)) < head.TreeSize {
+		err = ErrLogInconsistent
+		return
+	}
+	entries = entries[:head.TreeSize]
+
+	leaves, err = logLeaves(entries)
+	if err != nil {
+		return
+	}
+
+	if !bytes.Equal(merkle.RootHash(leaves), head.RootHash) {
+		err = ErrLogInconsistent
+	}
+
+	return
+
+}
+
+// LogHead returns the signed tree head of the issuance log of the CA
+func (s *Service) LogHead(ctx context.Context, collection string) (client.APISignedTreeHead, error) {
+
+	if s.server {
+		return s.logHeadAsServer(ctx, collection)
+	}
+
+	return s.client.LogHead(collection)
+
+}
+
+func (s *Service) logHeadAsServer(ctx context.Context, collection string) (head client.APISignedTreeHead, err error) {
+
+	err = s.store.Get(ctx, logHeadCollection(collection), "head", &head)
+	return
+
+}
+
+// LogEntries returns the issuance log entries of the CA from start to end (both included), an end
+// of 0 returns up to the last entry of the signed tree head
+func (s *Service) LogEntries(ctx context.Context, collection string, start, end uint64) ([]client.APILogEntry, error) {
+
+	if s.server {
+		return s.logEntriesAsServer(ctx, collection, start, end)
+	}
+
+	return s.client.LogEntries(collection, start, end)
+
+}
+
+func (s *Service) logEntriesAsServer(ctx context.Context, collection string, start, end uint64) ([]client.APILogEntry, error) {
+
+	head, entries, _, err := s.logTree(ctx, collection)
+	if err != nil {
+		return []client.APILogEntry{}, err
+	}
+
+	if start >= head.TreeSize {
+		return []client.APILogEntry{}, merkle.ErrIndexInvalid
+	}
+
+	if end == 0 || end >= head.TreeSize {
+		end = head.TreeSize - 1
+	}
+
+	if start > end {
+		return []client.APILogEntry{}, merkle.ErrIndexInvalid
+	}
+
+	return entries[start : end+1], nil
+
+}
+
+// LogInclusionProof returns the proof that the certificate with the serial number (hexadecimal) is
+// included on the issuance log tree of the size, a size of 0 uses the signed tree head size
+func (s *Service) LogInclusionProof(ctx context.Context, collection, serial string, treeSize uint64) (client.APIInclusionProof, error) {
+
+	if s.server {
+		return s.logInclusionProofAsServer(ctx, collection, serial, treeSize)
+	}
+
+	return s.client.LogInclusionProof(collection, serial, treeSize)
+
+}
+
+func (s *Service) logInclusionProofAsServer(ctx context.Context, collection, serial string, treeSize uint64) (proof client.APIInclusionProof, err error) {
+
+	var (
+		head    client.APISignedTreeHead
+		entries []client.APILogEntry
+		leaves  [][]byte
+		number  *big.Int
+		ok      bool
+	)
+
+	number, ok = manager.SerialFromString(serial)
+	if !ok {
+		err = rest.ErrBadRequest
+		return
+	}
+
+	head, entries, leaves, err = s.logTree(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	if treeSize == 0 {
+		treeSize = head.TreeSize
+	}
+
+	if treeSize > head.TreeSize {
+		err = merkle.ErrIndexInvalid
+		return
+	}
+
+	for index, entry := range entries[:treeSize] {
+		if entry.Serial == manager.SerialToString(number) {
+			proof.Entry = entry
+			proof.LeafHash = leaves[index]
+			proof.TreeSize = treeSize
+			proof.AuditPath, err = merkle.InclusionProof(uint64(index), leaves[:treeSize])
+			return
+		}
+	}
+
+	err = rest.ErrNotFound
+
+	return
+
+}
+
+// LogConsistencyProof returns the proof that the issuance log tree of the first size is a prefix of the
+// tree of the second size, a second size of 0 uses the signed tree head size
+func (s *Service) LogConsistencyProof(ctx context.Context, collection string, first, second uint64) (client.APIConsistencyProof, error) {
+
+	if s.server {
+		return s.logConsistencyProofAsServer(ctx, collection, first, second)
+	}
+
+	return s.client.LogConsistencyProof(collection, first, second)
+
+}
+
+func (s *Service) logConsistencyProofAsServer(ctx context.Context, collection string, first, second uint64) (proof client.APIConsistencyProof, err error) {
+
+	var (
+		head   client.APISignedTreeHead
+		leaves [][]byte
+	)
+
+	head, _, leaves, err = s.logTree(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	if second == 0 {
+		second = head.TreeSize
+	}
+
+	if second > head.TreeSize {
+		err = merkle.ErrIndexInvalid
+		return
+	}
+
+	proof.First = first
+	proof.Second = second
+	proof.Consistency, err = merkle.ConsistencyProof(first, leaves[:second])
+
+	return
+
+}
+
+// LogVerify checks the issuance log of the CA against the store: entries must be contiguous and match
+// the signed tree head, the tree head must be signed by the CA and every certificate stored must be on
+// the log. If a checkpoint (a tree head verified before) is set, the log must still contain it.
+//
+// Returns the signed tree head and the problems found, an empty list means the log is sound
+func (s *Service) LogVerify(ctx context.Context, collection string, checkpoint *client.APISignedTreeHead) (head client.APISignedTreeHead, problems []string, err error) {
+
+	var (
+		caCertificate client.Certificate
+		caX509        *x509.Certificate
+		entries       []client.APILogEntry
+		leaves        [][]byte
+		certificates  map[string]client.Certificate
+		logged        map[string]bool = make(map[string]bool)
+		notLogged     []string
+	)
+
+	if !s.server {
+		return head, nil, ErrServerOnly
+	}
+
+	problems = []string{}
+
+	err = s.loadCertificate(ctx, collection, "ca", &caCertificate)
+	if err != nil {
+		return
+	}
+
+	caX509, err = manager.CertificateFromPEM(caCertificate.Certificate)
+	if err != nil {
+		return
+	}
+
+	entries, err = s.logEntries(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	err = s.store.Get(ctx, logHeadCollection(collection), "head", &head)
+	switch err {
+	case nil:
+	case rest.ErrNotFound:
+		err = nil
+		if len(entries) > 0 {
+			problems = append(problems, "signed tree head not found")
+		}
+	default:
+		return
+	}
+
+	// entries
+	for i, entry := range entries {
+
+		var (
+			cert *x509.Certificate
+			leaf []byte
+		)
+
+		if entry.Index != uint64(i) {
+			problems = append(problems, fmt.Sprintf("entry %d found at position %d, entries were removed or reordered", entry.Index, i))
+		}
+
+		cert, err = manager.CertificateFromPEM(entry.Certificate)
+		if err != nil {
+			err = nil
+			problems = append(problems, fmt.Sprintf("entry %d: certificate is unparseable", entry.Index))
+			leaves = append(leaves, merkle.LeafHash(nil))
+			continue
+		}
+
+		if entry.Serial != manager.SerialToString(cert.SerialNumber) {
+			problems = append(problems, fmt.Sprintf("entry %d: serial %s does not match the certificate", entry.Index, entry.Serial))
+		}
+
+		leaf, err = manager.LogLeafHash(entry)
+		if err != nil {
+			return
+		}
+
+		leaves = append(leaves, leaf)
+		logged[string(cert.Raw)] = true
+
+	}
+
+	// tree head
+	switch {
+	case uint64(len(leaves)) < head.TreeSize:
+		problems = append(problems, fmt.Sprintf("log has %d entries, the signed tree head %d", len(leaves), head.TreeSize))
+	case uint64(len(leaves)) > head.TreeSize:
+		problems = append(problems, fmt.Sprintf("entries %d to %d are not covered by the signed tree head", head.TreeSize, len(leaves)-1))
+		fallthrough
+	default:
+		if head.TreeSize > 0 && !bytes.Equal(merkle.RootHash(leaves[:head.TreeSize]), head.RootHash) {
+			problems = append(problems, "root hash does not match the signed tree head, entries were modified")
+		}
+	}
+
+	if head.TreeSize > 0 && manager.VerifyTreeHead(caX509, head) != nil {
+		problems = append(problems, "signed tree head signature is invalid")
+	}
+
+	// checkpoint
+	if checkpoint != nil {
+		switch {
+		case checkpoint.TreeSize > head.TreeSize || checkpoint.TreeSize > uint64(len(leaves)):
+			problems = append(problems, fmt.Sprintf("log is smaller than the checkpoint (%d entries), entries were removed", checkpoint.TreeSize))
+		case !bytes.Equal(merkle.RootHash(leaves[:checkpoint.TreeSize]), checkpoint.RootHash):
+			problems = append(problems, "log is not consistent with the checkpoint, entries were modified")
+		}
+	}
+
+	// certificates stored
+	certificates, err = s.certificateListAsServer(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	for cn, certificate := range certificates {
+		if !logged[string(certificate.X509Certificate.Raw)] {
+			notLogged = append(notLogged, fmt.Sprintf("certificate %s (serial %s) is not on the log", cn, manager.SerialToString(certificate.X509Certificate.SerialNumber)))
+		}
+	}
+
+	sort.Strings(notLogged)
+	problems = append(problems, notLogged...)
+
+	return
+
+}
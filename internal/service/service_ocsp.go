@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"time"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// days the delegated OCSP signing certificates are valid
+	ocspResponderDays = 30
+
+	// ids of the delegated OCSP responders of the CA and of the previous CA while a rollover is in progress
+	ocspCurrentResponder  = "responder"
+	ocspPreviousResponder = "previous-responder"
+)
+
+// ocspCollection returns the collection where the OCSP responder of a CA is stored
+func ocspCollection(collection string) string {
+	return collection + ".ocsp"
+}
+
+// OCSP answers a DER encoded OCSP request (RFC 6960) for the CA, returning the DER encoded
+// response. Requests that cannot be answered return an OCSP error response, not an error
+func (s *Service) OCSP(ctx context.Context, collection string, request []byte) ([]byte, error) {
+
+	if s.server {
+		return s.ocspAsServer(ctx, collection, request)
+	}
+
+	return s.client.OCSP(collection, request)
+
+}
+
+func (s *Service) ocspAsServer(ctx context.Context, collection string, request []byte) ([]byte, error) {
+
+	var (
+		req           *ocsp.Request
+		ca            *manager.CA
+		revocation    client.Revocation
+		responderCert *x509.Certificate
+		responderKey  crypto.PrivateKey
+		responderID   string = ocspCurrentResponder
+		entry         serialEntry
+		indexed       bool
+		now           time.Time = time.Now()
+		err           error
+	)
+
+	req, err = ocsp.ParseRequest(request)
+	if err != nil {
+		return ocsp.MalformedRequestErrorResponse, nil
+	}
+
+	ca, _, err = s.caGet(ctx, collection)
+	if err == rest.ErrNotFound {
+		return ocsp.UnauthorizedErrorResponse, nil
+	}
+	if err != nil {
+		return []byte{}, err
+	}
+
+	// until the rollover finishes, the previous CA answers for the certificates it issued
+	if !ca.OCSPIssuedBy(req) {
+		ca, err = s.rolloverPrevious(ctx, collection, ca)
+		if err == rest.ErrNotFound {
+			return ocsp.UnauthorizedErrorResponse, nil
+		}
+		if err != nil {
+			return []byte{}, err
+		}
+
+		if !ca.OCSPIssuedBy(req) {
+			return ocsp.UnauthorizedErrorResponse, nil
+		}
+		responderID = ocspPreviousResponder
+	}
+
+	template := ocsp.Response{
+		Status:     ocsp.Unknown,
+		ThisUpdate: now,
+		NextUpdate: now.Add(s.crlNextUpdate),
+	}
+
+	entry, indexed, err = s.serialIndexed(ctx, collection, manager.SerialToString(req.SerialNumber))
+	if err != nil {
+		return []byte{}, err
+	}
+
+	err = s.store.Get(ctx, revocationsCollection(collection), manager.SerialToString(req.SerialNumber), &revocation)
+	switch {
+	case indexed && !entry.issuedBy(ca):
+		// unknown, issued by the other key of the CA
+	case err == nil:
+		template.Status = ocsp.Revoked
+		template.RevokedAt = revocation.Time
+		template.RevocationReason, err = manager.ReasonCode(revocation.Reason)
+		if err != nil {
+			return []byte{}, err
+		}
+	case err == rest.ErrNotFound:
+		// certificates already renewed are good until they expire, serials not indexed are unknown
+		if indexed && (entry.NotAfter.IsZero() || now.Before(entry.NotAfter)) {
+			template.Status = ocsp.Good
+		}
+	default:
+		return []byte{}, err
+	}
+
+	responderCert, responderKey, err = s.ocspResponder(ctx, collection, responderID, ca)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return ca.OCSPResponse(req, template, responderCert, responderKey)
+
+}
+
+// ocspResponder returns the delegated OCSP signing certificate and key (stored as id) if the responses
+// must not be signed by the CA, it is created (or renewed) when needed
+func (s *Service) ocspResponder(ctx context.Context, collection, id string, ca *manager.CA) (cert *x509.Certificate, key crypto.PrivateKey, err error) {
+
+	var responder client.Certificate
+
+	if !s.ocspDelegated && !ca.OCSPRequiresDelegation() {
+		return
+	}
+
+	err = s.loadCertificate(ctx, ocspCollection(collection), id, &responder)
+	switch err {
+	case nil:
+		cert, err = manager.CertificateFromPEM(responder.Certificate)
+		if err != nil {
+			return
+		}
+		// renew when less than 20% of its life remains
+		if time.Until(cert.NotAfter) > cert.NotAfter.Sub(cert.NotBefore)/5 {
+			key, err = manager.PrivateKeyFromPEM(responder.Key)
+			return
+		}
+	case rest.ErrNotFound:
+	default:
+		return
+	}
+
+	responder.Certificate, responder.Key, err = ca.NewOCSPResponder(ocspResponderDays)
+	if err != nil {
+		return
+	}
+
+	err = s.saveCertificate(ctx, ocspCollection(collection), id, responder)
+	if err != nil {
+		return
+	}
+
+	cert, err = manager.CertificateFromPEM(responder.Certificate)
+	if err != nil {
+		return
+	}
+
+	key, err = manager.PrivateKeyFromPEM(responder.Key)
+
+	return
+
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+)
+
+// policyCollection returns the collection where the issuance policy of a CA is stored
+func policyCollection(collection string) string {
+	return collection + ".policy"
+}
+
+// PolicySet sets the issuance policy of the CA, new certificates that do not meet it will be rejected
+func (s *Service) PolicySet(ctx context.Context, collection string, policy client.APIPolicy) (client.APIPolicy, error) {
+
+	if s.server {
+		return s.policySetAsServer(ctx, collection, policy)
+	}
+
+	return s.client.PolicySet(collection, policy)
+
+}
+
+func (s *Service) policySetAsServer(ctx context.Context, collection string, policy client.APIPolicy) (client.APIPolicy, error) {
+
+	var (
+		caCertificate client.Certificate
+		err           error
+	)
+
+	err = manager.ValidatePolicy(policy)
+	if err != nil {
+		return client.APIPolicy{}, err
+	}
+
+	// ensure the CA exists
+	err = s.store.Get(ctx, collection, "ca", &caCertificate)
+	if err != nil {
+		return client.APIPolicy{}, err
+	}
+
+	err = s.store.Set(ctx, policyCollection(collection), "policy", policy)
+	if err != nil {
+		return client.APIPolicy{}, err
+	}
+
+	return policy, nil
+
+}
+
+// PolicyGet returns the issuance policy of the CA
+func (s *Service) PolicyGet(ctx context.Context, collection string) (client.APIPolicy, error) {
+
+	if s.server {
+		return s.policyGetAsServer(ctx, collection)
+	}
+
+	return s.client.PolicyGet(collection)
+
+}
+
+func (s *Service) policyGetAsServer(ctx context.Context, collection string) (policy client.APIPolicy, err error) {
+
+	err = s.store.Get(ctx, policyCollection(collection), "policy", &policy)
+	return
+
+}
+
+// PolicyDelete removes the issuance policy of the CA
+func (s *Service) PolicyDelete(ctx context.Context, collection string) (bool, error) {
+
+	if s.server {
+		return s.store.Delete(ctx, policyCollection(collection), "policy")
+	}
+
+	return s.client.PolicyDelete(collection)
+
+}
+
+// policySetter is implemented by the CAs that check the issuance policy before signing (X.509 and SSH)
+type policySetter interface {
+	SetPolicy(policy client.APIPolicy)
+}
+
+// setPolicy sets the issuance policy of the CA, if any, on the CA struct
+func (s *Service) setPolicy(ctx context.Context, collection string, ca policySetter) error {
+
+	policy, err := s.policyGetAsServer(ctx, collection)
+	switch err {
+	case nil:
+		ca.SetPolicy(policy)
+	case rest.ErrNotFound:
+	default:
+		return err
+	}
+
+	return nil
+
+}
+
+// inheritPolicy copies the issuance policy of the parent CA, if any, to the new subordinate CA so it
+// cannot sign what its parent would reject
+func (s *Service) inheritPolicy(ctx context.Context, parent, collection string) error {
+
+	policy, err := s.policyGetAsServer(ctx, parent)
+	switch err {
+	case nil:
+		return s.store.Set(ctx, policyCollection(collection), "policy", policy)
+	case rest.ErrNotFound:
+		return nil
+	}
+
+	return err
+
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+)
+
+// errors
+var (
+	ErrProfileNotFound = errors.New("profile not found for the CA")
+)
+
+// profilesCollection returns the collection where the profiles of a CA are stored
+func profilesCollection(collection string) string {
+	return collection + ".profiles"
+}
+
+// ProfileSet creates or replaces the profile of the CA
+func (s *Service) ProfileSet(ctx context.Context, collection string, profile client.APIProfile) (client.APIProfile, error) {
+
+	if s.server {
+		return s.profileSetAsServer(ctx, collection, profile)
+	}
+
+	return s.client.ProfileSet(collection, profile)
+
+}
+
+func (s *Service) profileSetAsServer(ctx context.Context, collection string, profile client.APIProfile) (client.APIProfile, error) {
+
+	var (
+		caCertificate client.Certificate
+		err           error
+	)
+
+	err = manager.ValidateProfile(profile)
+	if err != nil {
+		return client.APIProfile{}, err
+	}
+
+	// ensure the CA exists
+	err = s.store.Get(ctx, collection, "ca", &caCertificate)
+	if err != nil {
+		return client.APIProfile{}, err
+	}
+
+	err = s.store.Set(ctx, profilesCollection(collection), profile.Name, profile)
+	if err != nil {
+		return client.APIProfile{}, err
+	}
+
+	return profile, nil
+
+}
+
+// ProfileGet returns the profile of the CA
+func (s *Service) ProfileGet(ctx context.Context, collection, name string) (client.APIProfile, error) {
+
+	if s.server {
+		return s.profileGetAsServer(ctx, collection, name)
+	}
+
+	return s.client.ProfileGet(collection, name)
+
+}
+
+func (s *Service) profileGetAsServer(ctx context.Context, collection, name string) (profile client.APIProfile, err error) {
+
+	err = s.store.Get(ctx, profilesCollection(collection), name, &profile)
+	return
+
+}
+
+// ProfileList returns the profiles of the CA sorted by name
+func (s *Service) ProfileList(ctx context.Context, collection string) ([]client.APIProfile, error) {
+
+	if s.server {
+		return s.profileListAsServer(ctx, collection)
+	}
+
+	return s.client.ProfileList(collection)
+
+}
+
+func (s *Service) profileListAsServer(ctx context.Context, collection string) (profiles []client.APIProfile, err error) {
+
+	var (
+		caCertificate client.Certificate
+	)
+
+	// ensure the CA exists
+	err = s.store.Get(ctx, collection, "ca", &caCertificate)
+	if err != nil {
+		return
+	}
+
+	profiles = []client.APIProfile{}
+
+	err = s.datasetItems(ctx, profilesCollection(collection), &profiles)
+	if err != nil {
+		return
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return
+
+}
+
+// ProfileDelete removes the profile of the CA, certificates already issued are not modified
+func (s *Service) ProfileDelete(ctx context.Context, collection, name string) (bool, error) {
+
+	if s.server {
+		return s.store.Delete(ctx, profilesCollection(collection), name)
+	}
+
+	return s.client.ProfileDelete(collection, name)
+
+}
+
+// applyProfile returns the request with its profile applied, if any
+func (s *Service) applyProfile(ctx context.Context, collection string, request client.APICertificateRequest) (client.APICertificateRequest, error) {
+
+	var (
+		profile client.APIProfile
+		err     error
+	)
+
+	if request.Profile == "" {
+		return request, nil
+	}
+
+	err = s.store.Get(ctx, profilesCollection(collection), request.Profile, &profile)
+	if err == rest.ErrNotFound {
+		return request, ErrProfileNotFound
+	}
+	if err != nil {
+		return request, err
+	}
+
+	return manager.ApplyProfile(request, profile), nil
+
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+)
+
+// revocationsCollection returns the collection where the revocations of a CA are stored
+func revocationsCollection(collection string) string {
+	return collection + ".revocations"
+}
+
+// CertificateRevoke revokes the certificate identified by its common name, the certificate
+// is kept on the store and will be included in the CA CRL. The certificates issued before for
+// the common name that have not expired are revoked too (renewals keep the key by default)
+func (s *Service) CertificateRevoke(ctx context.Context, collection, cn string, request client.APIRevocationRequest) (client.Revocation, error) {
+
+	if s.server {
+		return s.certificateRevokeAsServer(ctx, collection, cn, request)
+	}
+
+	return s.client.CertificateRevoke(collection, cn, request)
+
+}
+
+func (s *Service) certificateRevokeAsServer(ctx context.Context, collection, cn string, request client.APIRevocationRequest) (revocation client.Revocation, err error) {
+
+	var (
+		certificate client.Certificate
+		unlock      func() error
+	)
+
+	if cn == "ca" {
+		err = rest.ErrConflict
+		return
+	}
+
+	_, err = manager.ReasonCode(request.Reason)
+	if err != nil {
+		return
+	}
+
+	// ensure the CA exists
+	_, _, err = s.caGet(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	err = s.store.Get(ctx, collection, cn, &certificate)
+	if err != nil {
+		return
+	}
+
+	certificate.X509Certificate, err = manager.CertificateFromPEM(certificate.Certificate)
+	if err != nil {
+		return
+	}
+
+	s.revokeMutex.Lock()
+	defer s.revokeMutex.Unlock()
+
+	unlock, err = s.storeLock(ctx, revocationsCollection(collection))
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	// the first revocation time and reason are kept, they are already published
+	revocation.Serial = manager.SerialToString(certificate.X509Certificate.SerialNumber)
+	err = s.store.Get(ctx, revocationsCollection(collection), revocation.Serial, &client.Revocation{})
+	switch err {
+	case nil:
+		return client.Revocation{}, rest.ErrConflict
+	case rest.ErrNotFound:
+	default:
+		return client.Revocation{}, err
+	}
+
+	revocation.CN = cn
+	revocation.Time = time.Now().UTC().Truncate(time.Second)
+	revocation.Reason = request.Reason
+	if revocation.Reason == "" {
+		revocation.Reason = client.ReasonUnspecified
+	}
+
+	// the current certificate goes last, so a failed revocation can be retried
+	err = s.revokePrevious(ctx, collection, revocation)
+	if err != nil {
+		return client.Revocation{}, err
+	}
+
+	err = s.store.Set(ctx, revocationsCollection(collection), revocation.Serial, revocation)
+	if err != nil {
+		return client.Revocation{}, err
+	}
+
+	return
+
+}
+
+// revokePrevious revokes the serials indexed for the common name of the revocation that have not
+// expired, serials already revoked keep its revocation
+func (s *Service) revokePrevious(ctx context.Context, collection string, revocation client.Revocation) (err error) {
+
+	var (
+		serials []string
+		now     = time.Now()
+	)
+
+	serials, err = s.store.IDs(ctx, serialsCollection(collection))
+	if err != nil {
+		return
+	}
+
+	for _, serial := range serials {
+
+		var entry serialEntry
+
+		if serial == revocation.Serial {
+			continue
+		}
+
+		err = s.store.Get(ctx, serialsCollection(collection), serial, &entry)
+		if err == rest.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		if entry.CN != revocation.CN || (!entry.NotAfter.IsZero() && now.After(entry.NotAfter)) {
+			continue
+		}
+
+		err = s.store.Get(ctx, revocationsCollection(collection), serial, &client.Revocation{})
+		switch err {
+		case nil:
+			continue
+		case rest.ErrNotFound:
+		default:
+			return
+		}
+
+		previous := revocation
+		previous.Serial = serial
+
+		err = s.store.Set(ctx, revocationsCollection(collection), serial, previous)
+		if err != nil {
+			return
+		}
+
+	}
+
+	return nil
+
+}
+
+// isRevoked returns true if the serial is revoked for the CA
+func (s *Service) isRevoked(ctx context.Context, collection, serial string) bool {
+
+	var revocation client.Revocation
+
+	return s.store.Get(ctx, revocationsCollection(collection), serial, &revocation) == nil
+
+}
+
+// revocations returns all the revocations for the CA
+func (s *Service) revocations(ctx context.Context, collection string) (revocations []client.Revocation, err error) {
+
+	err = s.datasetItems(ctx, revocationsCollection(collection), &revocations)
+	return
+
+}
+
+// CRL returns the DER encoded certificate revocation list of the CA
+func (s *Service) CRL(ctx context.Context, collection string) ([]byte, error) {
+
+	if s.server {
+		return s.crlAsServer(ctx, collection)
+	}
+
+	return s.client.CRL(collection)
+
+}
+
+func (s *Service) crlAsServer(ctx context.Context, collection string) ([]byte, error) {
+
+	var (
+		ca          *manager.CA
+		revocations []client.Revocation
+		err         error
+	)
+
+	ca, _, err = s.caGet(ctx, collection)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	revocations, err = s.revocations(ctx, collection)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return ca.CRL(revocations, s.crlNextUpdate)
+
+}
+
+// CRLPrevious returns the DER encoded certificate revocation list signed by the previous CA while a
+// rollover is in progress, with the revocations of the certificates it issued
+func (s *Service) CRLPrevious(ctx context.Context, collection string) ([]byte, error) {
+
+	if s.server {
+		return s.crlPreviousAsServer(ctx, collection)
+	}
+
+	return s.client.CRLPrevious(collection)
+
+}
+
+func (s *Service) crlPreviousAsServer(ctx context.Context, collection string) ([]byte, error) {
+
+	var (
+		ca          *manager.CA
+		previous    *manager.CA
+		revocations []client.Revocation
+		own         []client.Revocation
+		err         error
+	)
+
+	ca, _, err = s.caGet(ctx, collection)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	previous, err = s.rolloverPrevious(ctx, collection, ca)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	revocations, err = s.revocations(ctx, collection)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	for _, revocation := range revocations {
+		var issued bool
+
+		issued, err = s.issuedBy(ctx, collection, revocation.Serial, previous)
+		if err != nil {
+			return []byte{}, err
+		}
+		if issued {
+			own = append(own, revocation)
+		}
+	}
+
+	return previous.CRL(own, s.crlNextUpdate)
+
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+)
+
+// errors
+var (
+	ErrRolloverInProgress = errors.New("the CA has a rollover in progress, finish it first")
+)
+
+// rolloverCollection returns the collection where the previous CA is stored while a rollover is in progress
+func rolloverCollection(collection string) string {
+	return collection + ".rollover"
+}
+
+// CARollover replaces the root CA by a new one under the same CA ID. Until the rollover finishes
+// the CA chain includes the previous CA and the cross signed certificates, so clients trusting
+// any of them keep working. Certificates are re-issued with the new CA key if requested
+func (s *Service) CARollover(ctx context.Context, collection string, request client.APICARolloverRequest) (client.Certificate, error) {
+
+	if s.server {
+		return s.caRolloverAsServer(ctx, collection, request)
+	}
+
+	return s.client.CARollover(collection, request)
+
+}
+
+func (s *Service) caRolloverAsServer(ctx context.Context, collection string, request client.APICARolloverRequest) (certificate client.Certificate, err error) {
+
+	var (
+		ca           *manager.CA
+		next         *manager.CA
+		previous     client.Certificate
+		rollover     manager.Rollover
+		certificates map[string]client.Certificate
+		unlock       func() error
+	)
+
+	// concurrent rollovers would replace the CA twice, the check is done holding the lock
+	unlock, err = s.rolloverLock(ctx, collection)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = s.store.Get(ctx, rolloverCollection(collection), "previous", &previous)
+	switch err {
+	case nil:
+		return client.Certificate{}, ErrRolloverInProgress
+	case rest.ErrNotFound:
+	default:
+		return
+	}
+
+	ca, previous, err = s.caGet(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	rollover, err = ca.Rollover(request.Key, request.ExpirationDays)
+	if err != nil {
+		return
+	}
+
+	err = s.saveCertificate(ctx, rolloverCollection(collection), "previous", previous)
+	if err != nil {
+		return
+	}
+
+	certificate.Certificate = rollover.Certificate
+	certificate.Key = rollover.Key
+	certificate.CACertificate = append(append(append([]byte{}, rollover.CrossSigned...), previous.Certificate...), rollover.PreviousCrossSigned...)
+	certificate.Request = previous.Request
+	certificate.ParentCAID = previous.ParentCAID
+	if request.Key != "" {
+		certificate.Request.Key = request.Key
+	}
+	if request.ExpirationDays > 0 {
+		certificate.Request.ExpirationDays = request.ExpirationDays
+	}
+
+	err = s.saveCertificate(ctx, collection, "ca", certificate)
+	if err != nil {
+		return
+	}
+
+	// the new CA appends itself and the previous CA cross signed, so the tree head is signed with the new key
+	next, err = s.caFromCertificate(ctx, collection, certificate)
+	if err != nil {
+		return
+	}
+
+	err = s.logAppend(ctx, collection, next, rollover.Certificate, rollover.PreviousCrossSigned)
+	if err != nil {
+		return
+	}
+
+	// the delegated OCSP responder was issued by the previous CA
+	_, err = s.store.Delete(ctx, ocspCollection(collection), ocspCurrentResponder)
+	switch err {
+	case nil, rest.ErrNotFound:
+		err = nil
+	default:
+		return
+	}
+
+	if request.Reissue {
+		certificates, err = s.certificateListAsServer(ctx, collection)
+		if err != nil {
+			return
+		}
+
+		for id, leaf := range certificates {
+			if leaf.X509Certificate.IsCA {
+				continue
+			}
+
+			// a 100% remaining lifetime renews the certificate always
+			_, err = s.certificateGetAsServer(ctx, collection, id, 100, false)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	certificate.CAID = collection
+
+	return
+
+}
+
+// CARolloverFinish ends the rollover of the CA, the previous CA and the cross signed certificates
+// are removed from the CA chain
+func (s *Service) CARolloverFinish(ctx context.Context, collection string) error {
+
+	if s.server {
+		return s.caRolloverFinishAsServer(ctx, collection)
+	}
+
+	return s.client.CARolloverFinish(collection)
+
+}
+
+func (s *Service) caRolloverFinishAsServer(ctx context.Context, collection string) (err error) {
+
+	var (
+		previous      client.Certificate
+		caCertificate client.Certificate
+		unlock        func() error
+	)
+
+	unlock, err = s.rolloverLock(ctx, collection)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = s.store.Get(ctx, rolloverCollection(collection), "previous", &previous)
+	if err != nil {
+		return
+	}
+
+	err = s.loadCertificate(ctx, collection, "ca", &caCertificate)
+	if err != nil {
+		return
+	}
+
+	caCertificate.CACertificate = nil
+
+	err = s.saveCertificate(ctx, collection, "ca", caCertificate)
+	if err != nil {
+		return
+	}
+
+	// the delegated OCSP responder of the previous CA is not needed anymore
+	_, err = s.store.Delete(ctx, ocspCollection(collection), ocspPreviousResponder)
+	if err != nil && err != rest.ErrNotFound {
+		return
+	}
+
+	_, err = s.store.Delete(ctx, rolloverCollection(collection), "previous")
+
+	return
+
+}
+
+// rolloverLock serializes the rollovers of the CA, also between processes sharing the store.
+// Returns the function that releases it
+func (s *Service) rolloverLock(ctx context.Context, collection string) (func() error, error) {
+
+	s.rolloverMutex.Lock()
+
+	unlock, err := s.storeLock(ctx, rolloverCollection(collection))
+	if err != nil {
+		s.rolloverMutex.Unlock()
+		return nil, err
+	}
+
+	return func() error {
+		defer s.rolloverMutex.Unlock()
+		return unlock()
+	}, nil
+
+}
+
+// rolloverPrevious returns the previous CA while a rollover is in progress, rest.ErrNotFound if there
+// is none. The certificates it signs (OCSP responders) are appended to the log by the current CA
+func (s *Service) rolloverPrevious(ctx context.Context, collection string, current *manager.CA) (ca *manager.CA, err error) {
+
+	var previous client.Certificate
+
+	err = s.loadCertificate(ctx, rolloverCollection(collection), "previous", &previous)
+	if err != nil {
+		return
+	}
+
+	ca, err = manager.FromBytes(previous.Certificate, previous.Key)
+	if err != nil {
+		return
+	}
+
+	ca.SetLog(func(certificate []byte) error {
+		return s.logAppend(ctx, collection, current, pem.EncodeToMemory(&pem.Block{Type: manager.FileCertificate, Bytes: certificate}))
+	})
+
+	return
+
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+)
+
+// serialsCollection returns the collection where the serial number index of a CA is stored
+func serialsCollection(collection string) string {
+	return collection + ".serials"
+}
+
+// metaCollection returns the collection where the metadata of a CA is stored
+func metaCollection(collection string) string {
+	return collection + ".meta"
+}
+
+// serialsIndexed is the metadata item that records that all the certificates of the CA are on the
+// serial number index, serials not found on it are unknown
+const serialsIndexed = "serials"
+
+// metaEntry is the item stored on the CA metadata
+type metaEntry struct {
+	Time int64 `json:"time"` // milliseconds
+}
+
+// serialEntry is the item stored on the serial number index, it points to the certificate
+// issued with the serial and the key of the CA that issued it (it changes on rollovers)
+type serialEntry struct {
+	CN       string    `json:"cn"`
+	Issuer   string    `json:"issuer,omitempty"` // authority key identifier (hexadecimal), empty if unknown
+	NotAfter time.Time `json:"not_after"`        // zero if unknown
+}
+
+// newSerialEntry returns the serial number index entry of the certificate stored as id
+func newSerialEntry(id string, cert *x509.Certificate) serialEntry {
+	return serialEntry{CN: id, Issuer: hex.EncodeToString(cert.AuthorityKeyId), NotAfter: cert.NotAfter}
+}
+
+// certificateStore stores the certificate and adds its serial number to the CA index
+func (s *Service) certificateStore(ctx context.Context, collection, id string, certificate client.Certificate) (err error) {
+
+	var cert *x509.Certificate
+
+	cert, err = manager.CertificateFromPEM(certificate.Certificate)
+	if err != nil {
+		return
+	}
+
+	err = s.saveCertificate(ctx, collection, id, certificate)
+	if err != nil {
+		return
+	}
+
+	return s.store.Set(ctx, serialsCollection(collection), manager.SerialToString(cert.SerialNumber), newSerialEntry(id, cert))
+
+}
+
+// serialsMarkIndexed records that all the certificates of the CA are on its serial number index
+func (s *Service) serialsMarkIndexed(ctx context.Context, collection string) error {
+	return s.store.Set(ctx, metaCollection(collection), serialsIndexed, metaEntry{Time: time.Now().UnixNano() / int64(time.Millisecond)})
+}
+
+// SerialsReindex adds to the serial number index the certificates of the CAs stored before the index
+// existed. Each CA is indexed once, it is a no-op for clients (the API server indexes its store)
+func (s *Service) SerialsReindex(ctx context.Context) (err error) {
+
+	var collections []string
+
+	if !s.server {
+		return
+	}
+
+	collections, err = s.store.Collections(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, collection := range collections {
+
+		var (
+			entry metaEntry
+			ca    client.Certificate
+		)
+
+		// CA datasets are named <caid>.<dataset>
+		if strings.Contains(collection, ".") {
+			continue
+		}
+
+		err = s.store.Get(ctx, metaCollection(collection), serialsIndexed, &entry)
+		switch err {
+		case nil:
+			continue
+		case rest.ErrNotFound:
+		default:
+			return
+		}
+
+		err = s.store.Get(ctx, collection, "ca", &ca)
+		switch err {
+		case nil:
+		case rest.ErrNotFound: // not a CA
+			err = nil
+			continue
+		default:
+			return
+		}
+
+		err = s.serialsReindex(ctx, collection)
+		if err != nil {
+			return
+		}
+
+	}
+
+	return
+
+}
+
+// serialsReindex adds all the certificates of the CA to its serial number index. Items are read as
+// stored, keys are not needed
+func (s *Service) serialsReindex(ctx context.Context, collection string) (err error) {
+
+	var ids []string
+
+	ids, err = s.store.IDs(ctx, collection)
+	if err != nil {
+		return
+	}
+
+	for _, id := range ids {
+
+		var (
+			certificate client.Certificate
+			cert        *x509.Certificate
+		)
+
+		if id == "ca" {
+			continue
+		}
+
+		err = s.store.Get(ctx, collection, id, &certificate)
+		if err == rest.ErrNotFound { // removed meanwhile
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		cert, err = manager.CertificateFromPEM(certificate.Certificate)
+		if err != nil {
+			return
+		}
+
+		if cert.IsCA {
+			continue
+		}
+
+		err = s.store.Set(ctx, serialsCollection(collection), manager.SerialToString(cert.SerialNumber), newSerialEntry(id, cert))
+		if err != nil {
+			return
+		}
+
+	}
+
+	return s.serialsMarkIndexed(ctx, collection)
+
+}
+
+// serialIndexed returns the serial number index entry of the serial, false if it is not indexed
+func (s *Service) serialIndexed(ctx context.Context, collection, serial string) (entry serialEntry, indexed bool, err error) {
+
+	err = s.store.Get(ctx, serialsCollection(collection), serial, &entry)
+	switch err {
+	case nil:
+		return entry, true, nil
+	case rest.ErrNotFound:
+		return entry, false, nil
+	}
+
+	return
+
+}
+
+// issuedBy returns false if the entry knows that the serial was not issued by the CA key, entries
+// indexed without its issuer are assumed to be issued by it
+func (entry serialEntry) issuedBy(ca *manager.CA) bool {
+	return entry.Issuer == "" || entry.Issuer == hex.EncodeToString(ca.CACertificate().SubjectKeyId)
+}
+
+// issuedBy returns false if the serial index knows that the serial was not issued by the CA key,
+// serials not indexed are assumed to be issued by it
+func (s *Service) issuedBy(ctx context.Context, collection, serial string, ca *manager.CA) (bool, error) {
+
+	entry, indexed, err := s.serialIndexed(ctx, collection, serial)
+	if err != nil {
+		return false, err
+	}
+
+	return !indexed || entry.issuedBy(ca), nil
+
+}
+
+// CertificateGetBySerial returns the certificate and its key information from its serial number
+// as hexadecimal string. Certificates already renewed do not hold its older serial numbers
+func (s *Service) CertificateGetBySerial(ctx context.Context, collection, serial string) (client.Certificate, error) {
+
+	if s.server {
+		return s.certificateGetBySerialAsServer(ctx, collection, serial)
+	}
+
+	return s.client.CertificateGetBySerial(collection, serial)
+
+}
+
+func (s *Service) certificateGetBySerialAsServer(ctx context.Context, collection, serial string) (certificate client.Certificate, err error) {
+
+	var (
+		number *big.Int
+		ok     bool
+		id     string
+	)
+
+	number, ok = manager.SerialFromString(serial)
+	if !ok {
+		err = rest.ErrBadRequest
+		return
+	}
+
+	id, err = s.serialLookup(ctx, collection, number)
+	if err != nil {
+		return
+	}
+
+	return s.certificateGetAsServer(ctx, collection, id, 0, false)
+
+}
+
+// serialLookup returns the id of the certificate stored for the CA that holds the serial number,
+// rest.ErrNotFound is returned if the serial is unknown or the certificate was renewed or deleted
+func (s *Service) serialLookup(ctx context.Context, collection string, serial *big.Int) (id string, err error) {
+
+	var (
+		entry       serialEntry
+		certificate client.Certificate
+	)
+
+	// the index holds all the certificates of the CA (see SerialsReindex)
+	err = s.store.Get(ctx, serialsCollection(collection), manager.SerialToString(serial), &entry)
+	if err != nil {
+		return
+	}
+
+	err = s.store.Get(ctx, collection, entry.CN, &certificate)
+	if err != nil {
+		return
+	}
+
+	certificate.X509Certificate, err = manager.CertificateFromPEM(certificate.Certificate)
+	if err != nil {
+		return
+	}
+
+	if certificate.X509Certificate.SerialNumber.Cmp(serial) != 0 {
+		return "", rest.ErrNotFound
+	}
+
+	return entry.CN, nil
+
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fernandezvara/certsfor/internal/manager"
+	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/rest"
+)
+
+// sshCollection returns the collection where the SSH CA key of a CA is stored
+func sshCollection(collection string) string {
+	return collection + ".ssh"
+}
+
+// SSHSign signs an OpenSSH public key as a user or host certificate (client.SSHUser or
+// client.SSHHost). The SSH CA is created on its first use, the SSH rules of the CA policy apply
+func (s *Service) SSHSign(ctx context.Context, collection, certType string, request client.APISSHRequest) (client.APISSHCertificate, error) {
+
+	if s.server {
+		return s.sshSignAsServer(ctx, collection, certType, request)
+	}
+
+	return s.client.SSHSign(collection, certType, request)
+
+}
+
+func (s *Service) sshSignAsServer(ctx context.Context, collection, certType string, request client.APISSHRequest) (client.APISSHCertificate, error) {
+
+	ca, err := s.sshCA(ctx, collection, true)
+	if err != nil {
+		return client.APISSHCertificate{}, err
+	}
+
+	err = s.setPolicy(ctx, collection, ca)
+	if err != nil {
+		return client.APISSHCertificate{}, err
+	}
+
+	return ca.Sign(certType, request)
+
+}
+
+// SSHCA returns the public key of the SSH CA and the known_hosts line that trusts its host
+// certificates for the hosts pattern (all hosts if empty). rest.ErrNotFound is returned until the
+// SSH CA is created, with the first certificate signed
+func (s *Service) SSHCA(ctx context.Context, collection, hosts string) (client.APISSHCA, error) {
+
+	if s.server {
+		return s.sshCAAsServer(ctx, collection, hosts)
+	}
+
+	return s.client.SSHCA(collection, hosts)
+
+}
+
+func (s *Service) sshCAAsServer(ctx context.Context, collection, hosts string) (response client.APISSHCA, err error) {
+
+	var ca *manager.SSHCA
+
+	ca, err = s.sshCA(ctx, collection, false)
+	if err != nil {
+		return
+	}
+
+	response.PublicKey = ca.PublicKey()
+	response.KnownHosts = ca.KnownHosts(hosts)
+
+	return
+
+}
+
+// sshCA returns the SSH CA of the CA, its key is created if the CA has none and create is true
+func (s *Service) sshCA(ctx context.Context, collection string, create bool) (ca *manager.SSHCA, err error) {
+
+	var (
+		caCertificate client.Certificate
+		sshKey        client.Certificate
+	)
+
+	// ensure the CA exists
+	err = s.store.Get(ctx, collection, "ca", &caCertificate)
+	if err != nil {
+		return
+	}
+
+	err = s.loadCertificate(ctx, sshCollection(collection), "ca", &sshKey)
+	if err == rest.ErrNotFound && create {
+		sshKey, err = s.sshCreate(ctx, collection)
+	}
+	if err != nil {
+		return
+	}
+
+	ca, err = manager.SSHFromBytes(sshKey.Key)
+	if err != nil {
+		return
+	}
+	ca.SetBackdate(s.backdate)
+
+	return
+
+}
+
+// sshCreate creates the SSH CA key of the CA, unless other request (or server sharing the store)
+// created it while waiting for the lock, so every certificate is signed by the same key
+func (s *Service) sshCreate(ctx context.Context, collection string) (sshKey client.Certificate, err error) {
+
+	var unlock func() error
+
+	s.sshMutex.Lock()
+	defer s.sshMutex.Unlock()
+
+	unlock, err = s.storeLock(ctx, sshCollection(collection))
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = s.loadCertificate(ctx, sshCollection(collection), "ca", &sshKey)
+	if err != rest.ErrNotFound {
+		return
+	}
+
+	sshKey.Key, err = manager.NewSSHCA()
+	if err != nil {
+		return
+	}
+
+	err = s.saveCertificate(ctx, sshCollection(collection), "ca", sshKey)
+
+	return
+
+}
@@ -1,19 +1,37 @@
 package service_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	_ "github.com/fernandezvara/certsfor/db/badger" // store driver
 	"github.com/fernandezvara/certsfor/db/store"
+	"github.com/fernandezvara/certsfor/internal/manager"
 	"github.com/fernandezvara/certsfor/internal/service"
 	"github.com/fernandezvara/certsfor/internal/tests"
 	"github.com/fernandezvara/certsfor/pkg/client"
+	"github.com/fernandezvara/certsfor/pkg/merkle"
 	"github.com/fernandezvara/rest"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ocsp"
+	"golang.org/x/crypto/ssh"
 )
 
 var (
@@ -85,6 +103,7 @@ func TestAPIWithService(t *testing.T) {
 
 	srv = service.NewAsServer(sto, tests.Version)
 	assert.True(t, srv.Server())
+	srv.SetOCSPURL("http://ocsp.example.com/")
 
 	// must fail, ca not found
 	_, err = srv.CAGet("caID-not-found")
@@ -111,8 +130,26 @@ func TestAPIWithService(t *testing.T) {
 	testCreateCA(t, srvClient)
 	testCreateCertificate(t, srvClient)
 	testGetCertificates(t, srvClient)
+	testGetCertificateBySerial(t, srvClient)
+	testSerialsReindex(t, srv, sto)
 	testListCertificates(t, srvClient)
 	testDeleteCertificate(t, srvClient)
+	testCreateIntermediate(t, srvClient)
+	testImportCA(t, srvClient)
+	testSignCSR(t, srvClient)
+	testRenewCertificate(t, srvClient)
+	testCARollover(t, srvClient)
+	testProfiles(t, srvClient)
+	testPolicy(t, srvClient, srv)
+	testValidity(t, srvClient, srv)
+	testSSH(t, srvClient)
+	testLog(t, srvClient, srv, sto)
+	testRevokeCertificate(t, srvClient)
+	testOCSP(t, srvClient)
+	testOCSPDelegated(t, srv)
+	testKeyEncryption(t, srv, sto)
+	testBackup(t, srv)
+	testCAs(t, srvClient, sto)
 
 	err = testAPI.StopAPI(t)
 	assert.Nil(t, err)
@@ -217,6 +254,84 @@ func testGetCertificates(t *testing.T, srv *service.Service) {
 
 }
 
+func testGetCertificateBySerial(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx         context.Context = context.Background()
+		certificate client.Certificate
+		bySerial    client.Certificate
+		cert        *x509.Certificate
+		err         error
+	)
+
+	certificate, err = srv.CertificateGet(ctx, caID, certRequest.DN.CN, 0)
+	assert.Nil(t, err)
+
+	cert, err = x509.ParseCertificate(pemBytes(certificate.Certificate))
+	assert.Nil(t, err)
+	assert.LessOrEqual(t, cert.SerialNumber.BitLen(), 128)
+
+	bySerial, err = srv.CertificateGetBySerial(ctx, caID, cert.SerialNumber.Text(16))
+	assert.Nil(t, err)
+	assert.Equal(t, certificate.Certificate, bySerial.Certificate)
+	assert.Equal(t, certificate.Key, bySerial.Key)
+	assert.Equal(t, caCertificateBytes, bySerial.CACertificate)
+
+	// must fail, the certificate was renewed so the first serial is not held anymore
+	cert, err = x509.ParseCertificate(pemBytes(certCertificateBytes))
+	assert.Nil(t, err)
+
+	_, err = srv.CertificateGetBySerial(ctx, caID, cert.SerialNumber.Text(16))
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	// must fail, ca not found
+	_, err = srv.CertificateGetBySerial(ctx, "caID-not-found", cert.SerialNumber.Text(16))
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+}
+
+func testSerialsReindex(t *testing.T, srv *service.Service, sto store.Store) {
+
+	var (
+		ctx         context.Context = context.Background()
+		certificate client.Certificate
+		cert        *x509.Certificate
+		serial      string
+		err         error
+	)
+
+	certificate, err = srv.CertificateGet(ctx, caID, certRequest.DN.CN, 0)
+	assert.Nil(t, err)
+
+	cert, err = x509.ParseCertificate(pemBytes(certificate.Certificate))
+	assert.Nil(t, err)
+
+	serial = manager.SerialToString(cert.SerialNumber)
+
+	// CAs created with the index are not indexed again
+	_, err = sto.Delete(ctx, caID+".serials", serial)
+	assert.Nil(t, err)
+	assert.Nil(t, srv.SerialsReindex(ctx))
+
+	// must fail, serials not indexed are unknown (no certificate scan)
+	_, err = srv.CertificateGetBySerial(ctx, caID, serial)
+	assert.Equal(t, rest.ErrNotFound, err)
+
+	// a CA stored before the index existed
+	_, err = sto.Delete(ctx, caID+".meta", "serials")
+	assert.Nil(t, err)
+	assert.Nil(t, srv.SerialsReindex(ctx))
+
+	assert.Nil(t, sto.Get(ctx, caID+".meta", "serials", &map[string]interface{}{}))
+
+	certificate, err = srv.CertificateGetBySerial(ctx, caID, serial)
+	assert.Nil(t, err)
+	assert.Equal(t, certRequest.DN.CN, certificate.Request.DN.CN)
+
+}
+
 func testListCertificates(t *testing.T, srv *service.Service) {
 
 	var (
@@ -267,3 +382,1564 @@ func testDeleteCertificate(t *testing.T, srv *service.Service) {
 	assert.True(t, ok)
 
 }
+
+func testCreateIntermediate(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx          context.Context = context.Background()
+		rootID       string
+		intermediate client.Certificate
+		certificate  client.Certificate
+		err          error
+	)
+
+	intermediateRequest := caRequest
+	intermediateRequest.DN.CN = "myintermediate"
+
+	// must fail, the CA path length does not allow intermediates
+	_, err = srv.IntermediateCreate(ctx, caID, intermediateRequest)
+	assert.Equal(t, http.StatusText(http.StatusConflict), err.Error())
+
+	// must fail, CA does not exists
+	_, err = srv.IntermediateCreate(ctx, "ca-not-exists", intermediateRequest)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	rootRequest := caRequest
+	rootRequest.PathLength = 1
+
+	rootID, _, _, err = srv.CACreate(ctx, rootRequest)
+	assert.Nil(t, err)
+
+	intermediate, err = srv.IntermediateCreate(ctx, rootID, intermediateRequest)
+	assert.Nil(t, err)
+	assert.Greater(t, len(intermediate.CAID), 0)
+	assert.NotEqual(t, rootID, intermediate.CAID)
+	assert.Greater(t, len(intermediate.Certificate), 0)
+	assert.Greater(t, len(intermediate.Key), 0)
+
+	certificate, err = srv.CertificateGet(ctx, rootID, "ca", 20)
+	assert.Nil(t, err)
+	assert.Equal(t, certificate.Certificate, intermediate.CACertificate)
+
+	// certificates issued by the intermediate include the full chain
+	ca, _, _, err := srv.CertificateSet(ctx, intermediate.CAID, certRequest)
+	assert.Nil(t, err)
+	assert.Equal(t, append(intermediate.Certificate, intermediate.CACertificate...), ca)
+
+}
+
+func testImportCA(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx         context.Context = context.Background()
+		imported    client.Certificate
+		certificate client.Certificate
+		err         error
+	)
+
+	imported, err = srv.CAImport(ctx, client.APICAImportRequest{Certificate: caCertificateBytes, Key: caKeyBytes})
+	assert.Nil(t, err)
+	assert.NotEqual(t, caID, imported.CAID)
+	assert.Equal(t, caCertificateBytes, imported.Certificate)
+	assert.Len(t, imported.Key, 0)
+	assert.Equal(t, caRequest.DN.CN, imported.Request.DN.CN)
+
+	_, _, _, err = srv.CertificateSet(ctx, imported.CAID, certRequest)
+	assert.Nil(t, err)
+
+	certificate, err = srv.CertificateGet(ctx, imported.CAID, certRequest.DN.CN, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, caCertificateBytes, certificate.CACertificate)
+
+	// must fail, key does not match
+	_, err = srv.CAImport(ctx, client.APICAImportRequest{Certificate: caCertificateBytes, Key: certKeyBytes})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	// must fail, signer plugins cannot be set through the API
+	_, err = srv.CAImport(ctx, client.APICAImportRequest{Certificate: caCertificateBytes, Key: manager.KeyReference("exec:/usr/bin/id", "ca")})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+}
+
+func testSignCSR(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx         context.Context = context.Background()
+		certificate client.Certificate
+		renewed     client.Certificate
+		err         error
+	)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "fromcsr"},
+		DNSNames: []string{"fromcsr.example.com"},
+	}, key)
+	assert.Nil(t, err)
+
+	request := client.APICSRRequest{
+		CSR:            pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}),
+		ExpirationDays: 90,
+	}
+
+	certificate, err = srv.CertificateSignCSR(ctx, caID, request)
+	assert.Nil(t, err)
+	assert.Len(t, certificate.Key, 0)
+	assert.Greater(t, len(certificate.Certificate), 0)
+	assert.Equal(t, caCertificateBytes, certificate.CACertificate)
+	assert.Equal(t, "fromcsr", certificate.Request.DN.CN)
+
+	// must fail, CA does not exists
+	_, err = srv.CertificateSignCSR(ctx, "ca-not-exists", request)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	// must fail, unparseable CSR
+	_, err = srv.CertificateSignCSR(ctx, caID, client.APICSRRequest{CSR: []byte("not a csr"), ExpirationDays: 90})
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	// must fail, the certificate would replace the CA, nothing is signed
+	head, err := srv.LogHead(ctx, caID)
+	assert.Nil(t, err)
+
+	for _, cn := range []string{"ca", caRequest.DN.CN} {
+		csrDER, err = x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: cn}}, key)
+		assert.Nil(t, err)
+
+		_, err = srv.CertificateSignCSR(ctx, caID, client.APICSRRequest{CSR: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}), ExpirationDays: 90})
+		assert.Equal(t, http.StatusText(http.StatusConflict), err.Error(), cn)
+	}
+
+	after, err := srv.LogHead(ctx, caID)
+	assert.Nil(t, err)
+	assert.Equal(t, head.TreeSize, after.TreeSize)
+
+	ca, err := srv.CAInfo(ctx, caID)
+	assert.Nil(t, err)
+	assert.Equal(t, caRequest.DN.CN, ca.CN)
+
+	// renewal signs the same public key again
+	renewed, err = srv.CertificateGet(ctx, caID, "fromcsr", 100)
+	assert.Nil(t, err)
+	assert.Len(t, renewed.Key, 0)
+	assert.NotEqual(t, certificate.Certificate, renewed.Certificate)
+
+	// must fail, there is no key to replace
+	_, err = srv.CertificateRenew(ctx, caID, "fromcsr", 0, true)
+	assert.Equal(t, http.StatusText(http.StatusConflict), err.Error())
+
+}
+
+func testRenewCertificate(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx         context.Context              = context.Background()
+		request     client.APICertificateRequest = certRequest
+		certificate client.Certificate
+		renewed     client.Certificate
+		ok          bool
+		err         error
+	)
+
+	request.DN.CN = "rekeyed"
+	request.Renewal = client.RenewalRekey
+
+	_, _, _, err = srv.CertificateSet(ctx, caID, request)
+	assert.Nil(t, err)
+
+	certificate, err = srv.CertificateGet(ctx, caID, request.DN.CN, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, client.RenewalRekey, certificate.Request.Renewal)
+
+	// renewal policy creates a new key
+	renewed, err = srv.CertificateGet(ctx, caID, request.DN.CN, 100)
+	assert.Nil(t, err)
+	assert.NotEqual(t, certificate.Certificate, renewed.Certificate)
+	assert.NotEqual(t, certificate.Key, renewed.Key)
+
+	// default policy reuses the key, rekey without remaining percent renews now
+	request.DN.CN = "reused"
+	request.Renewal = ""
+
+	_, _, _, err = srv.CertificateSet(ctx, caID, request)
+	assert.Nil(t, err)
+
+	certificate, err = srv.CertificateGet(ctx, caID, request.DN.CN, 100)
+	assert.Nil(t, err)
+
+	renewed, err = srv.CertificateRenew(ctx, caID, request.DN.CN, 0, true)
+	assert.Nil(t, err)
+	assert.NotEqual(t, certificate.Certificate, renewed.Certificate)
+	assert.NotEqual(t, certificate.Key, renewed.Key)
+
+	certificate, err = srv.CertificateGet(ctx, caID, request.DN.CN, 100)
+	assert.Nil(t, err)
+	assert.NotEqual(t, certificate.Certificate, renewed.Certificate)
+	assert.Equal(t, certificate.Key, renewed.Key)
+
+	// must fail, unknown renewal policy
+	request.Renewal = "unknown"
+	_, _, _, err = srv.CertificateSet(ctx, caID, request)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	for _, cn := range []string{"rekeyed", "reused"} {
+		ok, err = srv.CertificateDelete(ctx, caID, cn)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+
+}
+
+func testRevokeCertificate(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx         context.Context = context.Background()
+		certificate client.Certificate
+		revocation  client.Revocation
+		crlBytes    []byte
+		err         error
+	)
+
+	// empty CRL
+	crlBytes, err = srv.CRL(ctx, caID)
+	assert.Nil(t, err)
+	crl, err := x509.ParseCRL(crlBytes)
+	assert.Nil(t, err)
+	assert.Len(t, crl.TBSCertList.RevokedCertificates, 0)
+
+	_, _, _, err = srv.CertificateSet(ctx, caID, certRequest)
+	assert.Nil(t, err)
+
+	certificate, err = srv.CertificateGet(ctx, caID, certRequest.DN.CN, 0)
+	assert.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(pemBytes(certificate.Certificate))
+	assert.Nil(t, err)
+
+	// must fail, invalid reason
+	_, err = srv.CertificateRevoke(ctx, caID, certRequest.DN.CN, client.APIRevocationRequest{Reason: "invalid"})
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	// must fail, CA cannot be revoked
+	_, err = srv.CertificateRevoke(ctx, caID, "ca", client.APIRevocationRequest{})
+	assert.Equal(t, http.StatusText(http.StatusConflict), err.Error())
+
+	// must fail, certificate not found
+	_, err = srv.CertificateRevoke(ctx, caID, "not-found", client.APIRevocationRequest{})
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	revocation, err = srv.CertificateRevoke(ctx, caID, certRequest.DN.CN, client.APIRevocationRequest{Reason: client.ReasonKeyCompromise})
+	assert.Nil(t, err)
+	assert.Equal(t, cert.SerialNumber.Text(16), revocation.Serial)
+	assert.Equal(t, client.ReasonKeyCompromise, revocation.Reason)
+
+	// must fail, already revoked (the first revocation is kept, see testOCSP)
+	_, err = srv.CertificateRevoke(ctx, caID, certRequest.DN.CN, client.APIRevocationRequest{Reason: client.ReasonSuperseded})
+	assert.Equal(t, http.StatusText(http.StatusConflict), err.Error())
+
+	// the certificates issued before for the common name are revoked too
+	first, err := x509.ParseCertificate(pemBytes(certCertificateBytes))
+	assert.Nil(t, err)
+
+	crlBytes, err = srv.CRL(ctx, caID)
+	assert.Nil(t, err)
+	crl, err = x509.ParseCRL(crlBytes)
+	assert.Nil(t, err)
+	assert.Contains(t, crlSerials(crl), cert.SerialNumber.Text(16))
+	assert.Contains(t, crlSerials(crl), first.SerialNumber.Text(16))
+
+	// revoked certificates are not renewed
+	renewed, err := srv.CertificateGet(ctx, caID, certRequest.DN.CN, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, certificate.Certificate, renewed.Certificate)
+
+	// renewals reuse the key, the renewed certificate is revoked with the current one
+	request := client.APICertificateRequest{DN: client.APIDN{CN: "renewed-revoked"}, Key: client.ECDSA256, ExpirationDays: 30}
+	_, _, _, err = srv.CertificateSet(ctx, caID, request)
+	assert.Nil(t, err)
+
+	certificate, err = srv.CertificateGet(ctx, caID, request.DN.CN, 0)
+	assert.Nil(t, err)
+	previous, err := x509.ParseCertificate(pemBytes(certificate.Certificate))
+	assert.Nil(t, err)
+
+	certificate, err = srv.CertificateGet(ctx, caID, request.DN.CN, 1000)
+	assert.Nil(t, err)
+	current, err := x509.ParseCertificate(pemBytes(certificate.Certificate))
+	assert.Nil(t, err)
+	assert.NotEqual(t, previous.SerialNumber, current.SerialNumber)
+
+	revocation, err = srv.CertificateRevoke(ctx, caID, request.DN.CN, client.APIRevocationRequest{Reason: client.ReasonKeyCompromise})
+	assert.Nil(t, err)
+	assert.Equal(t, current.SerialNumber.Text(16), revocation.Serial)
+
+	crlBytes, err = srv.CRL(ctx, caID)
+	assert.Nil(t, err)
+	crl, err = x509.ParseCRL(crlBytes)
+	assert.Nil(t, err)
+	assert.Contains(t, crlSerials(crl), previous.SerialNumber.Text(16))
+	assert.Contains(t, crlSerials(crl), current.SerialNumber.Text(16))
+
+	// must fail, CA does not exists
+	_, err = srv.CRL(ctx, "ca-not-exists")
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+}
+
+// crlSerials returns the serial numbers (hexadecimal) revoked on the CRL
+func crlSerials(crl *pkix.CertificateList) (serials []string) {
+
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		serials = append(serials, revoked.SerialNumber.Text(16))
+	}
+
+	return
+
+}
+
+func pemBytes(data []byte) []byte {
+
+	block, _ := pem.Decode(data)
+	return block.Bytes
+
+}
+
+func testOCSP(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx         context.Context = context.Background()
+		certificate client.Certificate
+		response    []byte
+		err         error
+	)
+
+	ca, err := x509.ParseCertificate(pemBytes(caCertificateBytes))
+	assert.Nil(t, err)
+
+	// revoked on testRevokeCertificate
+	certificate, err = srv.CertificateGet(ctx, caID, certRequest.DN.CN, 0)
+	assert.Nil(t, err)
+	revoked, err := x509.ParseCertificate(pemBytes(certificate.Certificate))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{fmt.Sprintf("http://ocsp.example.com/v1/ca/%s/ocsp", caID)}, revoked.OCSPServer)
+
+	request, err := ocsp.CreateRequest(revoked, ca, nil)
+	assert.Nil(t, err)
+
+	response, err = srv.OCSP(ctx, caID, request)
+	assert.Nil(t, err)
+	res, err := ocsp.ParseResponseForCert(response, revoked, ca)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.Revoked, res.Status)
+	assert.Equal(t, ocsp.KeyCompromise, res.RevocationReason)
+
+	// good
+	certificate, err = srv.CertificateGet(ctx, caID, "fromcsr", 0)
+	assert.Nil(t, err)
+	good, err := x509.ParseCertificate(pemBytes(certificate.Certificate))
+	assert.Nil(t, err)
+
+	request, err = ocsp.CreateRequest(good, ca, nil)
+	assert.Nil(t, err)
+
+	response, err = srv.OCSP(ctx, caID, request)
+	assert.Nil(t, err)
+	res, err = ocsp.ParseResponseForCert(response, good, ca)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.Good, res.Status)
+
+	// good, the certificate was renewed but it has not expired
+	certificate, err = srv.CertificateGet(ctx, caID, "fromcsr", 1000)
+	assert.Nil(t, err)
+	renewed, err := x509.ParseCertificate(pemBytes(certificate.Certificate))
+	assert.Nil(t, err)
+	assert.NotEqual(t, good.SerialNumber, renewed.SerialNumber)
+
+	response, err = srv.OCSP(ctx, caID, request)
+	assert.Nil(t, err)
+	res, err = ocsp.ParseResponseForCert(response, good, ca)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.Good, res.Status)
+
+	// unknown, certificate issued by this CA but not stored
+	good.SerialNumber.Add(good.SerialNumber, good.SerialNumber)
+	request, err = ocsp.CreateRequest(good, ca, nil)
+	assert.Nil(t, err)
+
+	response, err = srv.OCSP(ctx, caID, request)
+	assert.Nil(t, err)
+	res, err = ocsp.ParseResponse(response, ca)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.Unknown, res.Status)
+
+	// unauthorized, CA does not exists
+	response, err = srv.OCSP(ctx, "ca-not-exists", request)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.UnauthorizedErrorResponse, response)
+
+	// malformed
+	response, err = srv.OCSP(ctx, caID, []byte("malformed"))
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.MalformedRequestErrorResponse, response)
+
+}
+
+func testOCSPDelegated(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx         context.Context = context.Background()
+		certificate client.Certificate
+		response    []byte
+		err         error
+	)
+
+	srv.SetOCSPDelegated(true)
+	defer srv.SetOCSPDelegated(false)
+
+	ca, err := x509.ParseCertificate(pemBytes(caCertificateBytes))
+	assert.Nil(t, err)
+
+	certificate, err = srv.CertificateGet(ctx, caID, "fromcsr", 0)
+	assert.Nil(t, err)
+
+	request, err := ocsp.CreateRequest(certificate.X509Certificate, ca, nil)
+	assert.Nil(t, err)
+
+	response, err = srv.OCSP(ctx, caID, request)
+	assert.Nil(t, err)
+	first, err := ocsp.ParseResponseForCert(response, certificate.X509Certificate, ca)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.Good, first.Status)
+	assert.NotNil(t, first.Certificate)
+	assert.Contains(t, first.Certificate.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning)
+
+	// the responder is stored and reused
+	response, err = srv.OCSP(ctx, caID, request)
+	assert.Nil(t, err)
+	second, err := ocsp.ParseResponseForCert(response, certificate.X509Certificate, ca)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Certificate.Raw, second.Certificate.Raw)
+
+}
+
+func testKeyEncryption(t *testing.T, srv *service.Service, sto store.Store) {
+
+	var (
+		ctx         context.Context = context.Background()
+		kek, other  *manager.KEK
+		stored      client.Certificate
+		certificate client.Certificate
+		count       int
+		err         error
+	)
+
+	kek, err = srv.KEKFromPassphrase(ctx, "passphrase")
+	assert.Nil(t, err)
+	other, err = srv.KEKFromPassphrase(ctx, "other passphrase")
+	assert.Nil(t, err)
+
+	// the salt is created once per store
+	same, err := srv.KEKFromPassphrase(ctx, "passphrase")
+	assert.Nil(t, err)
+	assert.Equal(t, kek.ID(), same.ID())
+
+	var metadata struct {
+		Salt []byte `json:"salt"`
+	}
+	err = sto.Get(ctx, "cfd.store", "kek", &metadata)
+	assert.Nil(t, err)
+	assert.Len(t, metadata.Salt, 16)
+
+	// a run that failed while writing left keys encrypted with the new KEK, running it again finishes it
+	err = sto.Get(ctx, caID, certRequest.DN.CN, &stored)
+	assert.Nil(t, err)
+	stored.Key, err = other.Encrypt(stored.Key)
+	assert.Nil(t, err)
+	err = sto.Set(ctx, caID, certRequest.DN.CN, stored)
+	assert.Nil(t, err)
+
+	_, err = srv.RekeyStore(ctx, []string{caID}, other)
+	assert.Nil(t, err)
+
+	_, err = srv.RekeyStore(ctx, []string{caID}, nil)
+	assert.Nil(t, err)
+
+	// ca, certificates and ocsp responder
+	count, err = srv.RekeyStore(ctx, []string{caID}, kek)
+	assert.Nil(t, err)
+	assert.Greater(t, count, 2)
+
+	err = sto.Get(ctx, caID, "ca", &stored)
+	assert.Nil(t, err)
+	assert.True(t, manager.IsEncryptedKey(stored.Key))
+
+	// the salt is on the key header, the KEK can be derived again from it
+	kekID, salt, err := manager.EncryptedKeyKEK(stored.Key)
+	assert.Nil(t, err)
+	assert.Equal(t, kek.ID(), kekID)
+	assert.Equal(t, metadata.Salt, salt)
+
+	// keys are decrypted transparently (the CA key signs) and new ones encrypted
+	_, _, certKeyBytes, err = srv.CertificateSet(ctx, caID, certRequest)
+	assert.Nil(t, err)
+
+	err = sto.Get(ctx, caID, certRequest.DN.CN, &stored)
+	assert.Nil(t, err)
+	assert.True(t, manager.IsEncryptedKey(stored.Key))
+
+	certificate, err = srv.CertificateGet(ctx, caID, certRequest.DN.CN, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, certKeyBytes, certificate.Key)
+
+	// must fail, no KEK or a different one
+	srv.SetKEK(nil)
+	_, err = srv.CertificateGet(ctx, caID, certRequest.DN.CN, 0)
+	assert.Equal(t, manager.ErrKEKRequired, err)
+
+	srv.SetKEK(other)
+	_, err = srv.CertificateGet(ctx, caID, certRequest.DN.CN, 0)
+	assert.Equal(t, manager.ErrKEKMismatch, err)
+
+	// keys stored decrypted again
+	srv.SetKEK(kek)
+	_, err = srv.RekeyStore(ctx, []string{caID}, nil)
+	assert.Nil(t, err)
+
+	err = sto.Get(ctx, caID, "ca", &stored)
+	assert.Nil(t, err)
+	assert.Equal(t, caKeyBytes, stored.Key)
+
+	// must fail, not allowed as client
+	_, err = service.NewAsClient(nil, tests.Version).RekeyStore(ctx, []string{caID}, kek)
+	assert.Equal(t, service.ErrServerOnly, err)
+
+}
+
+var errStoreFailed = errors.New("store failed")
+
+// failingStore fails every write on the collection
+type failingStore struct {
+	store.Store
+	collection string
+}
+
+func (f failingStore) Set(ctx context.Context, collection, id string, value interface{}) error {
+
+	if collection == f.collection {
+		return errStoreFailed
+	}
+
+	return f.Store.Set(ctx, collection, id, value)
+
+}
+
+func testBackup(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx                context.Context = context.Background()
+		kek, other         *manager.KEK
+		sealed             []byte
+		restoredID         string
+		stored             client.Certificate
+		original, restored map[string]client.Certificate
+		problems           []string
+		err                error
+	)
+
+	kek, err = srv.KEKFromPassphrase(ctx, "passphrase")
+	assert.Nil(t, err)
+	other, err = srv.KEKFromPassphrase(ctx, "other passphrase")
+	assert.Nil(t, err)
+
+	// keys encrypted at rest are decrypted on the backup
+	_, err = srv.RekeyStore(ctx, []string{caID}, kek)
+	assert.Nil(t, err)
+
+	sealed, err = srv.BackupExport(ctx, caID, "backup passphrase")
+	assert.Nil(t, err)
+
+	// restored on other store, encrypted with other KEK
+	databaseDir, err := ioutil.TempDir("", "cfd")
+	assert.Nil(t, err)
+
+	sto, err := store.Open(ctx, "badger", databaseDir)
+	assert.Nil(t, err)
+	defer sto.Close()
+
+	srvRestored := service.NewAsServer(sto, tests.Version)
+	srvRestored.SetKEK(other)
+
+	// must fail, wrong passphrase
+	_, err = srvRestored.BackupImport(ctx, sealed, "wrong passphrase")
+	assert.Equal(t, manager.ErrBackupPassphrase, err)
+
+	// must fail, the store fails while restoring, nothing is left behind so it can be retried
+	_, err = service.NewAsServer(failingStore{Store: sto, collection: caID + ".log"}, tests.Version).BackupImport(ctx, sealed, "backup passphrase")
+	assert.Equal(t, errStoreFailed, err)
+
+	collections, err := sto.Collections(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, collections)
+
+	restoredID, err = srvRestored.BackupImport(ctx, sealed, "backup passphrase")
+	assert.Nil(t, err)
+	assert.Equal(t, caID, restoredID)
+
+	err = sto.Get(ctx, caID, "ca", &stored)
+	assert.Nil(t, err)
+	assert.True(t, manager.IsEncryptedKey(stored.Key))
+
+	original, err = srv.CertificateList(ctx, caID)
+	assert.Nil(t, err)
+	restored, err = srvRestored.CertificateList(ctx, caID)
+	assert.Nil(t, err)
+	assert.Equal(t, len(original), len(restored))
+
+	for cn, certificate := range original {
+		assert.Equal(t, certificate.Certificate, restored[cn].Certificate)
+		assert.Equal(t, certificate.Request, restored[cn].Request)
+	}
+
+	certificate, err := srv.CertificateGet(ctx, caID, certRequest.DN.CN, 0)
+	assert.Nil(t, err)
+	restoredCertificate, err := srvRestored.CertificateGet(ctx, caID, certRequest.DN.CN, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, certificate.Key, restoredCertificate.Key)
+
+	// the restored CA signs and its issuance log is complete
+	_, _, _, err = srvRestored.CertificateSet(ctx, caID, client.APICertificateRequest{DN: client.APIDN{CN: "after restore"}, Key: client.ECDSA256, ExpirationDays: 1})
+	assert.Nil(t, err)
+
+	_, problems, err = srvRestored.LogVerify(ctx, caID, nil)
+	assert.Nil(t, err)
+	assert.Len(t, problems, 0)
+
+	// must fail, the CA exists
+	_, err = srvRestored.BackupImport(ctx, sealed, "backup passphrase")
+	assert.Equal(t, rest.ErrConflict, err)
+
+	// must fail, key references are not restored
+	var archive bytes.Buffer
+	writer := gzip.NewWriter(&archive)
+	err = json.NewEncoder(writer).Encode(map[string]interface{}{
+		"version": 1,
+		"ca_id":   "forged",
+		"collections": map[string]map[string]client.Certificate{
+			"forged": {"ca": {Certificate: caCertificateBytes, Key: manager.KeyReference("exec:/usr/bin/id", "ca")}},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+
+	forged, err := manager.SealBackup("backup passphrase", archive.Bytes())
+	assert.Nil(t, err)
+
+	_, err = srvRestored.BackupImport(ctx, forged, "backup passphrase")
+	assert.Equal(t, service.ErrBackupKeyReference, err)
+
+	_, err = srvRestored.CAInfo(ctx, "forged")
+	assert.Equal(t, rest.ErrNotFound, err)
+
+	// backups taken before the serial number index existed are indexed on import
+	archive.Reset()
+	writer = gzip.NewWriter(&archive)
+	err = json.NewEncoder(writer).Encode(map[string]interface{}{
+		"version": 1,
+		"ca_id":   "legacy",
+		"collections": map[string]map[string]client.Certificate{
+			"legacy": {"ca": {Certificate: caCertificateBytes, Key: caKeyBytes}, "cert": {Certificate: certCertificateBytes, Key: certKeyBytes}},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+
+	legacy, err := manager.SealBackup("backup passphrase", archive.Bytes())
+	assert.Nil(t, err)
+
+	_, err = srvRestored.BackupImport(ctx, legacy, "backup passphrase")
+	assert.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(pemBytes(certCertificateBytes))
+	assert.Nil(t, err)
+
+	certificate, err = srvRestored.CertificateGetBySerial(ctx, "legacy", manager.SerialToString(cert.SerialNumber))
+	assert.Nil(t, err)
+	assert.Equal(t, certCertificateBytes, certificate.Certificate)
+
+	// must fail, CA not found
+	_, err = srv.BackupExport(ctx, "caID-not-found", "backup passphrase")
+	assert.Equal(t, rest.ErrNotFound, err)
+
+	// must fail, not allowed as client
+	_, err = service.NewAsClient(nil, tests.Version).BackupExport(ctx, caID, "backup passphrase")
+	assert.Equal(t, service.ErrServerOnly, err)
+
+	_, err = srv.RekeyStore(ctx, []string{caID}, nil)
+	assert.Nil(t, err)
+	srv.SetKEK(nil)
+
+}
+
+func testCARollover(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx          context.Context = context.Background()
+		id           string
+		caCert       []byte
+		ca           client.Certificate
+		intermediate client.Certificate
+		leaf         client.Certificate
+		err          error
+	)
+
+	request := caRequest
+	request.PathLength = 1
+
+	id, caCert, _, err = srv.CACreate(ctx, request)
+	assert.Nil(t, err)
+
+	_, _, _, err = srv.CertificateSet(ctx, id, certRequest)
+	assert.Nil(t, err)
+
+	// revoked before the rollover, it is not re-issued
+	revokedRequest := certRequest
+	revokedRequest.DN.CN = "revoked"
+	_, _, _, err = srv.CertificateSet(ctx, id, revokedRequest)
+	assert.Nil(t, err)
+	_, err = srv.CertificateRevoke(ctx, id, revokedRequest.DN.CN, client.APIRevocationRequest{Reason: client.ReasonKeyCompromise})
+	assert.Nil(t, err)
+	previousLeaf, err := srv.CertificateGet(ctx, id, revokedRequest.DN.CN, 0)
+	assert.Nil(t, err)
+	revoked, err := x509.ParseCertificate(pemBytes(previousLeaf.Certificate))
+	assert.Nil(t, err)
+	previousCA, err := x509.ParseCertificate(pemBytes(caCert))
+	assert.Nil(t, err)
+
+	// must fail, no rollover in progress
+	_, err = srv.CRLPrevious(ctx, id)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	ca, err = srv.CARollover(ctx, id, client.APICARolloverRequest{Key: client.ECDSA256, Reissue: true})
+	assert.Nil(t, err)
+	assert.Equal(t, id, ca.CAID)
+	assert.NotEqual(t, caCert, ca.Certificate)
+	assert.Greater(t, len(ca.Key), 0)
+	assert.Contains(t, string(ca.CACertificate), string(caCert))
+	assert.Equal(t, client.ECDSA256, ca.Request.Key)
+
+	// certificates are re-issued by the new CA and can be verified trusting the previous one
+	leaf, err = srv.CertificateGet(ctx, id, certRequest.DN.CN, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, append(append([]byte{}, ca.Certificate...), ca.CACertificate...), leaf.CACertificate)
+
+	roots := x509.NewCertPool()
+	assert.True(t, roots.AppendCertsFromPEM(caCert))
+	intermediates := x509.NewCertPool()
+	assert.True(t, intermediates.AppendCertsFromPEM(leaf.CACertificate))
+
+	cert, err := x509.ParseCertificate(pemBytes(leaf.Certificate))
+	assert.Nil(t, err)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, DNSName: "cert.example.com"})
+	assert.Nil(t, err)
+
+	// must fail, rollover in progress
+	_, err = srv.CARollover(ctx, id, client.APICARolloverRequest{})
+	assert.Equal(t, http.StatusText(http.StatusConflict), err.Error())
+
+	// the previous CA answers OCSP and publishes a CRL for the certificates it issued
+	ocspRequest, err := ocsp.CreateRequest(revoked, previousCA, nil)
+	assert.Nil(t, err)
+	response, err := srv.OCSP(ctx, id, ocspRequest)
+	assert.Nil(t, err)
+	res, err := ocsp.ParseResponseForCert(response, revoked, previousCA)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.Revoked, res.Status)
+
+	crlBytes, err := srv.CRLPrevious(ctx, id)
+	assert.Nil(t, err)
+	crl, err := x509.ParseCRL(crlBytes)
+	assert.Nil(t, err)
+	assert.Nil(t, previousCA.CheckCRLSignature(crl))
+	assert.Len(t, crl.TBSCertList.RevokedCertificates, 1)
+	assert.Equal(t, revoked.SerialNumber, crl.TBSCertList.RevokedCertificates[0].SerialNumber)
+
+	// unknown for the previous CA, issued by the new one
+	ocspRequest, err = ocsp.CreateRequest(cert, previousCA, nil)
+	assert.Nil(t, err)
+	response, err = srv.OCSP(ctx, id, ocspRequest)
+	assert.Nil(t, err)
+	res, err = ocsp.ParseResponse(response, previousCA)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.Unknown, res.Status)
+
+	err = srv.CARolloverFinish(ctx, id)
+	assert.Nil(t, err)
+
+	// the previous CA does not answer anymore
+	response, err = srv.OCSP(ctx, id, ocspRequest)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.UnauthorizedErrorResponse, response)
+
+	_, err = srv.CRLPrevious(ctx, id)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	leaf, err = srv.CertificateGet(ctx, id, certRequest.DN.CN, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, ca.Certificate, leaf.CACertificate)
+
+	// must fail, no rollover in progress
+	err = srv.CARolloverFinish(ctx, id)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	// concurrent rollovers, only one replaces the CA
+	id, _, _, err = srv.CACreate(ctx, request)
+	assert.Nil(t, err)
+
+	head, err := srv.LogHead(ctx, id)
+	assert.Nil(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		succeeded int32
+		conflicts int32
+	)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := srv.CARollover(ctx, id, client.APICARolloverRequest{})
+			switch {
+			case err == nil:
+				atomic.AddInt32(&succeeded, 1)
+			case err.Error() == http.StatusText(http.StatusConflict):
+				atomic.AddInt32(&conflicts, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), succeeded)
+	assert.Equal(t, int32(3), conflicts)
+
+	// a single rollover logged: the new CA and both cross signed certificates
+	next, err := srv.LogHead(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, head.TreeSize+3, next.TreeSize)
+
+	// must fail, intermediates cannot be rolled over
+	request.PathLength = 0
+	request.DN.CN = "rollover intermediate"
+	intermediate, err = srv.IntermediateCreate(ctx, id, request)
+	assert.Nil(t, err)
+
+	_, err = srv.CARollover(ctx, intermediate.CAID, client.APICARolloverRequest{})
+	assert.Equal(t, http.StatusText(http.StatusConflict), err.Error())
+
+}
+
+func testProfiles(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx         context.Context = context.Background()
+		id          string
+		disabled    = false
+		profile     client.APIProfile
+		profiles    []client.APIProfile
+		certificate client.Certificate
+		err         error
+	)
+
+	id, _, _, err = srv.CACreate(ctx, caRequest)
+	assert.Nil(t, err)
+
+	profiles, err = srv.ProfileList(ctx, id)
+	assert.Nil(t, err)
+	assert.Len(t, profiles, 0)
+
+	for _, name := range []string{"server", "client"} {
+		profile, err = srv.ProfileSet(ctx, id, client.APIProfile{
+			Name: name,
+			Defaults: client.APIProfileValues{
+				Key:            client.ECDSA256,
+				ExpirationDays: 30,
+			},
+			Fixed: client.APIProfileValues{
+				DN:          client.APIDN{O: "Profiles"},
+				Client:      &disabled,
+				ExtKeyUsage: []string{client.ExtKeyUsageServerAuth},
+			},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, name, profile.Name)
+	}
+
+	profiles, err = srv.ProfileList(ctx, id)
+	assert.Nil(t, err)
+	assert.Len(t, profiles, 2)
+	assert.Equal(t, "client", profiles[0].Name)
+	assert.Equal(t, "server", profiles[1].Name)
+
+	profile, err = srv.ProfileGet(ctx, id, "server")
+	assert.Nil(t, err)
+	assert.Equal(t, client.ECDSA256, profile.Defaults.Key)
+	assert.False(t, *profile.Fixed.Client)
+
+	// the request only needs the names
+	_, _, _, err = srv.CertificateSet(ctx, id, client.APICertificateRequest{
+		DN:      client.APIDN{CN: "profiled", O: "Requested"},
+		SAN:     []string{"profiled.example.com"},
+		Client:  true,
+		Profile: "server",
+	})
+	assert.Nil(t, err)
+
+	certificate, err = srv.CertificateGet(ctx, id, "profiled", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, client.ECDSA256, certificate.Request.Key)
+	assert.Equal(t, "server", certificate.Request.Profile)
+
+	cert, err := x509.ParseCertificate(pemBytes(certificate.Certificate))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Profiles"}, cert.Subject.Organization)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, cert.ExtKeyUsage)
+	assert.True(t, cert.NotAfter.Before(time.Now().Add(31*24*time.Hour)))
+
+	// must fail, profile not found
+	_, _, _, err = srv.CertificateSet(ctx, id, client.APICertificateRequest{
+		DN:      client.APIDN{CN: "unprofiled"},
+		Profile: "not-found",
+	})
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	// must fail, profile invalid
+	_, err = srv.ProfileSet(ctx, id, client.APIProfile{Name: "invalid", Defaults: client.APIProfileValues{Key: "invalid"}})
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	// must fail, CA not found
+	_, err = srv.ProfileSet(ctx, "ca-not-exists", client.APIProfile{Name: "server"})
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	_, err = srv.ProfileList(ctx, "ca-not-exists")
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	ok, err := srv.ProfileDelete(ctx, id, "client")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	_, err = srv.ProfileGet(ctx, id, "client")
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	_, err = srv.ProfileDelete(ctx, id, "client")
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+}
+
+func testPolicy(t *testing.T, srv *service.Service, srvServer *service.Service) {
+
+	var (
+		ctx       context.Context = context.Background()
+		id        string
+		policy    client.APIPolicy
+		violation *client.PolicyViolation
+		ok        bool
+		err       error
+	)
+
+	request := caRequest
+	request.PathLength = 1
+	id, _, _, err = srv.CACreate(ctx, request)
+	assert.Nil(t, err)
+
+	// must fail, no policy set
+	_, err = srv.PolicyGet(ctx, id)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	policy, err = srv.PolicySet(ctx, id, client.APIPolicy{
+		AllowedSANs:       []string{"*.example.com", "192.168.1.*"},
+		MaxExpirationDays: 90,
+		AllowedKeys:       []string{client.ECDSA256, client.ECDSA521},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(90), policy.MaxExpirationDays)
+
+	policy, err = srv.PolicyGet(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{client.ECDSA256, client.ECDSA521}, policy.AllowedKeys)
+
+	_, _, _, err = srv.CertificateSet(ctx, id, certRequest)
+	assert.Nil(t, err)
+
+	// rejections are returned as policy violations for both server and client
+	for _, s := range []*service.Service{srv, srvServer} {
+		request := certRequest
+		request.SAN = []string{"www.google.com"}
+
+		_, _, _, err = s.CertificateSet(ctx, id, request)
+		violation, ok = err.(*client.PolicyViolation)
+		assert.True(t, ok)
+		if ok {
+			assert.Equal(t, client.PolicyRuleAllowedSANs, violation.Rule)
+			assert.Equal(t, "www.google.com", violation.Value)
+		}
+
+		request = certRequest
+		request.ExpirationDays = 36500
+
+		_, _, _, err = s.CertificateSet(ctx, id, request)
+		violation, ok = err.(*client.PolicyViolation)
+		assert.True(t, ok)
+		if ok {
+			assert.Equal(t, client.PolicyRuleMaxExp, violation.Rule)
+		}
+	}
+
+	// status codes are returned by the API
+	request = certRequest
+	request.Key = client.ECDSA224
+	_, _, _, err = srv.CertificateSet(ctx, id, request)
+	assert.Equal(t, http.StatusUnprocessableEntity, err.(*client.PolicyViolation).Code)
+
+	// CSRs are checked too
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err)
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "policy-csr"},
+		DNSNames: []string{"csr.example.com"},
+	}, key)
+	assert.Nil(t, err)
+
+	_, err = srv.CertificateSignCSR(ctx, id, client.APICSRRequest{CSR: pem.EncodeToMemory(&pem.Block{Type: manager.FileCSR, Bytes: csrDER}), ExpirationDays: 30})
+	violation, ok = err.(*client.PolicyViolation)
+	assert.True(t, ok)
+	if ok {
+		assert.Equal(t, client.PolicyRuleAllowedKeys, violation.Rule)
+		assert.Equal(t, http.StatusUnprocessableEntity, violation.Code)
+	}
+
+	// intermediates must meet the policy and inherit it
+	request = caRequest
+	request.DN.CN = "policy-sub"
+	request.Key = client.ECDSA224
+	_, err = srv.IntermediateCreate(ctx, id, request)
+	violation, ok = err.(*client.PolicyViolation)
+	assert.True(t, ok)
+	if ok {
+		assert.Equal(t, client.PolicyRuleAllowedKeys, violation.Rule)
+	}
+
+	request.Key = client.ECDSA256
+	intermediate, err := srv.IntermediateCreate(ctx, id, request)
+	assert.Nil(t, err)
+
+	policy, err = srv.PolicyGet(ctx, intermediate.CAID)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"*.example.com", "192.168.1.*"}, policy.AllowedSANs)
+
+	request = certRequest
+	request.SAN = []string{"www.google.com"}
+	_, _, _, err = srv.CertificateSet(ctx, intermediate.CAID, request)
+	assert.IsType(t, &client.PolicyViolation{}, err)
+
+	// client usage set by OID is a client certificate too
+	_, err = srv.PolicySet(ctx, intermediate.CAID, client.APIPolicy{DenyClient: true})
+	assert.Nil(t, err)
+
+	request = certRequest
+	request.ExtKeyUsage = []string{"1.3.6.1.5.5.7.3.2"}
+	_, _, _, err = srv.CertificateSet(ctx, intermediate.CAID, request)
+	violation, ok = err.(*client.PolicyViolation)
+	assert.True(t, ok)
+	if ok {
+		assert.Equal(t, client.PolicyRuleDenyClient, violation.Rule)
+	}
+
+	// must fail, policy invalid
+	_, err = srv.PolicySet(ctx, id, client.APIPolicy{AllowedKeys: []string{"invalid"}})
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	ok, err = srv.PolicyDelete(ctx, id)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	request = certRequest
+	request.DN.CN = "unrestricted"
+	request.SAN = []string{"www.google.com"}
+	_, _, _, err = srv.CertificateSet(ctx, id, request)
+	assert.Nil(t, err)
+
+	// renewals and rekeys must meet the policy set after the certificate was issued
+	_, err = srv.PolicySet(ctx, id, client.APIPolicy{AllowedSANs: []string{"*.example.com"}})
+	assert.Nil(t, err)
+
+	for _, rekey := range []bool{false, true} {
+		_, err = srv.CertificateRenew(ctx, id, request.DN.CN, 1000, rekey)
+		violation, ok = err.(*client.PolicyViolation)
+		assert.True(t, ok, rekey)
+		if ok {
+			assert.Equal(t, client.PolicyRuleAllowedSANs, violation.Rule)
+		}
+	}
+
+	ok, err = srv.PolicyDelete(ctx, id)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+}
+
+func testValidity(t *testing.T, srv *service.Service, srvServer *service.Service) {
+
+	var (
+		ctx         context.Context              = context.Background()
+		request     client.APICertificateRequest = certRequest
+		certificate client.Certificate
+		renewed     client.Certificate
+		now         time.Time = time.Now()
+		err         error
+	)
+
+	// remaining lifetime is compared with the whole lifetime, in any unit
+	certificate.X509Certificate = &x509.Certificate{NotBefore: now.Add(-5 * time.Hour), NotAfter: now.Add(1 * time.Hour)}
+	assert.True(t, srvServer.IsNearToExpire(certificate, 20))
+	assert.False(t, srvServer.IsNearToExpire(certificate, 10))
+	assert.True(t, srvServer.IsNearToExpire(certificate, 100))
+
+	certificate.X509Certificate = &x509.Certificate{NotBefore: now.Add(-10 * 24 * time.Hour), NotAfter: now.Add(80 * 24 * time.Hour)}
+	assert.False(t, srvServer.IsNearToExpire(certificate, 20))
+	assert.True(t, srvServer.IsNearToExpire(certificate, 100))
+
+	certificate.X509Certificate = &x509.Certificate{NotBefore: now.Add(-2 * time.Hour), NotAfter: now.Add(-1 * time.Hour)}
+	assert.True(t, srvServer.IsNearToExpire(certificate, 1))
+
+	request.DN.CN = "short-lived"
+	request.Validity = "6h"
+
+	_, _, _, err = srv.CertificateSet(ctx, caID, request)
+	assert.Nil(t, err)
+
+	certificate, err = srv.CertificateGet(ctx, caID, request.DN.CN, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "6h", certificate.Request.Validity)
+
+	x509Certificate, err := manager.CertificateFromPEM(certificate.Certificate)
+	assert.Nil(t, err)
+	assert.Equal(t, 6*time.Hour, x509Certificate.NotAfter.Sub(x509Certificate.NotBefore))
+
+	// a new certificate is not renewed, unless all its lifetime is asked
+	renewed, err = srv.CertificateGet(ctx, caID, request.DN.CN, 20)
+	assert.Nil(t, err)
+	assert.Equal(t, certificate.Certificate, renewed.Certificate)
+
+	renewed, err = srv.CertificateGet(ctx, caID, request.DN.CN, 100)
+	assert.Nil(t, err)
+	assert.NotEqual(t, certificate.Certificate, renewed.Certificate)
+
+	// must fail, validity invalid
+	request.Validity = "six hours"
+	_, _, _, err = srv.CertificateSet(ctx, caID, request)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+}
+
+func testSSH(t *testing.T, srv *service.Service) {
+
+	var (
+		ctx         context.Context = context.Background()
+		ca          client.APISSHCA
+		certificate client.APISSHCertificate
+		err         error
+	)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+
+	// must fail, CA not found
+	_, err = srv.SSHCA(ctx, "ca-non-existent", "")
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	// must fail, the SSH CA is created with the first certificate signed
+	_, err = srv.SSHCA(ctx, caID, "")
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	certificate, err = srv.SSHSign(ctx, caID, client.SSHUser, client.APISSHRequest{
+		PublicKey:  ssh.MarshalAuthorizedKey(publicKey),
+		Principals: []string{"alice"},
+		Validity:   "1h",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alice"}, certificate.Principals)
+
+	ca, err = srv.SSHCA(ctx, caID, "*.example.com")
+	assert.Nil(t, err)
+	assert.Contains(t, string(ca.KnownHosts), "@cert-authority *.example.com ")
+
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey(certificate.Certificate)
+	assert.Nil(t, err)
+
+	caKey, _, _, _, err := ssh.ParseAuthorizedKey(ca.PublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, caKey.Marshal(), parsed.(*ssh.Certificate).SignatureKey.Marshal())
+
+	certificate, err = srv.SSHSign(ctx, caID, client.SSHHost, client.APISSHRequest{
+		PublicKey:  ssh.MarshalAuthorizedKey(publicKey),
+		Principals: []string{"web1.example.com"},
+	})
+	assert.Nil(t, err)
+
+	// must fail, no principals
+	_, err = srv.SSHSign(ctx, caID, client.SSHUser, client.APISSHRequest{PublicKey: ssh.MarshalAuthorizedKey(publicKey)})
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	// must fail, CA not found
+	_, err = srv.SSHSign(ctx, "ca-non-existent", client.SSHUser, client.APISSHRequest{PublicKey: ssh.MarshalAuthorizedKey(publicKey), Principals: []string{"alice"}})
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	// concurrent requests on a new CA are signed by the same SSH CA key
+	id, _, _, err := srv.CACreate(ctx, caRequest)
+	assert.Nil(t, err)
+
+	var (
+		wg      sync.WaitGroup
+		signers = make([][]byte, 8)
+	)
+
+	for i := range signers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			signed, err := srv.SSHSign(ctx, id, client.SSHHost, client.APISSHRequest{PublicKey: ssh.MarshalAuthorizedKey(publicKey), Principals: []string{"web1"}})
+			if assert.Nil(t, err) {
+				parsed, _, _, _, err := ssh.ParseAuthorizedKey(signed.Certificate)
+				assert.Nil(t, err)
+				signers[i] = parsed.(*ssh.Certificate).SignatureKey.Marshal()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	ca, err = srv.SSHCA(ctx, id, "")
+	assert.Nil(t, err)
+	caKey, _, _, _, err = ssh.ParseAuthorizedKey(ca.PublicKey)
+	assert.Nil(t, err)
+	for _, signer := range signers {
+		assert.Equal(t, caKey.Marshal(), signer)
+	}
+
+	// the SSH rules of the policy apply
+	_, err = srv.PolicySet(ctx, id, client.APIPolicy{SSHMaxValidity: "8h", SSHAllowedPrincipals: []string{"deploy-*"}})
+	assert.Nil(t, err)
+
+	_, err = srv.SSHSign(ctx, id, client.SSHUser, client.APISSHRequest{PublicKey: ssh.MarshalAuthorizedKey(publicKey), Principals: []string{"deploy-api"}, Validity: "8h"})
+	assert.Nil(t, err)
+
+	_, err = srv.SSHSign(ctx, id, client.SSHUser, client.APISSHRequest{PublicKey: ssh.MarshalAuthorizedKey(publicKey), Principals: []string{"root"}, Validity: "8h"})
+	violation, ok := err.(*client.PolicyViolation)
+	if assert.True(t, ok) {
+		assert.Equal(t, client.PolicyRuleSSHAllowedPrincipals, violation.Rule)
+		assert.Equal(t, http.StatusForbidden, violation.Code)
+	}
+
+	_, err = srv.SSHSign(ctx, id, client.SSHUser, client.APISSHRequest{PublicKey: ssh.MarshalAuthorizedKey(publicKey), Principals: []string{"deploy-api"}})
+	violation, ok = err.(*client.PolicyViolation)
+	if assert.True(t, ok) {
+		assert.Equal(t, client.PolicyRuleSSHMaxValidity, violation.Rule)
+	}
+
+}
+
+func testLog(t *testing.T, srv *service.Service, srvServer *service.Service, sto store.Store) {
+
+	var (
+		ctx         context.Context = context.Background()
+		head, next  client.APISignedTreeHead
+		entry       client.APILogEntry
+		inclusion   client.APIInclusionProof
+		consistency client.APIConsistencyProof
+		entries     []client.APILogEntry
+		problems    []string
+		err         error
+	)
+
+	ca, err := srvServer.CAGet(caID)
+	assert.Nil(t, err)
+
+	// the CA certificate is the first entry
+	head, err = srv.LogHead(ctx, caID)
+	assert.Nil(t, err)
+	assert.Greater(t, head.TreeSize, uint64(1))
+	assert.Nil(t, manager.VerifyTreeHead(ca.CACertificate(), head))
+
+	entries, err = srv.LogEntries(ctx, caID, 0, 0)
+	assert.Nil(t, err)
+	assert.Len(t, entries, int(head.TreeSize))
+	assert.Equal(t, manager.SerialToString(ca.CACertificate().SerialNumber), entries[0].Serial)
+
+	// every certificate signed is appended
+	request := certRequest
+	request.DN.CN = "logged"
+	_, certPEM, _, err := srv.CertificateSet(ctx, caID, request)
+	assert.Nil(t, err)
+
+	cert, err := manager.CertificateFromPEM(certPEM)
+	assert.Nil(t, err)
+
+	next, err = srv.LogHead(ctx, caID)
+	assert.Nil(t, err)
+	assert.Equal(t, head.TreeSize+1, next.TreeSize)
+
+	entries, err = srv.LogEntries(ctx, caID, head.TreeSize, 0)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, manager.SerialToString(cert.SerialNumber), entries[0].Serial)
+
+	// proofs
+	inclusion, err = srv.LogInclusionProof(ctx, caID, manager.SerialToString(cert.SerialNumber), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, entries[0], inclusion.Entry)
+	leaf, err := manager.LogLeafHash(inclusion.Entry)
+	assert.Nil(t, err)
+	assert.Equal(t, leaf, inclusion.LeafHash)
+	assert.True(t, merkle.VerifyInclusion(inclusion.Entry.Index, next.TreeSize, leaf, inclusion.AuditPath, next.RootHash))
+
+	consistency, err = srv.LogConsistencyProof(ctx, caID, head.TreeSize, 0)
+	assert.Nil(t, err)
+	assert.True(t, merkle.VerifyConsistency(head.TreeSize, next.TreeSize, head.RootHash, next.RootHash, consistency.Consistency))
+
+	// must fail, not on the log, invalid serial or sizes
+	_, err = srv.LogInclusionProof(ctx, caID, "01", 0)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	_, err = srv.LogInclusionProof(ctx, caID, "zz", 0)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	_, err = srv.LogInclusionProof(ctx, caID, manager.SerialToString(cert.SerialNumber), head.TreeSize)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	_, err = srv.LogConsistencyProof(ctx, caID, 0, 0)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	_, err = srv.LogConsistencyProof(ctx, caID, 1, next.TreeSize+1)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	_, err = srv.LogEntries(ctx, caID, next.TreeSize, 0)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), err.Error())
+
+	_, err = srv.LogHead(ctx, "ca-non-existent")
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	// verification
+	next, problems, err = srvServer.LogVerify(ctx, caID, &head)
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+
+	_, _, err = srv.LogVerify(ctx, caID, nil)
+	assert.Equal(t, service.ErrServerOnly, err)
+
+	// must fail, certificate signed without the log
+	rogueCA, err := manager.FromBytes(caCertificateBytes, caKeyBytes)
+	assert.Nil(t, err)
+
+	request.DN.CN = "rogue"
+	rogue, rogueKey, err := rogueCA.CreateCertificateFromAPI(request)
+	assert.Nil(t, err)
+
+	err = sto.Set(ctx, caID, "rogue", client.Certificate{Certificate: rogue, Key: rogueKey, Request: request})
+	assert.Nil(t, err)
+
+	_, problems, err = srvServer.LogVerify(ctx, caID, nil)
+	assert.Nil(t, err)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "rogue")
+
+	_, err = sto.Delete(ctx, caID, "rogue")
+	assert.Nil(t, err)
+
+	// must fail, entry modified
+	logCollection := fmt.Sprintf("%s.log", caID)
+	logID := fmt.Sprintf("%020d", 1)
+
+	err = sto.Get(ctx, logCollection, logID, &entry)
+	assert.Nil(t, err)
+
+	tampered := entry
+	tampered.Timestamp++
+	err = sto.Set(ctx, logCollection, logID, tampered)
+	assert.Nil(t, err)
+
+	_, problems, err = srvServer.LogVerify(ctx, caID, &head)
+	assert.Nil(t, err)
+	assert.Len(t, problems, 2)
+
+	_, err = srv.LogConsistencyProof(ctx, caID, 1, 0)
+	assert.Equal(t, http.StatusText(http.StatusInternalServerError), err.Error())
+
+	// must fail, entry removed
+	err = sto.Set(ctx, logCollection, logID, entry)
+	assert.Nil(t, err)
+
+	_, err = sto.Delete(ctx, logCollection, fmt.Sprintf("%020d", next.TreeSize-1))
+	assert.Nil(t, err)
+
+	_, problems, err = srvServer.LogVerify(ctx, caID, &next)
+	assert.Nil(t, err)
+	assert.Contains(t, problems, fmt.Sprintf("log has %d entries, the signed tree head %d", next.TreeSize-1, next.TreeSize))
+
+	err = sto.Set(ctx, logCollection, fmt.Sprintf("%020d", next.TreeSize-1), entries[0])
+	assert.Nil(t, err)
+
+	_, problems, err = srvServer.LogVerify(ctx, caID, &next)
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+
+	// the tree head keeps the frontier of the tree, heads stored without it get it from the entries
+	var stored map[string]interface{}
+
+	err = sto.Get(ctx, fmt.Sprintf("%s.sth", caID), "head", &stored)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, stored["frontier"])
+
+	delete(stored, "frontier")
+	err = sto.Set(ctx, fmt.Sprintf("%s.sth", caID), "head", stored)
+	assert.Nil(t, err)
+
+	request.DN.CN = "logged-frontier"
+	_, _, _, err = srv.CertificateSet(ctx, caID, request)
+	assert.Nil(t, err)
+
+	head, err = srv.LogHead(ctx, caID)
+	assert.Nil(t, err)
+	assert.Equal(t, next.TreeSize+1, head.TreeSize)
+
+	_, problems, err = srvServer.LogVerify(ctx, caID, &next)
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+
+	err = sto.Get(ctx, fmt.Sprintf("%s.sth", caID), "head", &stored)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, stored["frontier"])
+
+}
+
+func testCAs(t *testing.T, srv *service.Service, sto store.Store) {
+
+	var (
+		ctx          context.Context = context.Background()
+		cas          []client.APICA
+		ca           client.APICA
+		collections  []string
+		rootID       string
+		intermediate client.Certificate
+		ok           bool
+		found        bool
+		err          error
+	)
+
+	// datasets that are not CAs are ignored
+	assert.Nil(t, sto.Set(ctx, "not-a-ca", "item", certRequest))
+
+	cas, err = srv.CAList(ctx)
+	assert.Nil(t, err)
+	assert.Greater(t, len(cas), 1)
+
+	for _, listed := range cas {
+		assert.Empty(t, listed.Certificate)
+		if listed.CAID == caID {
+			found = true
+			assert.Equal(t, caRequest.DN.CN, listed.CN)
+			assert.Greater(t, listed.Certificates, 0)
+		}
+	}
+	assert.True(t, found)
+
+	// must fail, CA does not exists
+	_, err = srv.CAInfo(ctx, "ca-not-exists")
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	ok, err = srv.CADelete(ctx, "ca-not-exists")
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+	assert.False(t, ok)
+
+	rootRequest := caRequest
+	rootRequest.DN.CN = "deleteme"
+	rootRequest.PathLength = 1
+
+	rootID, _, _, err = srv.CACreate(ctx, rootRequest)
+	assert.Nil(t, err)
+
+	intermediateRequest := caRequest
+	intermediateRequest.DN.CN = "deleteme-intermediate"
+
+	intermediate, err = srv.IntermediateCreate(ctx, rootID, intermediateRequest)
+	assert.Nil(t, err)
+
+	_, _, _, err = srv.CertificateSet(ctx, rootID, certRequest)
+	assert.Nil(t, err)
+
+	_, err = srv.CertificateRevoke(ctx, rootID, certRequest.DN.CN, client.APIRevocationRequest{})
+	assert.Nil(t, err)
+
+	ca, err = srv.CAInfo(ctx, rootID)
+	assert.Nil(t, err)
+	assert.Equal(t, rootID, ca.CAID)
+	assert.Equal(t, "deleteme", ca.CN)
+	assert.Equal(t, 1, ca.Certificates)
+	assert.Equal(t, 1, ca.Revoked)
+	assert.False(t, ca.Rollover)
+	assert.Greater(t, len(ca.Certificate), 0)
+	assert.Empty(t, ca.Chain)
+
+	ca, err = srv.CAInfo(ctx, intermediate.CAID)
+	assert.Nil(t, err)
+	assert.Equal(t, rootID, ca.ParentCAID)
+	assert.Equal(t, 0, ca.Certificates)
+	assert.Equal(t, intermediate.CACertificate, ca.Chain)
+
+	// must fail, the intermediate would lose its parent
+	ok, err = srv.CADelete(ctx, rootID)
+	assert.Equal(t, http.StatusText(http.StatusConflict), err.Error())
+	assert.False(t, ok)
+
+	ok, err = srv.CADelete(ctx, intermediate.CAID)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = srv.CADelete(ctx, rootID)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// everything is gone
+	_, err = srv.CAInfo(ctx, rootID)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	_, err = srv.CertificateGet(ctx, rootID, certRequest.DN.CN, 0)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	_, err = srv.CRL(ctx, rootID)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	cas, err = srv.CAList(ctx)
+	assert.Nil(t, err)
+
+	for _, listed := range cas {
+		assert.NotEqual(t, rootID, listed.CAID)
+		assert.NotEqual(t, intermediate.CAID, listed.CAID)
+	}
+
+	collections, err = sto.Collections(ctx)
+	assert.Nil(t, err)
+
+	for _, collection := range collections {
+		assert.False(t, strings.HasPrefix(collection, rootID), collection)
+		assert.False(t, strings.HasPrefix(collection, intermediate.CAID), collection)
+	}
+
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"fmt"
 	"net/http"
 )
 
@@ -21,3 +22,137 @@ func (c *Client) CACreate(request APICertificateRequest) (response Certificate,
 	return
 
 }
+
+// IntermediateCreate creates a subordinate CA signed by the CA, returning it with its new CA ID
+func (c *Client) IntermediateCreate(caID string, request APICertificateRequest) (response Certificate, err error) {
+
+	var (
+		res       *http.Response
+		violation PolicyViolation
+	)
+
+	res, err = c.http.Post(fmt.Sprintf("/v1/ca/%s/intermediates", caID)).BodyJSON(request).Receive(&response, &violation)
+	if err != nil {
+		return
+	}
+
+	err = isPolicyError(res, &violation)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusCreated)
+
+	return
+
+}
+
+// CAImport imports an existing CA and its key, returning it with its new CA ID
+func (c *Client) CAImport(request APICAImportRequest) (response Certificate, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Post("/v1/import/ca").BodyJSON(request).ReceiveSuccess(&response)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusCreated)
+
+	return
+
+}
+
+// CARollover replaces the root CA by a new one, both are cross signed until the rollover finishes
+func (c *Client) CARollover(caID string, request APICARolloverRequest) (response Certificate, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Post(fmt.Sprintf("/v1/ca/%s/rollover", caID)).BodyJSON(request).ReceiveSuccess(&response)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusCreated)
+
+	return
+
+}
+
+// CARolloverFinish ends the rollover of the CA, the previous CA is not trusted anymore
+func (c *Client) CARolloverFinish(caID string) (err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Delete(fmt.Sprintf("/v1/ca/%s/rollover", caID)).ReceiveSuccess(nil)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusNoContent)
+
+	return
+
+}
+
+// CAList returns the information of all the CAs on the store
+func (c *Client) CAList() (response []APICA, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Get("/v1/ca").ReceiveSuccess(&response)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// CAInfo returns the information of the CA with its certificate
+func (c *Client) CAInfo(caID string) (response APICA, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Get(fmt.Sprintf("/v1/ca/%s", caID)).ReceiveSuccess(&response)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// CADelete removes the CA with all the certificates it issued
+func (c *Client) CADelete(caID string) (ok bool, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	if res, err = c.http.Delete(fmt.Sprintf("/v1/ca/%s", caID)).ReceiveSuccess(nil); err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusNoContent)
+	if err == nil {
+		ok = true
+	}
+
+	return
+
+}
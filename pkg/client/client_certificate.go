@@ -9,10 +9,16 @@ import (
 func (c *Client) CertificateCreate(caID, cn string, request APICertificateRequest) (response Certificate, err error) {
 
 	var (
-		res *http.Response
+		res       *http.Response
+		violation PolicyViolation
 	)
 
-	res, err = c.http.Put(fmt.Sprintf("/v1/ca/%s/certificates/%s", caID, cn)).BodyJSON(request).ReceiveSuccess(&response)
+	res, err = c.http.Put(fmt.Sprintf("/v1/ca/%s/certificates/%s", caID, cn)).BodyJSON(request).Receive(&response, &violation)
+	if err != nil {
+		return
+	}
+
+	err = isPolicyError(res, &violation)
 	if err != nil {
 		return
 	}
@@ -22,3 +28,38 @@ func (c *Client) CertificateCreate(caID, cn string, request APICertificateReques
 	return
 
 }
+
+// CertificateSignCSR signs a certificate signing request, the response holds no key
+func (c *Client) CertificateSignCSR(caID string, request APICSRRequest) (response Certificate, err error) {
+
+	var (
+		res       *http.Response
+		violation PolicyViolation
+	)
+
+	res, err = c.http.Post(fmt.Sprintf("/v1/ca/%s/csr", caID)).BodyJSON(request).Receive(&response, &violation)
+	if err != nil {
+		return
+	}
+
+	err = isPolicyError(res, &violation)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusCreated)
+
+	return
+
+}
+
+// isPolicyError returns the policy violation if the request was rejected by the CA issuance policy
+func isPolicyError(res *http.Response, violation *PolicyViolation) error {
+
+	if res != nil && (res.StatusCode == http.StatusForbidden || res.StatusCode == http.StatusUnprocessableEntity) && violation.Rule != "" {
+		return violation
+	}
+
+	return nil
+
+}
@@ -3,21 +3,65 @@ package client
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 )
 
 // CertificateGet returns the certificate information if found
 func (c *Client) CertificateGet(caID, cn string, remaining int) (response Certificate, err error) {
 
+	return c.CertificateRenew(caID, cn, remaining, false)
+
+}
+
+// CertificateRenew returns the certificate information if found, renewed if its remaining lifetime
+// (percent) is lower than remaining. If rekey is true the renewed certificate will have a new key,
+// without remaining percent it is renewed now
+func (c *Client) CertificateRenew(caID, cn string, remaining int, rekey bool) (response Certificate, err error) {
+
 	var (
-		uri string = fmt.Sprintf("/v1/ca/%s/certificates/%s", caID, cn)
-		res *http.Response
+		uri       string     = fmt.Sprintf("/v1/ca/%s/certificates/%s", caID, cn)
+		values    url.Values = url.Values{}
+		res       *http.Response
+		violation PolicyViolation
 	)
 
 	if remaining > 0 {
-		uri = fmt.Sprintf("%s?renew=%d", uri, remaining)
+		values.Set("renew", strconv.Itoa(remaining))
+	}
+
+	if rekey {
+		values.Set("rekey", "true")
+	}
+
+	if len(values) > 0 {
+		uri = fmt.Sprintf("%s?%s", uri, values.Encode())
+	}
+
+	res, err = c.http.Get(uri).Receive(&response, &violation)
+	if err != nil {
+		return
+	}
+
+	err = isPolicyError(res, &violation)
+	if err != nil {
+		return
 	}
 
-	res, err = c.http.Get(uri).ReceiveSuccess(&response)
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// CertificateGetBySerial returns the certificate information for the serial number (hexadecimal) if found
+func (c *Client) CertificateGetBySerial(caID, serial string) (response Certificate, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Get(fmt.Sprintf("/v1/ca/%s/serials/%s", caID, serial)).ReceiveSuccess(&response)
 	if err != nil {
 		return
 	}
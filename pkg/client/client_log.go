@@ -0,0 +1,85 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// LogHead returns the signed tree head of the CA issuance log
+func (c *Client) LogHead(caID string) (response APISignedTreeHead, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Get(fmt.Sprintf("/v1/ca/%s/log/sth", caID)).ReceiveSuccess(&response)
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// LogEntries returns the CA issuance log entries from start to end (both included), an end of 0
+// returns up to the last entry of the signed tree head
+func (c *Client) LogEntries(caID string, start, end uint64) (response []APILogEntry, err error) {
+
+	var (
+		values url.Values = url.Values{}
+		res    *http.Response
+	)
+
+	values.Set("start", strconv.FormatUint(start, 10))
+	if end > 0 {
+		values.Set("end", strconv.FormatUint(end, 10))
+	}
+
+	res, err = c.http.Get(fmt.Sprintf("/v1/ca/%s/log/entries?%s", caID, values.Encode())).ReceiveSuccess(&response)
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// LogInclusionProof returns the proof that the certificate with the serial number (hexadecimal) is
+// on the CA issuance log tree of the size, a size of 0 uses the signed tree head size
+func (c *Client) LogInclusionProof(caID, serial string, treeSize uint64) (response APIInclusionProof, err error) {
+
+	var (
+		values url.Values = url.Values{}
+		res    *http.Response
+	)
+
+	values.Set("serial", serial)
+	if treeSize > 0 {
+		values.Set("tree_size", strconv.FormatUint(treeSize, 10))
+	}
+
+	res, err = c.http.Get(fmt.Sprintf("/v1/ca/%s/log/proof/inclusion?%s", caID, values.Encode())).ReceiveSuccess(&response)
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// LogConsistencyProof returns the proof that the CA issuance log tree of the first size is a prefix
+// of the tree of the second size, a second size of 0 uses the signed tree head size
+func (c *Client) LogConsistencyProof(caID string, first, second uint64) (response APIConsistencyProof, err error) {
+
+	var (
+		values url.Values = url.Values{}
+		res    *http.Response
+	)
+
+	values.Set("first", strconv.FormatUint(first, 10))
+	if second > 0 {
+		values.Set("second", strconv.FormatUint(second, 10))
+	}
+
+	res, err = c.http.Get(fmt.Sprintf("/v1/ca/%s/log/proof/consistency?%s", caID, values.Encode())).ReceiveSuccess(&response)
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// PolicySet sets the issuance policy of the CA
+func (c *Client) PolicySet(caID string, policy APIPolicy) (response APIPolicy, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Put(fmt.Sprintf("/v1/ca/%s/policy", caID)).BodyJSON(policy).ReceiveSuccess(&response)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// PolicyGet returns the issuance policy of the CA
+func (c *Client) PolicyGet(caID string) (response APIPolicy, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Get(fmt.Sprintf("/v1/ca/%s/policy", caID)).ReceiveSuccess(&response)
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// PolicyDelete removes the issuance policy of the CA, so it signs any request
+func (c *Client) PolicyDelete(caID string) (ok bool, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	if res, err = c.http.Delete(fmt.Sprintf("/v1/ca/%s/policy", caID)).ReceiveSuccess(nil); err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusNoContent)
+	if err == nil {
+		ok = true
+	}
+
+	return
+
+}
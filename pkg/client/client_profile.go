@@ -0,0 +1,72 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ProfileSet creates or replaces the profile of the CA
+func (c *Client) ProfileSet(caID string, profile APIProfile) (response APIProfile, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Put(fmt.Sprintf("/v1/ca/%s/profiles/%s", caID, profile.Name)).BodyJSON(profile).ReceiveSuccess(&response)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// ProfileGet returns the profile of the CA
+func (c *Client) ProfileGet(caID, name string) (response APIProfile, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Get(fmt.Sprintf("/v1/ca/%s/profiles/%s", caID, name)).ReceiveSuccess(&response)
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// ProfileList returns the profiles of the CA
+func (c *Client) ProfileList(caID string) (response []APIProfile, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Get(fmt.Sprintf("/v1/ca/%s/profiles", caID)).ReceiveSuccess(&response)
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// ProfileDelete deletes the profile of the CA
+func (c *Client) ProfileDelete(caID, name string) (ok bool, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	if res, err = c.http.Delete(fmt.Sprintf("/v1/ca/%s/profiles/%s", caID, name)).ReceiveSuccess(nil); err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusNoContent)
+	if err == nil {
+		ok = true
+	}
+
+	return
+
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// CertificateRevoke revokes the certificate, returning the revocation information
+func (c *Client) CertificateRevoke(caID, cn string, request APIRevocationRequest) (response Revocation, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.Post(fmt.Sprintf("/v1/ca/%s/certificates/%s/revoke", caID, cn)).BodyJSON(request).ReceiveSuccess(&response)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// CRL returns the DER encoded certificate revocation list of the CA
+func (c *Client) CRL(caID string) (response []byte, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.New().Get(fmt.Sprintf("/v1/ca/%s/crl", caID)).ResponseDecoder(rawDecoder{}).ReceiveSuccess(&response)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// CRLPrevious returns the DER encoded certificate revocation list signed by the previous CA while
+// a rollover is in progress
+func (c *Client) CRLPrevious(caID string) (response []byte, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.New().Get(fmt.Sprintf("/v1/ca/%s/crl?issuer=previous", caID)).ResponseDecoder(rawDecoder{}).ReceiveSuccess(&response)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
+
+// rawDecoder returns the response body as is, v must be a *[]byte
+type rawDecoder struct{}
+
+func (d rawDecoder) Decode(res *http.Response, v interface{}) (err error) {
+
+	*(v.(*[]byte)), err = ioutil.ReadAll(res.Body)
+	return
+
+}
+
+// OCSP sends the DER encoded OCSP request for the CA, returning the DER encoded OCSP response
+func (c *Client) OCSP(caID string, request []byte) (response []byte, err error) {
+
+	var (
+		res *http.Response
+	)
+
+	res, err = c.http.New().Post(fmt.Sprintf("/v1/ca/%s/ocsp", caID)).Body(bytes.NewReader(request)).Set("Content-Type", "application/ocsp-request").ResponseDecoder(rawDecoder{}).ReceiveSuccess(&response)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
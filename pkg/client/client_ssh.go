@@ -0,0 +1,50 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// SSHSign signs an OpenSSH public key as a user or host certificate (SSHUser or SSHHost)
+func (c *Client) SSHSign(caID, certType string, request APISSHRequest) (response APISSHCertificate, err error) {
+
+	var (
+		res       *http.Response
+		violation PolicyViolation
+	)
+
+	res, err = c.http.Post(fmt.Sprintf("/v1/ca/%s/ssh/%s", caID, certType)).BodyJSON(request).Receive(&response, &violation)
+	if err != nil {
+		return
+	}
+
+	err = isPolicyError(res, &violation)
+	if err != nil {
+		return
+	}
+
+	err = isError(res, err, http.StatusCreated)
+
+	return
+
+}
+
+// SSHCA returns the public key of the SSH CA and the known_hosts line for the hosts pattern
+func (c *Client) SSHCA(caID, hosts string) (response APISSHCA, err error) {
+
+	var (
+		uri string = fmt.Sprintf("/v1/ca/%s/ssh", caID)
+		res *http.Response
+	)
+
+	if hosts != "" {
+		uri = fmt.Sprintf("%s?%s", uri, url.Values{"hosts": []string{hosts}}.Encode())
+	}
+
+	res, err = c.http.Get(uri).ReceiveSuccess(&response)
+	err = isError(res, err, http.StatusOK)
+
+	return
+
+}
@@ -53,6 +53,7 @@ func TestClientAPI(t *testing.T) {
 	getCertificate(t, cli)
 	listCertificates(t, cli)
 	deleteCertificate(t, cli)
+	cas(t, cli)
 
 	err = testAPI.StopAPI(t)
 	assert.Nil(t, err)
@@ -147,6 +148,15 @@ func TestClientWithErrors(t *testing.T) {
 	_, err = cliWithErrors.CertificateDelete("ca-uuid", "common-name")
 	assert.Error(t, err)
 
+	_, err = cliWithErrors.CAList()
+	assert.Error(t, err)
+
+	_, err = cliWithErrors.CAInfo("ca-uuid")
+	assert.Error(t, err)
+
+	_, err = cliWithErrors.CADelete("ca-uuid")
+	assert.Error(t, err)
+
 	// client with non existent certificates or keys
 	// error ca cert
 	cliWithErrors, err = client.NewWithConnectionTimeouts("127.0.0.1:64100", "/non-existent/cacert.txt", "", "", false, 100*time.Millisecond, 100*time.Millisecond, 500*time.Millisecond)
@@ -420,3 +430,44 @@ func deleteCertificate(t *testing.T, cli *client.Client) {
 	assert.Len(t, certificates, 2)
 
 }
+
+func cas(t *testing.T, cli *client.Client) {
+
+	var (
+		cas []client.APICA
+		ca  client.APICA
+		ok  bool
+		err error
+	)
+
+	cas, err = cli.CAList()
+	assert.Nil(t, err)
+	assert.Len(t, cas, 1)
+	assert.Equal(t, caID, cas[0].CAID)
+	assert.Equal(t, 1, cas[0].Certificates)
+
+	// 404 - Not found
+	_, err = cli.CAInfo("1234")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+
+	ca, err = cli.CAInfo(caID)
+	assert.Nil(t, err)
+	assert.Equal(t, caRequest.DN.CN, ca.CN)
+	assert.Equal(t, caCertificateBytes, ca.Certificate)
+
+	// 404 - Not found
+	ok, err = cli.CADelete("1234")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Error())
+	assert.False(t, ok)
+
+	ok, err = cli.CADelete(caID)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	cas, err = cli.CAList()
+	assert.Nil(t, err)
+	assert.Len(t, cas, 0)
+
+}
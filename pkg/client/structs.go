@@ -3,6 +3,9 @@ package client
 import (
 	"crypto/x509"
 	"errors"
+	"fmt"
+	"net/http"
+	"time"
 )
 
 const (
@@ -24,16 +27,247 @@ type Certificate struct {
 	X509Certificate *x509.Certificate     `json:"-"`
 	Request         APICertificateRequest `json:"request"`
 	CAID            string                `json:"ca_id,omitempty"`
+	ParentCAID      string                `json:"parent_ca_id,omitempty"`
 }
 
 // APICertificateRequest is the struct with the data needed to create a new
 // certificate
 type APICertificateRequest struct {
 	DN             APIDN    `json:"dn"`
-	SAN            []string `json:"san" yaml:"san"`       // SAN
-	Key            string   `json:"key" yaml:"key"`       // Key Type (RSA/ECDSA):(complexity)
-	ExpirationDays int64    `json:"exp" yaml:"exp"`       // Days the certificate will be valid
-	Client         bool     `json:"client" yaml:"client"` // requesting a client certificate?
+	SAN            []string `json:"san" yaml:"san"`           // SAN, optionally typed with a prefix (dns:, ip:, email:, uri:, upn:)
+	Key            string   `json:"key" yaml:"key"`           // Key Type (RSA/ECDSA):(complexity) or Ed25519
+	ExpirationDays int64    `json:"exp" yaml:"exp"`           // Days the certificate will be valid
+	Client         bool     `json:"client" yaml:"client"`     // requesting a client certificate?
+	PathLength     int      `json:"path_len" yaml:"path_len"` // CA only: intermediate CAs allowed below it (0: none, -1: unlimited)
+
+	// time the certificate will be valid as a duration (ex: 6h, 90m), takes precedence over exp
+	Validity string `json:"validity,omitempty" yaml:"validity"`
+
+	// leaves only: renewal policy, reuse-key (default) or rekey
+	Renewal string `json:"renewal,omitempty" yaml:"renewal"`
+
+	// leaves only: profile of the CA applied to the request before signing
+	Profile string `json:"profile,omitempty" yaml:"profile"`
+
+	// CA only: names allowed on the certificates issued by the CA
+	NameConstraints APINameConstraints `json:"name_constraints" yaml:"name_constraints"`
+
+	// extensions
+	ExtKeyUsage           []string       `json:"ext_key_usage,omitempty" yaml:"ext_key_usage"` // extended key usages by name or OID (ex: codeSigning, 1.3.6.1.5.5.7.3.3)
+	Policies              []string       `json:"policies,omitempty" yaml:"policies"`           // certificate policy OIDs
+	CRLDistributionPoints []string       `json:"crl_dp,omitempty" yaml:"crl_dp"`               // CRL distribution point URLs
+	IssuingCertificateURL []string       `json:"issuer_url,omitempty" yaml:"issuer_url"`       // AIA CA issuers URLs
+	Extensions            []APIExtension `json:"extensions,omitempty" yaml:"extensions"`       // custom extensions
+}
+
+// APIExtension is a custom X.509 extension, its value can be a string (encoded as UTF8String)
+// or the DER encoded value as base64
+type APIExtension struct {
+	OID      string `json:"oid" yaml:"oid"`                     // extension OID (ex: 1.2.3.4)
+	Critical bool   `json:"critical,omitempty" yaml:"critical"` // must clients reject the certificate if they cannot process it?
+	Value    string `json:"value,omitempty" yaml:"value"`       // string value
+	Raw      string `json:"raw,omitempty" yaml:"raw"`           // DER encoded value as base64 (if no value)
+}
+
+// APINameConstraints are the names that a CA is allowed to issue certificates for. If any permitted name
+// of a type is set, names of that type must match one of them. Excluded names are never allowed.
+//
+// DNS and URI constraints are domains, a leading dot (ex: .example.com) only matches its subdomains.
+// IP constraints are ranges in CIDR notation (ex: 10.0.0.0/8).
+// Email constraints are mailboxes (ex: john@example.com) or domains.
+type APINameConstraints struct {
+	PermittedDNS    []string `json:"permitted_dns,omitempty" yaml:"permitted_dns"`
+	ExcludedDNS     []string `json:"excluded_dns,omitempty" yaml:"excluded_dns"`
+	PermittedIPs    []string `json:"permitted_ips,omitempty" yaml:"permitted_ips"`
+	ExcludedIPs     []string `json:"excluded_ips,omitempty" yaml:"excluded_ips"`
+	PermittedEmails []string `json:"permitted_emails,omitempty" yaml:"permitted_emails"`
+	ExcludedEmails  []string `json:"excluded_emails,omitempty" yaml:"excluded_emails"`
+	PermittedURIs   []string `json:"permitted_uris,omitempty" yaml:"permitted_uris"`
+	ExcludedURIs    []string `json:"excluded_uris,omitempty" yaml:"excluded_uris"`
+}
+
+// APICSRRequest is the struct with the data needed to sign a certificate signing request,
+// subject and SANs are taken from the CSR
+type APICSRRequest struct {
+	CSR            []byte `json:"csr"`                // PEM encoded PKCS#10 request
+	ExpirationDays int64  `json:"exp"`                // Days the certificate will be valid
+	Validity       string `json:"validity,omitempty"` // time the certificate will be valid as a duration (ex: 6h), takes precedence over exp
+	Client         bool   `json:"client"`             // requesting a client certificate?
+}
+
+// APICA is the information of a CA stored, returned by the API on GET /v1/ca and GET /v1/ca/:caid
+type APICA struct {
+	CAID         string    `json:"ca_id"`
+	ParentCAID   string    `json:"parent_ca_id,omitempty"`   // intermediates only
+	CN           string    `json:"cn"`                       // common name
+	Subject      string    `json:"subject"`                  // distinguished name
+	Serial       string    `json:"serial"`                   // serial number (hexadecimal)
+	NotBefore    time.Time `json:"not_before"`               // start of the validity
+	NotAfter     time.Time `json:"not_after"`                // expiration time
+	Certificates int       `json:"certificates"`             // certificates issued by the CA stored
+	Revoked      int       `json:"revoked"`                  // certificates revoked
+	Rollover     bool      `json:"rollover"`                 // a rollover is in progress
+	Certificate  []byte    `json:"certificate,omitempty"`    // CA certificate as PEM (details only)
+	Chain        []byte    `json:"ca_certificate,omitempty"` // chain of its issuer as PEM (details only, empty on roots)
+}
+
+// APICAImportRequest is the struct with the data needed to import an existing CA
+type APICAImportRequest struct {
+	Certificate []byte `json:"certificate"`        // CA certificate (PEM followed by its chain, DER or PKCS#12 bundle)
+	Key         []byte `json:"key,omitempty"`      // CA key (PKCS#1, SEC1 or PKCS#8 as PEM or DER, or a signer key reference), empty for PKCS#12
+	Password    string `json:"password,omitempty"` // PKCS#12 password
+}
+
+// APICARolloverRequest is the struct with the data needed to replace a root CA by a new one,
+// empty values keep the ones of the current CA
+type APICARolloverRequest struct {
+	Key            string `json:"key,omitempty"`     // Key Type (RSA/ECDSA):(complexity) or Ed25519
+	ExpirationDays int64  `json:"exp,omitempty"`     // Days the new CA will be valid
+	Reissue        bool   `json:"reissue,omitempty"` // re-issue the certificates with the new CA key
+}
+
+// APIProfile is a named set of values stored for a CA, applied to the certificate requests that
+// use it. Defaults fill the values left empty on the request, fixed values always replace them
+type APIProfile struct {
+	Name     string           `json:"name" yaml:"name"`         // profile name (ex: server, client, peer, email)
+	Defaults APIProfileValues `json:"defaults" yaml:"defaults"` // values used if the request does not set them
+	Fixed    APIProfileValues `json:"fixed" yaml:"fixed"`       // values that override the request ones
+}
+
+// APIProfileValues are the request values a profile can set, empty values are ignored
+type APIProfileValues struct {
+	DN             APIDN    `json:"dn" yaml:"dn,omitempty"`                                 // common name cannot be set
+	Key            string   `json:"key,omitempty" yaml:"key,omitempty"`                     // Key Type (RSA/ECDSA):(complexity) or Ed25519
+	ExpirationDays int64    `json:"exp,omitempty" yaml:"exp,omitempty"`                     // Days the certificate will be valid
+	Client         *bool    `json:"client,omitempty" yaml:"client,omitempty"`               // requesting a client certificate?
+	ExtKeyUsage    []string `json:"ext_key_usage,omitempty" yaml:"ext_key_usage,omitempty"` // extended key usages by name or OID
+}
+
+// SSH certificate types
+const (
+	SSHUser = "user"
+	SSHHost = "host"
+)
+
+// APISSHRequest is the struct with the data needed to sign an OpenSSH user or host certificate
+type APISSHRequest struct {
+	PublicKey       []byte            `json:"public_key"`                 // OpenSSH public key to sign (authorized_keys format)
+	KeyID           string            `json:"key_id,omitempty"`           // identifier logged by sshd (Default: first principal)
+	Principals      []string          `json:"principals"`                 // user names or host names the certificate is valid for
+	Validity        string            `json:"validity,omitempty"`         // time the certificate will be valid as a duration (Default: 24h)
+	CriticalOptions map[string]string `json:"critical_options,omitempty"` // users only: force-command, source-address
+	Extensions      map[string]string `json:"extensions,omitempty"`       // users only: (Default: the ssh-keygen ones, permit-pty...)
+}
+
+// APISSHCertificate is a signed OpenSSH certificate
+type APISSHCertificate struct {
+	Certificate []byte    `json:"certificate"` // certificate in authorized_keys format (as the -cert.pub files)
+	Serial      uint64    `json:"serial"`
+	KeyID       string    `json:"key_id"`
+	Principals  []string  `json:"principals"`
+	ValidAfter  time.Time `json:"valid_after"`
+	ValidBefore time.Time `json:"valid_before"`
+}
+
+// APISSHCA is the public information of the SSH CA of a CA
+type APISSHCA struct {
+	PublicKey  []byte `json:"public_key"`  // CA public key in authorized_keys format, as used by sshd TrustedUserCAKeys
+	KnownHosts []byte `json:"known_hosts"` // @cert-authority line to trust the host certificates
+}
+
+// APILogEntry is a certificate appended to the issuance log of a CA, its leaf on the Merkle tree is
+// the hash of the timestamp (8 bytes, big endian) followed by the certificate DER
+type APILogEntry struct {
+	Index       uint64 `json:"index"`
+	Timestamp   int64  `json:"timestamp"` // milliseconds since the epoch
+	Serial      string `json:"serial"`
+	Certificate []byte `json:"certificate"` // PEM
+}
+
+// APISignedTreeHead is the root of the issuance log of a CA at a tree size, signed by the CA key
+type APISignedTreeHead struct {
+	TreeSize  uint64 `json:"tree_size"`
+	Timestamp int64  `json:"timestamp"` // milliseconds since the epoch
+	RootHash  []byte `json:"sha256_root_hash"`
+	Signature []byte `json:"tree_head_signature"`
+}
+
+// APIInclusionProof proves that a log entry is included on the tree of the size
+type APIInclusionProof struct {
+	Entry     APILogEntry `json:"entry"`
+	LeafHash  []byte      `json:"leaf_hash"`
+	TreeSize  uint64      `json:"tree_size"`
+	AuditPath [][]byte    `json:"audit_path"`
+}
+
+// APIConsistencyProof proves that the log tree of the first size is a prefix of the tree of the
+// second size, so no entries were modified or removed between them
+type APIConsistencyProof struct {
+	First       uint64   `json:"first"`
+	Second      uint64   `json:"second"`
+	Consistency [][]byte `json:"consistency"`
+}
+
+// APIPolicy is the issuance policy of a CA, certificate requests that do not meet it are rejected.
+// Empty values do not restrict anything.
+//
+// SAN patterns are globs (ex: *.example.com, * matches any characters) compared in lowercase, or
+// regular expressions if prefixed by `regex:` (ex: regex:^[a-z]+\.example\.com$). Typed SANs are
+// compared without its prefix.
+type APIPolicy struct {
+	AllowedSANs       []string `json:"allowed_sans,omitempty" yaml:"allowed_sans,omitempty"` // SANs must match any of these patterns
+	DeniedSANs        []string `json:"denied_sans,omitempty" yaml:"denied_sans,omitempty"`   // SANs must not match any of these patterns
+	MaxExpirationDays int64    `json:"max_exp,omitempty" yaml:"max_exp,omitempty"`           // maximum days the certificates can be valid
+	AllowedKeys       []string `json:"allowed_keys,omitempty" yaml:"allowed_keys,omitempty"` // key algorithms allowed (ex: ecdsa:256)
+	DenyClient        bool     `json:"deny_client,omitempty" yaml:"deny_client,omitempty"`   // client certificates are not allowed
+	RequiredDN        []string `json:"required_dn,omitempty" yaml:"required_dn,omitempty"`   // DN fields that must be set (c, l, o, ou, p, pc, st)
+
+	// SSH certificates
+	SSHMaxValidity       string   `json:"ssh_max_validity,omitempty" yaml:"ssh_max_validity,omitempty"`             // maximum time the certificates can be valid (ex: 8h)
+	SSHAllowedPrincipals []string `json:"ssh_allowed_principals,omitempty" yaml:"ssh_allowed_principals,omitempty"` // principals must match any of these patterns
+	SSHAllowedExtensions []string `json:"ssh_allowed_extensions,omitempty" yaml:"ssh_allowed_extensions,omitempty"` // user certificate extensions allowed (ex: permit-pty)
+}
+
+// policy rules, named as the APIPolicy fields
+const (
+	PolicyRuleAllowedSANs = "allowed_sans"
+	PolicyRuleDeniedSANs  = "denied_sans"
+	PolicyRuleMaxExp      = "max_exp"
+	PolicyRuleAllowedKeys = "allowed_keys"
+	PolicyRuleDenyClient  = "deny_client"
+	PolicyRuleRequiredDN  = "required_dn"
+
+	PolicyRuleSSHMaxValidity       = "ssh_max_validity"
+	PolicyRuleSSHAllowedPrincipals = "ssh_allowed_principals"
+	PolicyRuleSSHAllowedExtensions = "ssh_allowed_extensions"
+)
+
+// PolicyViolation is the error returned when a certificate request does not meet the issuance
+// policy of the CA. The API returns it as body with the status code of the rule (see Status)
+type PolicyViolation struct {
+	Code    int    `json:"code"`            // HTTP status code
+	Message string `json:"message"`         // HTTP status text
+	Rule    string `json:"rule"`            // policy rule not met (ex: denied_sans)
+	Value   string `json:"value,omitempty"` // request value that does not meet the rule
+	Reason  string `json:"reason"`          // explanation
+}
+
+// Error returns the violation as text
+func (v *PolicyViolation) Error() string {
+	return fmt.Sprintf("policy violation (%s): %s", v.Rule, v.Reason)
+}
+
+// Status returns the HTTP status code for the violation: 403 if the names or usages requested are
+// forbidden, 422 if the request values are out of the policy limits
+func (v *PolicyViolation) Status() int {
+
+	switch v.Rule {
+	case PolicyRuleAllowedSANs, PolicyRuleDeniedSANs, PolicyRuleDenyClient, PolicyRuleSSHAllowedPrincipals, PolicyRuleSSHAllowedExtensions:
+		return http.StatusForbidden
+	}
+
+	return http.StatusUnprocessableEntity
+
 }
 
 // APIDN is the struct of a Distinguished Name
@@ -57,6 +291,59 @@ const (
 	ECDSA256 = "ecdsa:256"
 	ECDSA384 = "ecdsa:384"
 	ECDSA521 = "ecdsa:521"
+	ED25519  = "ed25519"
+)
+
+// SAN type prefixes, values without prefix are guessed (IP, email, URI or DNS)
+const (
+	SANDNS   = "dns:"
+	SANIP    = "ip:"
+	SANEmail = "email:"
+	SANURI   = "uri:"
+	SANUPN   = "upn:" // Microsoft User Principal Name (otherName)
+)
+
+// renewal policies
+const (
+	RenewalReuseKey = "reuse-key" // the key is signed again on each renewal
+	RenewalRekey    = "rekey"     // a new key is created on each renewal
+)
+
+// extended key usages
+const (
+	ExtKeyUsageAny             = "any"
+	ExtKeyUsageServerAuth      = "serverAuth"
+	ExtKeyUsageClientAuth      = "clientAuth"
+	ExtKeyUsageCodeSigning     = "codeSigning"
+	ExtKeyUsageEmailProtection = "emailProtection"
+	ExtKeyUsageTimeStamping    = "timeStamping"
+	ExtKeyUsageOCSPSigning     = "ocspSigning"
+)
+
+// Revocation holds the information of a revoked certificate
+type Revocation struct {
+	Serial string    `json:"serial"` // serial number (hexadecimal)
+	CN     string    `json:"cn"`     // common name of the revoked certificate
+	Time   time.Time `json:"time"`   // revocation time
+	Reason string    `json:"reason"` // revocation reason
+}
+
+// APIRevocationRequest is the struct with the data needed to revoke a certificate
+type APIRevocationRequest struct {
+	Reason string `json:"reason" yaml:"reason"` // revocation reason (defaults to unspecified)
+}
+
+// revocation reasons (RFC 5280, 5.3.1)
+const (
+	ReasonUnspecified          = "unspecified"
+	ReasonKeyCompromise        = "keyCompromise"
+	ReasonCACompromise         = "cACompromise"
+	ReasonAffiliationChanged   = "affiliationChanged"
+	ReasonSuperseded           = "superseded"
+	ReasonCessationOfOperation = "cessationOfOperation"
+	ReasonCertificateHold      = "certificateHold"
+	ReasonPrivilegeWithdrawn   = "privilegeWithdrawn"
+	ReasonAACompromise         = "aACompromise"
 )
 
 // APIStatus is returned by the API on GET /status
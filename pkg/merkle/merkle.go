@@ -0,0 +1,255 @@
+// Package merkle implements the Merkle hash trees used by the cfd issuance logs.
+//
+// Trees, inclusion proofs and consistency proofs follow the Certificate Transparency definitions
+// (RFC 6962 section 2.1): leaves are hashed as SHA-256(0x00 || data) and nodes as
+// SHA-256(0x01 || left || right), so any RFC 6962 compliant verifier can check the proofs.
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+// errors
+var (
+	ErrIndexInvalid = errors.New("leaf index or tree size out of range")
+)
+
+// hash prefixes, leaves and nodes are hashed differently to avoid second preimage attacks
+const (
+	leafPrefix byte = 0x00
+	nodePrefix byte = 0x01
+)
+
+// LeafHash returns the hash of the leaf data
+func LeafHash(data []byte) []byte {
+
+	hash := sha256.Sum256(append([]byte{leafPrefix}, data...))
+	return hash[:]
+
+}
+
+// nodeHash returns the hash of the node with the left and right children hashes
+func nodeHash(left, right []byte) []byte {
+
+	hash := sha256.New()
+	hash.Write([]byte{nodePrefix})
+	hash.Write(left)
+	hash.Write(right)
+	return hash.Sum(nil)
+
+}
+
+// split returns the largest power of two smaller than n (n > 1)
+func split(n int) int {
+
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+
+}
+
+// RootHash returns the root hash of the tree with the leaf hashes, the empty tree root is the
+// hash of an empty string
+func RootHash(leaves [][]byte) []byte {
+
+	switch len(leaves) {
+	case 0:
+		hash := sha256.Sum256(nil)
+		return hash[:]
+	case 1:
+		return leaves[0]
+	}
+
+	k := split(len(leaves))
+	return nodeHash(RootHash(leaves[:k]), RootHash(leaves[k:]))
+
+}
+
+// AppendFrontier returns the frontier of the tree with size+1 leaves, adding the leaf hash to the
+// frontier of the tree with size leaves. The frontier holds the roots of the perfect subtrees of the
+// tree, from left to right, so leaves can be appended without the previous leaf hashes
+func AppendFrontier(frontier [][]byte, size uint64, leafHash []byte) [][]byte {
+
+	frontier = append(append([][]byte{}, frontier...), leafHash)
+
+	for ; size&1 == 1; size >>= 1 {
+		n := len(frontier)
+		frontier = append(frontier[:n-2], nodeHash(frontier[n-2], frontier[n-1]))
+	}
+
+	return frontier
+
+}
+
+// FrontierRootHash returns the root hash of the tree with the frontier, it is the same returned
+// by RootHash with its leaves
+func FrontierRootHash(frontier [][]byte) []byte {
+
+	if len(frontier) == 0 {
+		return RootHash(nil)
+	}
+
+	hash := frontier[len(frontier)-1]
+	for i := len(frontier) - 2; i >= 0; i-- {
+		hash = nodeHash(frontier[i], hash)
+	}
+
+	return hash
+
+}
+
+// InclusionProof returns the audit path of the leaf index on the tree with the leaf hashes
+func InclusionProof(index uint64, leaves [][]byte) ([][]byte, error) {
+
+	if index >= uint64(len(leaves)) {
+		return nil, ErrIndexInvalid
+	}
+
+	return path(int(index), leaves), nil
+
+}
+
+// path is PATH(m, D[n]) as defined on RFC 6962 2.1.1
+func path(m int, leaves [][]byte) [][]byte {
+
+	if len(leaves) <= 1 {
+		return [][]byte{}
+	}
+
+	k := split(len(leaves))
+	if m < k {
+		return append(path(m, leaves[:k]), RootHash(leaves[k:]))
+	}
+
+	return append(path(m-k, leaves[k:]), RootHash(leaves[:k]))
+
+}
+
+// ConsistencyProof returns the proof that the tree with the first size leaves is a prefix of the tree
+// with the leaf hashes
+func ConsistencyProof(first uint64, leaves [][]byte) ([][]byte, error) {
+
+	if first == 0 || first > uint64(len(leaves)) {
+		return nil, ErrIndexInvalid
+	}
+
+	return subproof(int(first), leaves, true), nil
+
+}
+
+// subproof is SUBPROOF(m, D[n], b) as defined on RFC 6962 2.1.2
+func subproof(m int, leaves [][]byte, complete bool) [][]byte {
+
+	if m == len(leaves) {
+		if complete {
+			return [][]byte{}
+		}
+		return [][]byte{RootHash(leaves)}
+	}
+
+	k := split(len(leaves))
+	if m <= k {
+		return append(subproof(m, leaves[:k], complete), RootHash(leaves[k:]))
+	}
+
+	return append(subproof(m-k, leaves[k:], false), RootHash(leaves[:k]))
+
+}
+
+// VerifyInclusion returns true if the audit path proves that the leaf hash is on the index of the
+// tree with the size and root hash
+func VerifyInclusion(index, size uint64, leafHash []byte, proof [][]byte, root []byte) bool {
+
+	if index >= size {
+		return false
+	}
+
+	var (
+		fn   uint64 = index
+		sn   uint64 = size - 1
+		hash []byte = leafHash
+	)
+
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+
+		if fn&1 == 1 || fn == sn {
+			hash = nodeHash(p, hash)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			hash = nodeHash(hash, p)
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	return sn == 0 && bytes.Equal(hash, root)
+
+}
+
+// VerifyConsistency returns true if the proof shows that the tree with the first size and root is
+// a prefix of the tree with the second size and root
+func VerifyConsistency(first, second uint64, firstRoot, secondRoot []byte, proof [][]byte) bool {
+
+	if first == 0 || first > second {
+		return false
+	}
+
+	if first == second {
+		return len(proof) == 0 && bytes.Equal(firstRoot, secondRoot)
+	}
+
+	// the first tree is a complete subtree, its root is the first node of the proof
+	if first&(first-1) == 0 {
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+
+	if len(proof) == 0 {
+		return false
+	}
+
+	var (
+		fn uint64 = first - 1
+		sn uint64 = second - 1
+		fr []byte = proof[0]
+		sr []byte = proof[0]
+	)
+
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return false
+		}
+
+		if fn&1 == 1 || fn == sn {
+			fr = nodeHash(c, fr)
+			sr = nodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = nodeHash(sr, c)
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	return sn == 0 && bytes.Equal(fr, firstRoot) && bytes.Equal(sr, secondRoot)
+
+}
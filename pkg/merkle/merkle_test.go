@@ -0,0 +1,112 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func leaves(n int) (hashes [][]byte) {
+
+	for i := 0; i < n; i++ {
+		hashes = append(hashes, LeafHash([]byte(fmt.Sprintf("leaf %d", i))))
+	}
+	return
+
+}
+
+func TestRootHash(t *testing.T) {
+
+	empty := sha256.Sum256(nil)
+	assert.Equal(t, empty[:], RootHash(nil))
+
+	// RFC 6962 test vectors (certificate-transparency test data)
+	assert.Equal(t, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d", hex.EncodeToString(LeafHash([]byte{})))
+	assert.Equal(t, "fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125", hex.EncodeToString(RootHash([][]byte{LeafHash([]byte{}), LeafHash([]byte{0x00})})))
+
+	// trees are built from the leaves, left to right
+	hashes := leaves(3)
+	assert.Equal(t, nodeHash(nodeHash(hashes[0], hashes[1]), hashes[2]), RootHash(hashes))
+
+}
+
+func TestFrontier(t *testing.T) {
+
+	var frontier [][]byte
+
+	hashes := leaves(70)
+	assert.Equal(t, RootHash(nil), FrontierRootHash(frontier))
+
+	for size := 1; size <= len(hashes); size++ {
+		frontier = AppendFrontier(frontier, uint64(size-1), hashes[size-1])
+		assert.Equal(t, RootHash(hashes[:size]), FrontierRootHash(frontier), "size %d", size)
+	}
+
+	// a perfect subtree per bit set of the size
+	assert.Len(t, frontier, 3)
+
+}
+
+func TestInclusionProof(t *testing.T) {
+
+	hashes := leaves(20)
+
+	for size := 1; size <= len(hashes); size++ {
+		root := RootHash(hashes[:size])
+
+		for index := 0; index < size; index++ {
+			proof, err := InclusionProof(uint64(index), hashes[:size])
+			assert.Nil(t, err)
+			assert.True(t, VerifyInclusion(uint64(index), uint64(size), hashes[index], proof, root), "%d/%d", index, size)
+
+			// must fail, other leaf, index or root
+			assert.False(t, VerifyInclusion(uint64(index), uint64(size), LeafHash([]byte("other")), proof, root))
+			assert.False(t, VerifyInclusion(uint64(index), uint64(size), hashes[index], proof, RootHash(hashes[:size-1])))
+			if size > 1 {
+				assert.False(t, VerifyInclusion(uint64((index+1)%size), uint64(size), hashes[index], proof, root))
+			}
+		}
+	}
+
+	_, err := InclusionProof(3, hashes[:3])
+	assert.Equal(t, ErrIndexInvalid, err)
+
+}
+
+func TestConsistencyProof(t *testing.T) {
+
+	hashes := leaves(20)
+
+	for second := 1; second <= len(hashes); second++ {
+		secondRoot := RootHash(hashes[:second])
+
+		for first := 1; first <= second; first++ {
+			firstRoot := RootHash(hashes[:first])
+
+			proof, err := ConsistencyProof(uint64(first), hashes[:second])
+			assert.Nil(t, err)
+			assert.True(t, VerifyConsistency(uint64(first), uint64(second), firstRoot, secondRoot, proof), "%d/%d", first, second)
+
+			// must fail, the first tree was rewritten
+			assert.False(t, VerifyConsistency(uint64(first), uint64(second), LeafHash([]byte("other")), secondRoot, proof))
+		}
+	}
+
+	// must fail, a leaf of the first tree was changed
+	tampered := append([][]byte{}, hashes...)
+	tampered[2] = LeafHash([]byte("other"))
+
+	proof, err := ConsistencyProof(7, tampered)
+	assert.Nil(t, err)
+	assert.False(t, VerifyConsistency(7, 20, RootHash(hashes[:7]), RootHash(tampered), proof))
+
+	_, err = ConsistencyProof(0, hashes)
+	assert.Equal(t, ErrIndexInvalid, err)
+
+	_, err = ConsistencyProof(21, hashes)
+	assert.Equal(t, ErrIndexInvalid, err)
+
+}
@@ -0,0 +1,385 @@
+// Package signer implements the protocol used by cfd to sign with keys held outside its store.
+//
+// A signer plugin is a process that holds the CA keys and signs the digests requested by cfd.
+// Requests and responses are JSON objects, one per line, exchanged through the standard
+// input/output of a subprocess (`exec:/path/to/plugin --flags`) or through a unix socket
+// (`unix:/path/to/socket`).
+package signer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// operations
+const (
+	OpPublicKey = "public" // returns the public key
+	OpSign      = "sign"   // signs the digest
+)
+
+// URI schemes
+const (
+	SchemeExec = "exec:"
+	SchemeUnix = "unix:"
+)
+
+// errors
+var (
+	ErrURIInvalid  = errors.New("signer: uri must be exec:<command> or unix:<socket path>")
+	ErrHashInvalid = errors.New("signer: hash function not available")
+)
+
+// Request is sent to the plugin
+type Request struct {
+	Op         string `json:"op"`                    // operation (public, sign)
+	KeyID      string `json:"key_id,omitempty"`      // key to use, if the plugin holds more than one
+	Digest     []byte `json:"digest,omitempty"`      // digest to sign (the whole message for Ed25519)
+	Hash       string `json:"hash,omitempty"`        // hash function of the digest (ex: SHA-256), empty for Ed25519
+	PSS        bool   `json:"pss,omitempty"`         // RSA only: sign using PSS instead of PKCS#1 v1.5
+	SaltLength int    `json:"salt_length,omitempty"` // RSA PSS only: salt length (0: auto, -1: equals hash)
+}
+
+// Response is returned by the plugin
+type Response struct {
+	PublicKey []byte `json:"public_key,omitempty"` // DER encoded PKIX public key
+	Signature []byte `json:"signature,omitempty"`  // signature of the digest
+	Error     string `json:"error,omitempty"`      // error message, if the request failed
+}
+
+var (
+	plugins   = make(map[string]*Plugin)
+	pluginsMu sync.Mutex
+)
+
+// Plugin is a crypto.Signer backed by a signer plugin
+type Plugin struct {
+	uri    string
+	keyID  string
+	public crypto.PublicKey
+
+	mu   sync.Mutex
+	conn io.ReadWriteCloser
+	enc  *json.Encoder
+	dec  *json.Decoder
+}
+
+// Open returns the signer for the key of the plugin, connections are shared between callers
+func Open(uri, keyID string) (*Plugin, error) {
+
+	var (
+		plugin   *Plugin
+		response Response
+		ok       bool
+		err      error
+	)
+
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	plugin, ok = plugins[uri+"#"+keyID]
+	if ok {
+		return plugin, nil
+	}
+
+	if !strings.HasPrefix(uri, SchemeExec) && !strings.HasPrefix(uri, SchemeUnix) {
+		return nil, ErrURIInvalid
+	}
+
+	plugin = &Plugin{
+		uri:   uri,
+		keyID: keyID,
+	}
+
+	response, err = plugin.call(Request{Op: OpPublicKey})
+	if err != nil {
+		return nil, err
+	}
+
+	plugin.public, err = x509.ParsePKIXPublicKey(response.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plugins[uri+"#"+keyID] = plugin
+
+	return plugin, nil
+
+}
+
+// Public returns the public key of the plugin key
+func (p *Plugin) Public() crypto.PublicKey {
+
+	return p.public
+
+}
+
+// Sign asks the plugin to sign the digest
+func (p *Plugin) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+
+	var (
+		request  Request
+		response Response
+		err      error
+	)
+
+	request.Op = OpSign
+	request.Digest = digest
+
+	if opts.HashFunc() != 0 {
+		request.Hash = opts.HashFunc().String()
+	}
+
+	if pss, ok := opts.(*rsa.PSSOptions); ok {
+		request.PSS = true
+		request.SaltLength = pss.SaltLength
+	}
+
+	response, err = p.call(request)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return response.Signature, nil
+
+}
+
+// Close ends the connection with the plugin, a new one is opened when needed
+func (p *Plugin) Close() error {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.disconnect()
+
+}
+
+// call sends the request and waits for its response, on errors the connection is closed
+func (p *Plugin) call(request Request) (response Response, err error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		err = p.connect()
+		if err != nil {
+			return
+		}
+	}
+
+	request.KeyID = p.keyID
+
+	err = p.enc.Encode(request)
+	if err == nil {
+		err = p.dec.Decode(&response)
+	}
+	if err != nil {
+		p.disconnect()
+		return
+	}
+
+	if response.Error != "" {
+		err = fmt.Errorf("signer: %s", response.Error)
+	}
+
+	return
+
+}
+
+func (p *Plugin) connect() (err error) {
+
+	switch {
+	case strings.HasPrefix(p.uri, SchemeUnix):
+		p.conn, err = net.Dial("unix", strings.TrimPrefix(p.uri, SchemeUnix))
+	case strings.HasPrefix(p.uri, SchemeExec):
+		p.conn, err = startProcess(strings.Fields(strings.TrimPrefix(p.uri, SchemeExec)))
+	default:
+		err = ErrURIInvalid
+	}
+	if err != nil {
+		return
+	}
+
+	p.enc = json.NewEncoder(p.conn)
+	p.dec = json.NewDecoder(p.conn)
+
+	return
+
+}
+
+func (p *Plugin) disconnect() (err error) {
+
+	if p.conn != nil {
+		err = p.conn.Close()
+		p.conn = nil
+	}
+
+	return
+
+}
+
+// process is a plugin running as subprocess, it is read from its standard output
+// and written to its standard input
+type process struct {
+	io.Reader
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+func startProcess(args []string) (*process, error) {
+
+	var (
+		p   process
+		err error
+	)
+
+	if len(args) == 0 {
+		return nil, ErrURIInvalid
+	}
+
+	p.cmd = exec.Command(args[0], args[1:]...)
+	p.cmd.Stderr = os.Stderr
+
+	p.WriteCloser, err = p.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	p.Reader, err = p.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+
+}
+
+// Close closes the plugin standard input, so it can exit, and waits for it
+func (p *process) Close() error {
+
+	p.WriteCloser.Close()
+	return p.cmd.Wait()
+
+}
+
+// Serve answers the requests read from r until it is closed, it is used to implement plugins.
+// keys returns the signer for a key ID
+func Serve(r io.Reader, w io.Writer, keys func(keyID string) (crypto.Signer, error)) error {
+
+	var (
+		dec *json.Decoder = json.NewDecoder(r)
+		enc *json.Encoder = json.NewEncoder(w)
+	)
+
+	for {
+
+		var request Request
+
+		err := dec.Decode(&request)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		response, err := answer(request, keys)
+		if err != nil {
+			response = Response{Error: err.Error()}
+		}
+
+		err = enc.Encode(response)
+		if err != nil {
+			return err
+		}
+
+	}
+
+}
+
+// ServeListener answers the requests of every connection accepted by the listener (ex: unix socket)
+func ServeListener(l net.Listener, keys func(keyID string) (crypto.Signer, error)) error {
+
+	for {
+
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			Serve(conn, conn, keys)
+		}()
+
+	}
+
+}
+
+func answer(request Request, keys func(keyID string) (crypto.Signer, error)) (response Response, err error) {
+
+	var (
+		key  crypto.Signer
+		hash crypto.Hash
+		opts crypto.SignerOpts
+	)
+
+	key, err = keys(request.KeyID)
+	if err != nil {
+		return
+	}
+
+	switch request.Op {
+	case OpPublicKey:
+		response.PublicKey, err = x509.MarshalPKIXPublicKey(key.Public())
+	case OpSign:
+		hash, err = hashFromString(request.Hash)
+		if err != nil {
+			return
+		}
+
+		opts = hash
+		if request.PSS {
+			opts = &rsa.PSSOptions{Hash: hash, SaltLength: request.SaltLength}
+		}
+
+		response.Signature, err = key.Sign(rand.Reader, request.Digest, opts)
+	default:
+		err = fmt.Errorf("operation not supported: %s", request.Op)
+	}
+
+	return
+
+}
+
+// hashFromString returns the hash function from its name, empty means no hash
+func hashFromString(name string) (crypto.Hash, error) {
+
+	if name == "" {
+		return 0, nil
+	}
+
+	for hash := crypto.MD4; hash <= crypto.BLAKE2b_512; hash++ {
+		if hash.String() == name && hash.Available() {
+			return hash, nil
+		}
+	}
+
+	return 0, ErrHashInvalid
+
+}
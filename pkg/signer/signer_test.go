@@ -0,0 +1,173 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const envTestKey = "CFD_SIGNER_TEST_KEY"
+
+// TestMain runs the test binary as a plugin if required, so the exec transport can be tested
+func TestMain(m *testing.M) {
+
+	if keyFile := os.Getenv(envTestKey); keyFile != "" {
+		keyPEM, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			os.Exit(1)
+		}
+		block, _ := pem.Decode(keyPEM)
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			os.Exit(1)
+		}
+		err = Serve(os.Stdin, os.Stdout, func(keyID string) (crypto.Signer, error) {
+			return key.(crypto.Signer), nil
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+
+}
+
+func TestUnixSocket(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cfd-signer")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	keys := map[string]crypto.Signer{
+		"ecdsa":   ecdsaKey,
+		"rsa":     rsaKey,
+		"ed25519": ed25519Key,
+	}
+
+	socket := filepath.Join(dir, "signer.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	go ServeListener(listener, func(keyID string) (crypto.Signer, error) {
+		key, ok := keys[keyID]
+		if !ok {
+			return nil, errors.New("key not found")
+		}
+		return key, nil
+	})
+
+	digest := sha256.Sum256([]byte("message"))
+
+	// ecdsa
+	plugin, err := Open(SchemeUnix+socket, "ecdsa")
+	assert.Nil(t, err)
+	assert.Equal(t, &ecdsaKey.PublicKey, plugin.Public())
+
+	signature, err := plugin.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Nil(t, err)
+	assert.True(t, ecdsa.VerifyASN1(&ecdsaKey.PublicKey, digest[:], signature))
+
+	// connections are shared and reopened when needed
+	same, err := Open(SchemeUnix+socket, "ecdsa")
+	assert.Nil(t, err)
+	assert.Equal(t, plugin, same)
+	assert.Nil(t, plugin.Close())
+
+	_, err = plugin.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Nil(t, err)
+
+	// rsa, PKCS#1 v1.5 and PSS
+	plugin, err = Open(SchemeUnix+socket, "rsa")
+	assert.Nil(t, err)
+
+	signature, err = plugin.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Nil(t, err)
+	assert.Nil(t, rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest[:], signature))
+
+	signature, err = plugin.Sign(rand.Reader, digest[:], &rsa.PSSOptions{Hash: crypto.SHA256, SaltLength: rsa.PSSSaltLengthEqualsHash})
+	assert.Nil(t, err)
+	assert.Nil(t, rsa.VerifyPSS(&rsaKey.PublicKey, crypto.SHA256, digest[:], signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}))
+
+	// ed25519 signs the whole message
+	plugin, err = Open(SchemeUnix+socket, "ed25519")
+	assert.Nil(t, err)
+
+	signature, err = plugin.Sign(rand.Reader, []byte("message"), crypto.Hash(0))
+	assert.Nil(t, err)
+	assert.True(t, ed25519.Verify(ed25519Key.Public().(ed25519.PublicKey), []byte("message"), signature))
+
+	// must fail, unknown key
+	_, err = Open(SchemeUnix+socket, "unknown")
+	assert.NotNil(t, err)
+
+	// must fail, invalid uri
+	_, err = Open("tcp://127.0.0.1:1234", "")
+	assert.Equal(t, ErrURIInvalid, err)
+
+	// must fail, socket not found
+	_, err = Open(SchemeUnix+filepath.Join(dir, "not-found.sock"), "")
+	assert.NotNil(t, err)
+
+}
+
+func TestExec(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cfd-signer")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err)
+
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Nil(t, err)
+
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}), 0600))
+
+	// the test binary serves as plugin
+	os.Setenv(envTestKey, keyFile)
+	defer os.Unsetenv(envTestKey)
+
+	plugin, err := Open(SchemeExec+os.Args[0]+" -test.run=^$", "")
+	assert.Nil(t, err)
+	assert.Equal(t, &key.PublicKey, plugin.Public())
+
+	digest := sha512.Sum384([]byte("message"))
+
+	signature, err := plugin.Sign(rand.Reader, digest[:], crypto.SHA384)
+	assert.Nil(t, err)
+	assert.True(t, ecdsa.VerifyASN1(&key.PublicKey, digest[:], signature))
+
+	assert.Nil(t, plugin.Close())
+
+	// must fail, command not found
+	_, err = Open(SchemeExec+filepath.Join(dir, "not-found"), "")
+	assert.NotNil(t, err)
+
+}